@@ -0,0 +1,268 @@
+// Package catalog contains a model of the Puppet JSON catalog format together with an encoder and a
+// decoder for that format.
+package catalog
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/puppet-evaluator/evaluator"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+)
+
+// Format is the catalog_format written by the encoder
+const Format = 1
+
+type (
+	// A Catalog is the result of an evaluation in a form that can be sent to a Puppet agent
+	Catalog struct {
+		// Name is the name of the node that the catalog was produced for
+		Name string
+
+		// Version is the Integer or String that identifies this version of the catalog
+		Version px.Value
+
+		// Environment is the name of the environment that the catalog was produced in
+		Environment string
+
+		// Tags are the tags of the catalog, i.e. the names of all classes and their segments
+		Tags []string
+
+		// Classes are the lower case names of all classes that were declared
+		Classes []string
+
+		// Resources are the resources of the catalog
+		Resources []*Resource
+
+		// Edges are the containment edges of the catalog
+		Edges []*Edge
+	}
+
+	// A Resource is a resource in a Catalog
+	Resource struct {
+		// Type is the capitalized name of the resource type, e.g. "File"
+		Type string
+
+		// Title is the title of the resource
+		Title string
+
+		// Tags are the lower case tags of the resource
+		Tags []string
+
+		// File is the file where the resource was declared, or the empty string if unknown
+		File string
+
+		// Line is the line where the resource was declared, or zero if unknown
+		Line int
+
+		// Exported is true if the resource was declared as an exported resource
+		Exported bool
+
+		// Parameters are the parameters of the resource. A parameter value is either data or, for
+		// parameters that are sensitive, a Sensitive that wraps data.
+		Parameters px.OrderedMap
+	}
+
+	// An Edge records that the resource referenced by Target is contained by the resource referenced
+	// by Source. References are strings in the form Type[title].
+	Edge struct {
+		Source string
+		Target string
+	}
+)
+
+// FromCatalog creates a Catalog for the given node from the resources and relationships recorded in the
+// given catalog. Virtual resources are not included. Relationship edges are recorded as before and notify
+// parameters of their source unless the relationship is already expressed by a parameter. Resource
+// references in parameter values are converted to strings, and values that are not data are
+// converted to their string representation.
+//
+// The function panics with an issue.Reported if a Sensitive value is found in a position other than the
+// top level value of a parameter.
+func FromCatalog(name, environment string, version px.Value, catalog pdsl.Catalog) *Catalog {
+	relationships := relationshipParameters(catalog)
+	result := &Catalog{Name: name, Version: version, Environment: environment,
+		Tags: []string{}, Classes: []string{}, Resources: []*Resource{}, Edges: []*Edge{}}
+	tags := make(map[string]bool)
+	for _, r := range catalog.Resources() {
+		if r.Virtual() {
+			continue
+		}
+		key := reference(r.Type(), r.Title())
+		parameters := make([]*types.HashEntry, 0, r.Attributes().Len())
+		r.Attributes().EachPair(func(k, v px.Value) {
+			parameters = append(parameters, types.WrapHashEntry(k, toParameter(r, k.String(), v)))
+		})
+		for _, rp := range relationships[key] {
+			parameters = appendRelationship(parameters, rp.name, rp.ref)
+		}
+
+		cr := &Resource{Type: r.Type(), Title: r.Title(), Tags: r.Tags(), Exported: r.Exported(),
+			Parameters: types.WrapHash(parameters)}
+		if cr.Tags == nil {
+			cr.Tags = []string{}
+		}
+		if l := r.Location(); l != nil {
+			cr.File = l.File()
+			cr.Line = l.Line()
+		}
+		result.Resources = append(result.Resources, cr)
+
+		if container, ok := catalog.Container(r); ok {
+			result.Edges = append(result.Edges, &Edge{Source: reference(container.Type(), container.Title()), Target: key})
+		}
+
+		if r.Type() == `Class` {
+			className := strings.ToLower(r.Title())
+			result.Classes = append(result.Classes, className)
+			tags[className] = true
+			for _, segment := range strings.Split(className, `::`) {
+				tags[segment] = true
+			}
+		}
+	}
+	for tag := range tags {
+		result.Tags = append(result.Tags, tag)
+	}
+	sort.Strings(result.Tags)
+	return result
+}
+
+type relationshipParameter struct {
+	name string
+	ref  string
+}
+
+// relationshipParameters returns the before and notify parameters that must be added to resources in order
+// to express the edges of the given catalog that are not already expressed by the parameters of the source
+// or the target of the edge. The returned map is keyed by the reference of the source.
+func relationshipParameters(catalog pdsl.Catalog) map[string][]relationshipParameter {
+	result := make(map[string][]relationshipParameter)
+	for _, e := range catalog.Edges() {
+		source := reference(e.Source().Type(), e.Source().Title())
+		target := reference(e.Target().Type(), e.Target().Title())
+		forward, reverse := `before`, `require`
+		if e.Subscribe() {
+			forward, reverse = `notify`, `subscribe`
+		}
+		if hasReference(catalog, e.Source(), forward, target) || hasReference(catalog, e.Target(), reverse, source) {
+			continue
+		}
+		// Use the real titles in case the edge refers to a resource using an alias
+		if r, ok := catalog.Resource(e.Source().Type(), e.Source().Title()); ok {
+			source = reference(r.Type(), r.Title())
+		}
+		if r, ok := catalog.Resource(e.Target().Type(), e.Target().Title()); ok {
+			target = reference(r.Type(), r.Title())
+		}
+		result[source] = append(result[source], relationshipParameter{forward, target})
+	}
+	return result
+}
+
+// hasReference returns true if the given parameter of the given resource contains a reference to the
+// resource with the given key
+func hasReference(catalog pdsl.Catalog, ref pdsl.Reference, parameter, key string) bool {
+	r, ok := catalog.Resource(ref.Type(), ref.Title())
+	if !ok {
+		return false
+	}
+	found := false
+	var find func(v px.Value)
+	find = func(v px.Value) {
+		switch v := v.(type) {
+		case pdsl.Reference:
+			if reference(v.Type(), v.Title()) == key {
+				found = true
+			}
+		case *types.Array:
+			v.Each(find)
+		}
+	}
+	if v, ok := r.Attributes().Get4(parameter); ok {
+		find(v)
+	}
+	return found
+}
+
+// appendRelationship adds the given reference to the parameter with the given name, creating the
+// parameter if necessary
+func appendRelationship(parameters []*types.HashEntry, name, ref string) []*types.HashEntry {
+	rv := types.WrapString(ref)
+	for i, p := range parameters {
+		if p.Key().String() != name {
+			continue
+		}
+		if a, ok := p.Value().(*types.Array); ok {
+			parameters[i] = types.WrapHashEntry(p.Key(), a.Add(rv))
+		} else {
+			parameters[i] = types.WrapHashEntry(p.Key(), types.WrapValues([]px.Value{p.Value(), rv}))
+		}
+		return parameters
+	}
+	return append(parameters, types.WrapHashEntry2(name, rv))
+}
+
+// toParameter converts the given value of the given parameter into data. A Sensitive is retained but
+// its content is converted.
+func toParameter(r pdsl.Resource, name string, value px.Value) px.Value {
+	if s, ok := value.(*types.Sensitive); ok {
+		return types.WrapSensitive(toData(r, name, s.Unwrap()))
+	}
+	return toData(r, name, value)
+}
+
+// toData converts the given value into data. Deferred values are retained.
+func toData(r pdsl.Resource, name string, value px.Value) px.Value {
+	switch v := value.(type) {
+	case *types.UndefValue, px.Boolean, px.Integer, px.Float, px.StringValue:
+		return v
+	case pdsl.Reference:
+		if v.Title() != `` {
+			return types.WrapString(reference(v.Type(), v.Title()))
+		}
+	case types.Deferred:
+		// A Deferred is retained so that it can be resolved by the agent
+		if v.Name() != `` {
+			var args []px.Value
+			if da := v.Arguments(); da != nil {
+				args = da.Map(func(e px.Value) px.Value { return toData(r, name, e) }).AppendTo(nil)
+			}
+			return types.NewDeferred(v.Name(), args...)
+		}
+	case *types.Array:
+		return v.Map(func(e px.Value) px.Value { return toData(r, name, e) })
+	case *types.Hash:
+		entries := make([]*types.HashEntry, 0, v.Len())
+		v.EachPair(func(k, e px.Value) {
+			entries = append(entries, types.WrapHashEntry2(k.String(), toData(r, name, e)))
+		})
+		return types.WrapHash(entries)
+	case *types.Sensitive:
+		panic(issue.NewReported(pdsl.CatalogNestedSensitive, issue.SeverityError,
+			issue.H{`resource`: reference(r.Type(), r.Title()), `name`: name}, r.Location()))
+	}
+	return types.WrapString(value.String())
+}
+
+// ResolveDeferred replaces the Deferred values in the parameters of the resources of the catalog with the
+// result of calling their functions. Only the functions of the given registry can be called. The path in
+// an error that reports a failing Deferred value starts with the reference of the resource and the name
+// of the parameter.
+func (c *Catalog) ResolveDeferred(ctx px.Context, registry evaluator.FunctionRegistry) {
+	entries := make([]*types.HashEntry, len(c.Resources))
+	for i, r := range c.Resources {
+		entries[i] = types.WrapHashEntry2(reference(r.Type, r.Title), r.Parameters)
+	}
+	resolved := evaluator.ResolveDeferred(ctx, types.WrapHash(entries), registry).(*types.Hash)
+	for i, r := range c.Resources {
+		r.Parameters = resolved.At(i).(*types.HashEntry).Value().(px.OrderedMap)
+	}
+}
+
+func reference(typeName, title string) string {
+	return typeName + `[` + title + `]`
+}
@@ -0,0 +1,217 @@
+package catalog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/puppet-evaluator/catalog"
+	"github.com/lyraproj/puppet-evaluator/evaluator"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+	"github.com/lyraproj/puppet-evaluator/puppet"
+)
+
+// compile evaluates the given manifest and calls f with the resulting catalog
+func compile(manifest string, f func(c pdsl.EvaluationContext, cat *catalog.Catalog)) {
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		expr := c.ParseAndValidate(`test.pp`, manifest, false)
+		c.AddDefinitions(expr)
+		pdsl.TopEvaluate(c, expr)
+		f(c, catalog.FromCatalog(`example.com`, `production`, types.WrapInteger(1), c.Catalog()))
+	})
+}
+
+func encode(cat *catalog.Catalog) []byte {
+	b := bytes.NewBufferString(``)
+	cat.Encode(b)
+	return b.Bytes()
+}
+
+func resource(t *testing.T, cat *catalog.Catalog, typeName, title string) *catalog.Resource {
+	t.Helper()
+	for _, r := range cat.Resources {
+		if r.Type == typeName && r.Title == title {
+			return r
+		}
+	}
+	t.Fatalf(`%s[%s] is not in the catalog`, typeName, title)
+	return nil
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	compile(`
+class foo($x = 3) {
+  notify { x: message => { a => [1, 2.5, true, undef], b => 'text' } }
+}
+include foo
+notify { y: message => Sensitive('secret') }`,
+		func(c pdsl.EvaluationContext, cat *catalog.Catalog) {
+			first := encode(cat)
+			second := encode(catalog.Decode(`first.json`, bytes.NewReader(first)))
+			if !bytes.Equal(first, second) {
+				t.Fatalf("round trip changed the catalog\nfirst:  %s\nsecond: %s", first, second)
+			}
+		})
+}
+
+func TestEncodeJSONCatalog(t *testing.T) {
+	compile(`notify { x: message => 'hello' }`, func(c pdsl.EvaluationContext, cat *catalog.Catalog) {
+		jc := make(map[string]interface{})
+		if err := json.Unmarshal(encode(cat), &jc); err != nil {
+			t.Fatal(err)
+		}
+		if jc[`name`] != `example.com` || jc[`environment`] != `production` {
+			t.Errorf(`unexpected name or environment in %v`, jc)
+		}
+		if jc[`catalog_format`] != float64(catalog.Format) {
+			t.Errorf(`expected catalog_format %d, got %v`, catalog.Format, jc[`catalog_format`])
+		}
+		if _, ok := jc[`code_id`]; !ok {
+			t.Error(`expected a code_id`)
+		}
+	})
+}
+
+func TestSensitiveParameters(t *testing.T) {
+	compile(`notify { x: message => Sensitive('secret'), withpath => true }`, func(c pdsl.EvaluationContext, cat *catalog.Catalog) {
+		b := encode(cat)
+		jc := struct {
+			Resources []struct {
+				Parameters          map[string]interface{} `json:"parameters"`
+				SensitiveParameters []string               `json:"sensitive_parameters"`
+			} `json:"resources"`
+		}{}
+		if err := json.Unmarshal(b, &jc); err != nil {
+			t.Fatal(err)
+		}
+		jr := jc.Resources[0]
+		if jr.Parameters[`message`] != `secret` {
+			t.Errorf(`expected the sensitive value in clear text, got %v`, jr.Parameters[`message`])
+		}
+		if len(jr.SensitiveParameters) != 1 || jr.SensitiveParameters[0] != `message` {
+			t.Errorf(`expected sensitive_parameters [message], got %v`, jr.SensitiveParameters)
+		}
+
+		r := resource(t, catalog.Decode(`x.json`, bytes.NewReader(b)), `Notify`, `x`)
+		s, ok := r.Parameters.Get5(`message`, px.Undef).(*types.Sensitive)
+		if !ok {
+			t.Fatalf(`expected decoded message to be Sensitive, got %v`, r.Parameters.Get5(`message`, px.Undef))
+		}
+		if s.Unwrap().String() != `secret` {
+			t.Errorf(`expected decoded Sensitive to contain 'secret', got %s`, s.Unwrap())
+		}
+		if _, ok := r.Parameters.Get5(`withpath`, px.Undef).(*types.Sensitive); ok {
+			t.Error(`expected withpath to not be Sensitive`)
+		}
+	})
+}
+
+func TestNestedSensitiveIsRejected(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatal(`expected a panic for a nested Sensitive`)
+		} else if err, ok := r.(error); !ok || !bytes.Contains([]byte(err.Error()), []byte(`Notify[x]`)) {
+			t.Fatalf(`unexpected error %v`, r)
+		}
+	}()
+	compile(`notify { x: message => [Sensitive('secret')] }`, func(c pdsl.EvaluationContext, cat *catalog.Catalog) {})
+}
+
+func TestRelationshipsAreParameters(t *testing.T) {
+	compile(`
+notify { a: } -> notify { b: } ~> notify { c: }
+notify { d: before => Notify[e] }
+notify { e: }
+Notify[d] -> Notify[e]
+notify { f: require => Notify[g] }
+notify { g: }
+Notify[g] -> Notify[f]`,
+		func(c pdsl.EvaluationContext, cat *catalog.Catalog) {
+			expectParameter := func(title, name, expected string) {
+				t.Helper()
+				v := resource(t, cat, `Notify`, title).Parameters.Get5(name, px.Undef)
+				if v.String() != expected {
+					t.Errorf(`expected Notify[%s] %s to be %s, got %s`, title, name, expected, v)
+				}
+			}
+			expectParameter(`a`, `before`, `Notify[b]`)
+			expectParameter(`b`, `notify`, `Notify[c]`)
+			expectParameter(`c`, `before`, `undef`)
+
+			// Edges that are already expressed by parameters are not added again
+			expectParameter(`d`, `before`, `Notify[e]`)
+			expectParameter(`f`, `require`, `Notify[g]`)
+			expectParameter(`g`, `before`, `undef`)
+		})
+}
+
+func TestContainmentEdges(t *testing.T) {
+	compile(`
+class foo { notify { x: } }
+define bar { notify { "y${title}": } }
+include foo
+bar { one: }
+notify { z: }`,
+		func(c pdsl.EvaluationContext, cat *catalog.Catalog) {
+			edges := make(map[string]string, len(cat.Edges))
+			for _, e := range cat.Edges {
+				edges[e.Target] = e.Source
+			}
+			expected := map[string]string{
+				`Notify[x]`:    `Class[Foo]`,
+				`Notify[yone]`: `Bar[one]`,
+			}
+			for target, source := range expected {
+				if edges[target] != source {
+					t.Errorf(`expected %s to be contained by %s, got %q`, target, source, edges[target])
+				}
+			}
+			if source, ok := edges[`Notify[z]`]; ok {
+				t.Errorf(`expected Notify[z] to not be contained, got %s`, source)
+			}
+
+			decoded := catalog.Decode(`x.json`, bytes.NewReader(encode(cat)))
+			if len(decoded.Edges) != len(cat.Edges) {
+				t.Fatalf(`expected %d decoded edges, got %d`, len(cat.Edges), len(decoded.Edges))
+			}
+			for i, e := range decoded.Edges {
+				if *e != *cat.Edges[i] {
+					t.Errorf(`expected decoded edge %v to equal %v`, *e, *cat.Edges[i])
+				}
+			}
+			if fmt.Sprint(decoded.Classes) != `[foo]` {
+				t.Errorf(`expected classes [foo], got %v`, decoded.Classes)
+			}
+		})
+}
+
+func TestDeferredValues(t *testing.T) {
+	compile(`notify { x: message => Deferred('split', ['a-b', '-']), withpath => Sensitive(Deferred('split', ['c-d', '-'])) }`,
+		func(c pdsl.EvaluationContext, cat *catalog.Catalog) {
+			b := encode(cat)
+			if !bytes.Contains(b, []byte(`{"__ptype":"Deferred","name":"split","arguments":["a-b","-"]}`)) {
+				t.Fatalf(`expected an encoded Deferred in %s`, b)
+			}
+
+			decoded := catalog.Decode(`x.json`, bytes.NewReader(b))
+			if _, ok := resource(t, decoded, `Notify`, `x`).Parameters.Get5(`message`, px.Undef).(types.Deferred); !ok {
+				t.Fatal(`expected the decoded message to be a Deferred`)
+			}
+
+			decoded.ResolveDeferred(c, evaluator.LoadFunctionRegistry(c, `split`))
+			r := resource(t, decoded, `Notify`, `x`)
+			if v := r.Parameters.Get5(`message`, px.Undef); v.String() != `['a', 'b']` {
+				t.Errorf(`expected message to be resolved to ['a', 'b'], got %s`, v)
+			}
+			s, ok := r.Parameters.Get5(`withpath`, px.Undef).(*types.Sensitive)
+			if !ok {
+				t.Fatal(`expected withpath to remain Sensitive`)
+			}
+			if v := s.Unwrap(); v.String() != `['c', 'd']` {
+				t.Errorf(`expected withpath to be resolved to ['c', 'd'], got %s`, v)
+			}
+		})
+}
@@ -0,0 +1,146 @@
+package catalog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+)
+
+// Decode reads a catalog in the Puppet JSON catalog format from the given reader. The values of the
+// parameters that are listed in the sensitive_parameters of a resource are wrapped in a Sensitive. The
+// given path is only used in error messages.
+//
+// The function panics with an issue.Reported if the JSON cannot be parsed or if it does not represent a
+// catalog.
+func Decode(path string, in io.Reader) *Catalog {
+	jc := &jsonCatalog{}
+	d := json.NewDecoder(in)
+	d.UseNumber()
+	if err := d.Decode(jc); err != nil {
+		panic(px.Error(pdsl.CatalogBadJson, issue.H{`path`: path, `detail`: err}))
+	}
+	if jc.Name == `` || jc.Resources == nil {
+		panic(px.Error(pdsl.CatalogBadJson, issue.H{`path`: path, `detail`: `a catalog must have a name and resources`}))
+	}
+
+	c := &Catalog{
+		Name:        jc.Name,
+		Version:     jc.Version.value,
+		Environment: jc.Environment,
+		Tags:        nonNil(jc.Tags),
+		Classes:     nonNil(jc.Classes),
+		Resources:   make([]*Resource, len(jc.Resources)),
+		Edges:       make([]*Edge, len(jc.Edges))}
+
+	for i, jr := range jc.Resources {
+		r := &Resource{Type: jr.Type, Title: jr.Title, Tags: nonNil(jr.Tags), File: jr.File, Line: jr.Line,
+			Exported: jr.Exported, Parameters: px.EmptyMap}
+		if jr.Parameters != nil {
+			parameters, ok := jr.Parameters.value.(*types.Hash)
+			if !ok {
+				panic(px.Error(pdsl.CatalogBadJson, issue.H{`path`: path,
+					`detail`: fmt.Sprintf(`the parameters of %s must be an object`, reference(r.Type, r.Title))}))
+			}
+			r.Parameters = parameters
+		}
+		for _, name := range jr.SensitiveParameters {
+			if v, ok := r.Parameters.Get4(name); ok {
+				r.Parameters = r.Parameters.Merge(types.WrapHash([]*types.HashEntry{types.WrapHashEntry2(name, types.WrapSensitive(v))}))
+			}
+		}
+		c.Resources[i] = r
+	}
+
+	for i, je := range jc.Edges {
+		c.Edges[i] = &Edge{Source: je.Source, Target: je.Target}
+	}
+	return c
+}
+
+func (d *data) UnmarshalJSON(b []byte) error {
+	jd := json.NewDecoder(bytes.NewReader(b))
+	jd.UseNumber()
+	v, err := readData(jd)
+	if err == nil {
+		d.value = v
+	}
+	return err
+}
+
+func readData(d *json.Decoder) (px.Value, error) {
+	t, err := d.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := t.(type) {
+	case nil:
+		return px.Undef, nil
+	case bool:
+		return types.WrapBoolean(t), nil
+	case string:
+		return types.WrapString(t), nil
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return types.WrapInteger(i), nil
+		}
+		f, err := t.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return types.WrapFloat(f), nil
+	case json.Delim:
+		if t == '[' {
+			elements := make([]px.Value, 0)
+			for d.More() {
+				e, err := readData(d)
+				if err != nil {
+					return nil, err
+				}
+				elements = append(elements, e)
+			}
+			_, err = d.Token()
+			return types.WrapValues(elements), err
+		}
+		entries := make([]*types.HashEntry, 0)
+		for d.More() {
+			k, err := d.Token()
+			if err != nil {
+				return nil, err
+			}
+			v, err := readData(d)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, types.WrapHashEntry2(k.(string), v))
+		}
+		_, err = d.Token()
+		return deferredOrHash(types.WrapHash(entries)), err
+	}
+	return nil, fmt.Errorf(`unexpected JSON token %v`, t)
+}
+
+// deferredOrHash returns the Deferred that the given hash represents when its __ptype is Deferred, and
+// otherwise the hash itself
+func deferredOrHash(h *types.Hash) px.Value {
+	if h.Get5(`__ptype`, px.Undef).String() != `Deferred` {
+		return h
+	}
+	var args []px.Value
+	if a, ok := h.Get5(`arguments`, px.EmptyArray).(*types.Array); ok {
+		args = a.AppendTo(nil)
+	}
+	return types.NewDeferred(h.Get5(`name`, px.EmptyString).String(), args...)
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
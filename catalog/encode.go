@@ -0,0 +1,91 @@
+package catalog
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/puppet-evaluator/evaluator"
+)
+
+type (
+	jsonCatalog struct {
+		Tags          []string        `json:"tags"`
+		Name          string          `json:"name"`
+		Version       data            `json:"version"`
+		CodeID        *string         `json:"code_id"`
+		CatalogFormat int             `json:"catalog_format"`
+		Environment   string          `json:"environment"`
+		Resources     []*jsonResource `json:"resources"`
+		Edges         []*jsonEdge     `json:"edges"`
+		Classes       []string        `json:"classes"`
+	}
+
+	jsonResource struct {
+		Type                string   `json:"type"`
+		Title               string   `json:"title"`
+		Tags                []string `json:"tags"`
+		File                string   `json:"file,omitempty"`
+		Line                int      `json:"line,omitempty"`
+		Exported            bool     `json:"exported"`
+		Parameters          *data    `json:"parameters,omitempty"`
+		SensitiveParameters []string `json:"sensitive_parameters,omitempty"`
+	}
+
+	jsonEdge struct {
+		Source string `json:"source"`
+		Target string `json:"target"`
+	}
+
+	// data is a px.Value that is encoded as JSON. Hash entries retain their order.
+	data struct {
+		value px.Value
+	}
+)
+
+// Encode writes the catalog to the given writer in the Puppet JSON catalog format. The values of sensitive
+// parameters are written in clear text and the names of those parameters are listed in the
+// sensitive_parameters of the resource. The function panics if the writer returns an error.
+func (c *Catalog) Encode(out io.Writer) {
+	jc := &jsonCatalog{
+		Tags:          c.Tags,
+		Name:          c.Name,
+		Version:       data{c.Version},
+		CatalogFormat: Format,
+		Environment:   c.Environment,
+		Resources:     make([]*jsonResource, len(c.Resources)),
+		Edges:         make([]*jsonEdge, len(c.Edges)),
+		Classes:       c.Classes}
+
+	for i, r := range c.Resources {
+		jr := &jsonResource{Type: r.Type, Title: r.Title, Tags: r.Tags, File: r.File, Line: r.Line, Exported: r.Exported}
+		if r.Parameters != nil && r.Parameters.Len() > 0 {
+			parameters := make([]*types.HashEntry, 0, r.Parameters.Len())
+			r.Parameters.EachPair(func(k, v px.Value) {
+				if s, ok := v.(*types.Sensitive); ok {
+					jr.SensitiveParameters = append(jr.SensitiveParameters, k.String())
+					v = s.Unwrap()
+				}
+				parameters = append(parameters, types.WrapHashEntry(k, v))
+			})
+			jr.Parameters = &data{types.WrapHash(parameters)}
+		}
+		jc.Resources[i] = jr
+	}
+
+	for i, e := range c.Edges {
+		jc.Edges[i] = &jsonEdge{Source: e.Source, Target: e.Target}
+	}
+
+	if err := json.NewEncoder(out).Encode(jc); err != nil {
+		panic(err)
+	}
+}
+
+func (d data) MarshalJSON() ([]byte, error) {
+	b := bytes.NewBufferString(``)
+	evaluator.WriteJSON(b, d.value)
+	return b.Bytes(), nil
+}
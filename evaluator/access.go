@@ -33,6 +33,14 @@ func evalAccessExpression(e pdsl.Evaluator, expr *parser.AccessExpression) (resu
 			}
 		}
 
+		if isResourceTypeName(e, qr.Name()) {
+			args := make([]px.Value, len(keys))
+			for idx, key := range keys {
+				args[idx] = e.Eval(key)
+			}
+			return evalResourceReference(qr.Name(), args, expr)
+		}
+
 		args := make([]px.Value, len(keys))
 		e.DoStatic(func() {
 			for idx, key := range keys {
@@ -1,6 +1,9 @@
 package evaluator
 
 import (
+	"fmt"
+	"math"
+	"math/big"
 	"strconv"
 
 	"github.com/lyraproj/issue/issue"
@@ -28,39 +31,74 @@ func calculate(expr *parser.ArithmeticExpression, a px.Value, b px.Value) px.Val
 				return av.Add(b)
 			}
 		}
+	case *types.Timestamp:
+		return lhsTimestampArithmetic(expr, a.Time(), b)
+	case types.Timespan:
+		return lhsTimespanArithmetic(expr, a.Duration(), b)
 	case px.Float:
 		return lhsFloatArithmetic(expr, a.Float(), b)
 	case px.Integer:
 		return lhsIntArithmetic(expr, a.Int(), b)
+	case *BigInteger:
+		return lhsBigArithmetic(expr, a.value, b)
 	case px.StringValue:
-		s := a.String()
-		if iv, err := strconv.ParseInt(s, 0, 64); err == nil {
-			return lhsIntArithmetic(expr, iv, b)
-		}
-		if fv, err := strconv.ParseFloat(s, 64); err == nil {
-			return lhsFloatArithmetic(expr, fv, b)
-		}
-		panic(evalError(pdsl.NotNumeric, expr.Lhs(), issue.H{`value`: s}))
+		return calculate(expr, toNumber(expr.Lhs(), a.String()), b)
 	}
 	panic(evalError(pdsl.OperatorNotApplicable, expr, issue.H{`operator`: op, `left`: a.PType()}))
 }
 
+// toNumber converts a string operand to an Integer or a Float. A string that denotes an integer that doesn't
+// fit in 64 bits is an IntegerOverflow error unless the big_integers setting is true, in which case it is
+// converted to a BigInteger.
+func toNumber(expr parser.Expression, s string) px.Value {
+	iv, err := strconv.ParseInt(s, 0, 64)
+	if err == nil {
+		return types.WrapInteger(iv)
+	}
+	if ne, ok := err.(*strconv.NumError); ok && ne.Err == strconv.ErrRange {
+		if pdsl.BigIntegers() {
+			if bv, ok := new(big.Int).SetString(s, 0); ok {
+				return &BigInteger{bv}
+			}
+		}
+		panic(evalError(pdsl.IntegerOverflow, expr, issue.H{`value`: s}))
+	}
+	if fv, err := strconv.ParseFloat(s, 64); err == nil {
+		return types.WrapFloat(fv)
+	}
+	panic(evalError(pdsl.NotNumeric, expr, issue.H{`value`: s}))
+}
+
 func lhsIntArithmetic(expr *parser.ArithmeticExpression, ai int64, b px.Value) px.Value {
 	op := expr.Operator()
 	switch b := b.(type) {
 	case px.Integer:
-		return types.WrapInteger(intArithmetic(expr, ai, b.Int()))
+		return intArithmetic(expr, ai, b.Int())
+	case *BigInteger:
+		return bigArithmetic(expr, big.NewInt(ai), b.value)
 	case px.Float:
 		return types.WrapFloat(floatArithmetic(expr, float64(ai), b.Float()))
 	case px.StringValue:
-		s := b.String()
-		if iv, err := strconv.ParseInt(s, 0, 64); err == nil {
-			return types.WrapInteger(intArithmetic(expr, ai, iv))
-		}
-		if fv, err := strconv.ParseFloat(s, 64); err == nil {
-			return types.WrapFloat(floatArithmetic(expr, float64(ai), fv))
+		return lhsIntArithmetic(expr, ai, toNumber(expr.Rhs(), b.String()))
+	case types.Timespan:
+		if op == `*` {
+			return lhsTimespanArithmetic(expr, b.Duration(), types.WrapInteger(ai))
 		}
-		panic(evalError(pdsl.NotNumeric, expr.Rhs(), issue.H{`value`: s}))
+	}
+	panic(evalError(pdsl.OperatorNotApplicableWhen, expr, issue.H{`operator`: op, `left`: `Integer`, `right`: b.PType()}))
+}
+
+func lhsBigArithmetic(expr *parser.ArithmeticExpression, ab *big.Int, b px.Value) px.Value {
+	op := expr.Operator()
+	switch b := b.(type) {
+	case px.Integer:
+		return bigArithmetic(expr, ab, big.NewInt(b.Int()))
+	case *BigInteger:
+		return bigArithmetic(expr, ab, b.value)
+	case px.Float:
+		return types.WrapFloat(floatArithmetic(expr, (&BigInteger{ab}).Float(), b.Float()))
+	case px.StringValue:
+		return lhsBigArithmetic(expr, ab, toNumber(expr.Rhs(), b.String()))
 	default:
 		panic(evalError(pdsl.OperatorNotApplicableWhen, expr, issue.H{`operator`: op, `left`: `Integer`, `right`: b.PType()}))
 	}
@@ -73,18 +111,16 @@ func lhsFloatArithmetic(expr *parser.ArithmeticExpression, af float64, b px.Valu
 		return types.WrapFloat(floatArithmetic(expr, af, b.Float()))
 	case px.Integer:
 		return types.WrapFloat(floatArithmetic(expr, af, float64(b.Int())))
+	case *BigInteger:
+		return types.WrapFloat(floatArithmetic(expr, af, b.Float()))
 	case px.StringValue:
-		s := b.String()
-		if iv, err := strconv.ParseInt(s, 0, 64); err == nil {
-			return types.WrapFloat(floatArithmetic(expr, af, float64(iv)))
+		return lhsFloatArithmetic(expr, af, toNumber(expr.Rhs(), b.String()))
+	case types.Timespan:
+		if op == `*` {
+			return lhsTimespanArithmetic(expr, b.Duration(), types.WrapFloat(af))
 		}
-		if fv, err := strconv.ParseFloat(s, 64); err == nil {
-			return types.WrapFloat(floatArithmetic(expr, af, fv))
-		}
-		panic(evalError(pdsl.NotNumeric, expr.Rhs(), issue.H{`value`: s}))
-	default:
-		panic(evalError(pdsl.OperatorNotApplicableWhen, expr, issue.H{`operator`: op, `left`: `Float`, `right`: b.PType()}))
 	}
+	panic(evalError(pdsl.OperatorNotApplicableWhen, expr, issue.H{`operator`: op, `left`: `Float`, `right`: b.PType()}))
 }
 
 func floatArithmetic(expr *parser.ArithmeticExpression, a float64, b float64) float64 {
@@ -96,31 +132,138 @@ func floatArithmetic(expr *parser.ArithmeticExpression, a float64, b float64) fl
 	case `*`:
 		return a * b
 	case `/`:
+		if b == 0 {
+			panic(evalError(pdsl.DivisionByZero, expr.Rhs(), issue.NoArgs))
+		}
 		return a / b
 	default:
 		panic(evalError(pdsl.OperatorNotApplicable, expr, issue.H{`operator`: expr.Operator(), `left`: `Float`}))
 	}
 }
 
-func intArithmetic(expr *parser.ArithmeticExpression, a int64, b int64) int64 {
+// intArithmetic performs checked 64-bit integer arithmetic. An operation that overflows is redone with
+// arbitrary precision by bigArithmetic.
+func intArithmetic(expr *parser.ArithmeticExpression, a int64, b int64) px.Value {
+	var r int64
+	overflow := false
 	switch expr.Operator() {
 	case `+`:
-		return a + b
+		r, overflow = addInt(a, b)
 	case `-`:
-		return a - b
+		r, overflow = subInt(a, b)
 	case `*`:
-		return a * b
+		r, overflow = mulInt(a, b)
 	case `/`:
-		return a / b
+		if b == 0 {
+			panic(evalError(pdsl.DivisionByZero, expr.Rhs(), issue.NoArgs))
+		}
+		r = a / b
+		overflow = a == math.MinInt64 && b == -1
 	case `%`:
-		return a % b
+		if b == 0 {
+			panic(evalError(pdsl.ModuloByZero, expr.Rhs(), issue.NoArgs))
+		}
+		r = a % b
 	case `<<`:
-		return a << uint(b)
+		r, overflow = shiftInt(a, b, true)
 	case `>>`:
-		return a >> uint(b)
+		r, overflow = shiftInt(a, b, false)
 	default:
 		panic(evalError(pdsl.OperatorNotApplicable, expr, issue.H{`operator`: expr.Operator(), `left`: `Integer`}))
 	}
+	if overflow {
+		return bigArithmetic(expr, big.NewInt(a), big.NewInt(b))
+	}
+	return types.WrapInteger(r)
+}
+
+// addInt returns a + b. The returned boolean is true when the sum overflows.
+func addInt(a int64, b int64) (int64, bool) {
+	r := a + b
+	return r, (b > 0 && r < a) || (b < 0 && r > a)
+}
+
+// subInt returns a - b. The returned boolean is true when the difference overflows.
+func subInt(a int64, b int64) (int64, bool) {
+	r := a - b
+	return r, (b > 0 && r > a) || (b < 0 && r < a)
+}
+
+// mulInt returns a * b. The returned boolean is true when the product overflows.
+func mulInt(a int64, b int64) (int64, bool) {
+	r := a * b
+	return r, a != 0 && (r/a != b || a == -1 && b == math.MinInt64)
+}
+
+// shiftInt shifts the given value n bits to the left or to the right. A negative n shifts in the opposite
+// direction. The returned boolean is true when bits are lost in a shift to the left.
+func shiftInt(a int64, n int64, left bool) (int64, bool) {
+	c := uint64(n)
+	if n < 0 {
+		c = -c
+		left = !left
+	}
+	if !left {
+		return a >> c, false
+	}
+	if c >= 64 {
+		return 0, a != 0
+	}
+	r := a << c
+	return r, r>>c != a
+}
+
+// maxShift is the largest number of bits that a value can be shifted to the left without being considered
+// an overflow, regardless of the big_integers setting
+const maxShift = 0xffff
+
+// bigArithmetic performs arbitrary-precision integer arithmetic. A result that doesn't fit in 64 bits is an
+// IntegerOverflow error unless the big_integers setting is true.
+func bigArithmetic(expr *parser.ArithmeticExpression, a *big.Int, b *big.Int) px.Value {
+	op := expr.Operator()
+	r := new(big.Int)
+	switch op {
+	case `+`:
+		r.Add(a, b)
+	case `-`:
+		r.Sub(a, b)
+	case `*`:
+		r.Mul(a, b)
+	case `/`:
+		if b.Sign() == 0 {
+			panic(evalError(pdsl.DivisionByZero, expr.Rhs(), issue.NoArgs))
+		}
+		r.Quo(a, b)
+	case `%`:
+		if b.Sign() == 0 {
+			panic(evalError(pdsl.ModuloByZero, expr.Rhs(), issue.NoArgs))
+		}
+		r.Rem(a, b)
+	case `<<`, `>>`:
+		left := op == `<<`
+		n := new(big.Int).Abs(b)
+		if b.Sign() < 0 {
+			left = !left
+		}
+		c := ^uint(0)
+		if n.IsUint64() && n.Uint64() < uint64(c) {
+			c = uint(n.Uint64())
+		}
+		if !left {
+			r.Rsh(a, c)
+		} else if a.Sign() != 0 {
+			if c > maxShift {
+				panic(evalError(pdsl.IntegerOverflow, expr, issue.H{`value`: fmt.Sprintf(`%s %s %s`, a, op, b)}))
+			}
+			r.Lsh(a, c)
+		}
+	default:
+		panic(evalError(pdsl.OperatorNotApplicable, expr, issue.H{`operator`: op, `left`: `Integer`}))
+	}
+	if !(r.IsInt64() || pdsl.BigIntegers()) {
+		panic(evalError(pdsl.IntegerOverflow, expr, issue.H{`value`: r.String()}))
+	}
+	return WrapBigInteger(r)
 }
 
 func concatenate(expr *parser.ArithmeticExpression, a px.Value, b px.Value) px.Value {
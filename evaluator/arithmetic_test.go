@@ -0,0 +1,154 @@
+package evaluator_test
+
+import (
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/pcore"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+	"github.com/lyraproj/puppet-evaluator/puppet"
+)
+
+// withBigIntegers runs the given function with the big_integers setting enabled
+func withBigIntegers(f func(c pdsl.EvaluationContext)) {
+	pcore.Set(pdsl.BigIntegerSetting, types.WrapBoolean(true))
+	defer pcore.Set(pdsl.BigIntegerSetting, types.WrapBoolean(false))
+	puppet.Do(f)
+}
+
+type arithmeticError struct {
+	source string
+	code   issue.Code
+
+	// pos is the position on the line of the expression that the error is reported for
+	pos int
+}
+
+// expectErrors asserts that each of the given sources is an error that is reported at the expected position
+func expectErrors(t *testing.T, c pdsl.EvaluationContext, errors []arithmeticError) {
+	t.Helper()
+	for _, tt := range errors {
+		ri := expectIssue(t, tt.code, func() { evaluate(c, tt.source) })
+		if ri != nil && ri.Location().Pos() != tt.pos {
+			t.Errorf(`%s: expected %s at position %d, got %d`, tt.source, tt.code, tt.pos, ri.Location().Pos())
+		}
+	}
+}
+
+func TestIntegerArithmetic(t *testing.T) {
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		expectValues(t, c, map[string]string{
+			`9223372036854775806 + 1`:   `9223372036854775807`,
+			`-9223372036854775807 - 1`:  `-9223372036854775808`,
+			`-4611686018427387904 * 2`:  `-9223372036854775808`,
+			`7 / 2`:                     `3`,
+			`7 % 3`:                     `1`,
+			`1 << 62`:                   `4611686018427387904`,
+			`8 >> 1`:                    `4`,
+			`-8 >> 1`:                   `-4`,
+			`8 >> 70`:                   `0`,
+			`0 << 100`:                  `0`,
+			`5 =~ BigInteger`:           `false`,
+			`7 / 2.0`:                   `3.5`,
+			`1.5 * 3`:                   `4.5`,
+			`9223372036854775807 + 1.0`: `9.223372036854776e+18`,
+		})
+	})
+}
+
+func TestNegativeShift(t *testing.T) {
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		// A negative shift count shifts in the opposite direction
+		expectValues(t, c, map[string]string{
+			`8 << -1`:  `4`,
+			`8 >> -1`:  `16`,
+			`1 << -70`: `0`,
+		})
+		expectErrors(t, c, []arithmeticError{
+			{`1 >> -63`, pdsl.IntegerOverflow, 1},
+			{`1 >> -64`, pdsl.IntegerOverflow, 1},
+		})
+	})
+}
+
+func TestStringToNumberCoercion(t *testing.T) {
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		expectValues(t, c, map[string]string{
+			`'10' + 5`:     `15`,
+			`5 - '2'`:      `3`,
+			`'0x10' + 1`:   `17`,
+			`'010' + 0`:    `8`,
+			`'1.5' * 3`:    `4.5`,
+			`2.5 + '0.25'`: `2.75`,
+			`'3' * '4'`:    `12`,
+			`'-2' << 1`:    `-4`,
+		})
+		expectErrors(t, c, []arithmeticError{
+			{`'abc' + 1`, pdsl.NotNumeric, 1},
+			{`1 + 'abc'`, pdsl.NotNumeric, 5},
+			{`1.0 + '1x'`, pdsl.NotNumeric, 7},
+			{`'99999999999999999999' + 1`, pdsl.IntegerOverflow, 1},
+			{`1 + '99999999999999999999'`, pdsl.IntegerOverflow, 5},
+		})
+	})
+}
+
+func TestArithmeticErrors(t *testing.T) {
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		expectErrors(t, c, []arithmeticError{
+			// Overflow is reported for the operation
+			{`9223372036854775807 + 1`, pdsl.IntegerOverflow, 1},
+			{`-9223372036854775807 - 2`, pdsl.IntegerOverflow, 1},
+			{`4611686018427387904 * 2`, pdsl.IntegerOverflow, 1},
+			{`(-9223372036854775807 - 1) / -1`, pdsl.IntegerOverflow, 1},
+			{`1 << 63`, pdsl.IntegerOverflow, 1},
+			{`3 << 100`, pdsl.IntegerOverflow, 1},
+
+			// Division by zero is reported for the right operand
+			{`1 / 0`, pdsl.DivisionByZero, 5},
+			{`1.5 / 0`, pdsl.DivisionByZero, 7},
+			{`1 / 0.0`, pdsl.DivisionByZero, 5},
+			{`1 % 0`, pdsl.ModuloByZero, 5},
+			{`10 %  '0'`, pdsl.ModuloByZero, 7},
+
+			{`1 + true`, pdsl.OperatorNotApplicableWhen, 1},
+			{`1.5 % 2`, pdsl.OperatorNotApplicable, 1},
+		})
+	})
+}
+
+func TestBigIntegerArithmetic(t *testing.T) {
+	withBigIntegers(func(c pdsl.EvaluationContext) {
+		expectValues(t, c, map[string]string{
+			`9223372036854775807 + 1`:                          `9223372036854775808`,
+			`-9223372036854775807 - 2`:                         `-9223372036854775809`,
+			`1 << 64`:                                          `18446744073709551616`,
+			`(1 << 64) >> 64`:                                  `1`,
+			`(1 << 64) - (1 << 64) + 5`:                        `5`,
+			`((1 << 64) - 1) % 10`:                             `5`,
+			`'99999999999999999999' + 1`:                       `100000000000000000000`,
+			`(1 << 64) / 2.0`:                                  `9.223372036854776e+18`,
+			`(1 << 64) > 9223372036854775807`:                  `true`,
+			`(1 << 64) == (1 << 64)`:                           `true`,
+			`(1 << 64) == 1`:                                   `false`,
+			`type(1 << 64)`:                                    `BigInteger`,
+			`(1 << 64) =~ BigInteger`:                          `true`,
+			`((1 << 64) - (1 << 64)) =~ BigInteger`:            `false`,
+			`(1 << 64) =~ Integer`:                             `true`,
+			`(1 << 64) =~ Integer[0]`:                          `true`,
+			`(1 << 64) =~ Numeric`:                             `true`,
+			`(1 << 64) =~ Integer[0, 100]`:                     `false`,
+			`(-1 << 64) =~ Integer[0]`:                         `false`,
+			`(-1 << 64) =~ Integer[default, 0]`:                `true`,
+			`(1 << 64) !~ String`:                              `true`,
+			`(1 << 64) ? { Integer[0, 9] => a, Integer => b }`: `b`,
+		})
+		expectErrors(t, c, []arithmeticError{
+			{`(1 << 64) / 0`, pdsl.DivisionByZero, 13},
+			{`(1 << 64) % 0`, pdsl.ModuloByZero, 13},
+			{`1 << 70000`, pdsl.IntegerOverflow, 1},
+			{`(1 << 64) + 'x'`, pdsl.NotNumeric, 13},
+		})
+	})
+}
@@ -1,6 +1,8 @@
 package evaluator
 
 import (
+	"strings"
+
 	"github.com/lyraproj/issue/issue"
 	"github.com/lyraproj/pcore/px"
 	"github.com/lyraproj/pcore/types"
@@ -14,6 +16,9 @@ func evalAssignmentExpression(e pdsl.Evaluator, expr *parser.AssignmentExpressio
 
 func assign(expr *parser.AssignmentExpression, scope px.Keyed, lv px.Value, rv px.Value) px.Value {
 	if sv, ok := lv.(px.StringValue); ok {
+		if name := strings.TrimPrefix(sv.String(), `::`); reservedVariables[name] {
+			panic(evalError(pdsl.IllegalReservedAssignment, expr, issue.H{`var`: name}))
+		}
 		if !scope.(pdsl.Scope).Set(sv.String(), rv) {
 			panic(evalError(pdsl.IllegalReassignment, expr, issue.H{`var`: sv.String()}))
 		}
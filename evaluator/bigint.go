@@ -0,0 +1,107 @@
+package evaluator
+
+import (
+	"io"
+	"math"
+	"math/big"
+
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/pcore/utils"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+)
+
+// BigInteger is an arbitrary-precision integer. It is produced by integer arithmetic that overflows a 64-bit
+// Integer when the big_integers setting is true.
+//
+// The type of a BigInteger is the BigInteger type. The Integer types of pcore only have instances that fit in
+// 64 bits, so the match operator and the case and selector expressions check a BigInteger against other
+// types using the bound that it exceeds, see matchesBigInteger.
+type BigInteger struct {
+	value *big.Int
+}
+
+var bigIntegerType px.ObjectType
+
+func init() {
+	bigIntegerType = px.NewObjectType(`BigInteger`, `{}`)
+}
+
+// WrapBigInteger returns the given value as an Integer when it fits in 64 bits and as a BigInteger otherwise
+func WrapBigInteger(value *big.Int) px.Value {
+	if value.IsInt64() {
+		return types.WrapInteger(value.Int64())
+	}
+	return &BigInteger{value}
+}
+
+// Big returns the wrapped value
+func (bi *BigInteger) Big() *big.Int {
+	return bi.value
+}
+
+// Float returns the nearest float64 value
+func (bi *BigInteger) Float() float64 {
+	f, _ := new(big.Float).SetInt(bi.value).Float64()
+	return f
+}
+
+func (bi *BigInteger) Equals(other interface{}, guard px.Guard) bool {
+	if o, ok := other.(*BigInteger); ok {
+		return bi.value.Cmp(o.value) == 0
+	}
+	return false
+}
+
+func (bi *BigInteger) String() string {
+	return bi.value.String()
+}
+
+func (bi *BigInteger) ToString(b io.Writer, s px.FormatContext, g px.RDetect) {
+	utils.WriteString(b, bi.value.String())
+}
+
+func (bi *BigInteger) PType() px.Type {
+	return bigIntegerType
+}
+
+// bigOf returns the arbitrary-precision value of the given Integer or BigInteger
+func bigOf(v px.Value) (*big.Int, bool) {
+	switch v := v.(type) {
+	case *BigInteger:
+		return v.value, true
+	case px.Integer:
+		return big.NewInt(v.Int()), true
+	}
+	return nil, false
+}
+
+// matchesBigInteger returns true if the given BigInteger is an instance of the given type. When the
+// big_integers setting is true, a BigInteger is also an instance of the types that the 64-bit bound that
+// it exceeds is an instance of. It is then an instance of Integer, Numeric, and an Integer type that is
+// unbounded in its direction, but not of an Integer type that has a bound in that direction, such as
+// Integer[0, 100].
+func matchesBigInteger(bi *BigInteger, t px.Type) bool {
+	if t.IsInstance(bi, nil) {
+		return true
+	}
+	if !pdsl.BigIntegers() {
+		return false
+	}
+	bound := types.WrapInteger(math.MaxInt64)
+	if bi.value.Sign() < 0 {
+		bound = types.WrapInteger(math.MinInt64)
+	}
+	return px.IsInstance(t, bound)
+}
+
+// floatOf returns the float64 value of the given Number or BigInteger
+func floatOf(v px.Value) (float64, bool) {
+	switch v := v.(type) {
+	case *BigInteger:
+		return v.Float(), true
+	case px.Number:
+		return v.Float(), true
+	}
+	return 0, false
+}
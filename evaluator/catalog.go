@@ -0,0 +1,323 @@
+package evaluator
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+)
+
+type (
+	catalog struct {
+		lock       sync.RWMutex
+		resources  []pdsl.Resource
+		index      map[string]int
+		edges      []pdsl.Edge
+		targets    map[string][]string
+		containers map[string]pdsl.Reference
+	}
+
+	resource struct {
+		typeName   string
+		title      string
+		attributes px.OrderedMap
+		location   issue.Location
+		tags       []string
+		virtual    bool
+		exported   bool
+	}
+
+	edge struct {
+		source    pdsl.Reference
+		target    pdsl.Reference
+		subscribe bool
+		location  issue.Location
+	}
+)
+
+// NewCatalog returns a new empty catalog
+func NewCatalog() pdsl.Catalog {
+	return &catalog{
+		resources:  make([]pdsl.Resource, 0, 16),
+		index:      make(map[string]int, 16),
+		targets:    make(map[string][]string, 16),
+		containers: make(map[string]pdsl.Reference, 16)}
+}
+
+// NewResource returns a new resource with the given type name, title, and attributes. The
+// type name is expected to be capitalized.
+func NewResource(typeName, title string, attributes px.OrderedMap, location issue.Location) pdsl.Resource {
+	return &resource{typeName: typeName, title: title, attributes: attributes, location: location}
+}
+
+// NewVirtualResource returns a new virtual resource with the given type name, title, and attributes. The
+// resource is also exported when exported is true.
+func NewVirtualResource(typeName, title string, attributes px.OrderedMap, exported bool, location issue.Location) pdsl.Resource {
+	return &resource{typeName: typeName, title: title, attributes: attributes, location: location, virtual: true, exported: exported}
+}
+
+// NewEdge returns a new edge that makes the target dependent on the source
+func NewEdge(source, target pdsl.Reference, subscribe bool, location issue.Location) pdsl.Edge {
+	return &edge{source: source, target: target, subscribe: subscribe, location: location}
+}
+
+func resourceKey(typeName, title string) string {
+	return typeName + `[` + title + `]`
+}
+
+func referenceKey(ref pdsl.Reference) string {
+	return resourceKey(ref.Type(), ref.Title())
+}
+
+// copyResource returns a copy of the given resource
+func copyResource(r pdsl.Resource) *resource {
+	return &resource{
+		typeName:   r.Type(),
+		title:      r.Title(),
+		attributes: r.Attributes(),
+		location:   r.Location(),
+		tags:       r.Tags(),
+		virtual:    r.Virtual(),
+		exported:   r.Exported()}
+}
+
+// canonicalKey returns the key of the resource that the given reference appoints. The reference may use
+// an alias of the resource. The key of the reference is returned when no resource is found.
+func (c *catalog) canonicalKey(ref pdsl.Reference) string {
+	key := referenceKey(ref)
+	if i, ok := c.index[key]; ok {
+		return referenceKey(c.resources[i])
+	}
+	return key
+}
+
+func (c *catalog) AddEdge(e pdsl.Edge) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	source := c.canonicalKey(e.Source())
+	target := c.canonicalKey(e.Target())
+
+	// The new edge closes a cycle if the source can be reached from the target
+	if path, ok := c.findPath(target, source, make(map[string]bool)); ok {
+		b := bytes.NewBufferString(source)
+		for _, key := range path {
+			b.WriteString(` => `)
+			b.WriteString(key)
+		}
+		panic(evalError(pdsl.DependencyCycle, e.Location(), issue.H{`cycle`: b.String()}))
+	}
+	c.targets[source] = append(c.targets[source], target)
+	c.edges = append(c.edges, e)
+}
+
+// findPath returns the path of resource keys that leads from the start to the goal. Both ends are included
+// in the path.
+func (c *catalog) findPath(start, goal string, visited map[string]bool) ([]string, bool) {
+	if start == goal {
+		return []string{start}, true
+	}
+	visited[start] = true
+	for _, next := range c.targets[start] {
+		if visited[next] {
+			continue
+		}
+		if path, ok := c.findPath(next, goal, visited); ok {
+			return append([]string{start}, path...), true
+		}
+	}
+	return nil, false
+}
+
+func (c *catalog) AddResource(r pdsl.Resource) {
+	key := referenceKey(r)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if prev, ok := c.index[key]; ok {
+		pl := c.resources[prev].Location()
+		panic(evalError(pdsl.DuplicateResource, r.Location(),
+			issue.H{`type`: r.Type(), `title`: r.Title(), `file`: pl.File(), `line`: pl.Line()}))
+	}
+	c.index[key] = len(c.resources)
+	c.resources = append(c.resources, r)
+}
+
+func (c *catalog) Alias(ref pdsl.Reference, alias string) bool {
+	key := resourceKey(ref.Type(), alias)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	i, ok := c.index[referenceKey(ref)]
+	if !ok {
+		return false
+	}
+	if prev, ok := c.index[key]; ok {
+		if prev == i {
+			return true
+		}
+		pl := c.resources[prev].Location()
+		panic(evalError(pdsl.DuplicateAlias, c.resources[i].Location(),
+			issue.H{`resource`: ref, `type`: ref.Type(), `alias`: alias, `file`: pl.File(), `line`: pl.Line()}))
+	}
+	c.index[key] = i
+	return true
+}
+
+func (c *catalog) Contain(container, resource pdsl.Reference) {
+	c.lock.Lock()
+	key := c.canonicalKey(resource)
+	if _, ok := c.containers[key]; !ok {
+		c.containers[key] = container
+	}
+	c.lock.Unlock()
+}
+
+func (c *catalog) Container(resource pdsl.Reference) (container pdsl.Reference, ok bool) {
+	c.lock.RLock()
+	container, ok = c.containers[c.canonicalKey(resource)]
+	c.lock.RUnlock()
+	return
+}
+
+func (c *catalog) Edges() []pdsl.Edge {
+	c.lock.RLock()
+	es := make([]pdsl.Edge, len(c.edges))
+	copy(es, c.edges)
+	c.lock.RUnlock()
+	return es
+}
+
+func (c *catalog) Realize(ref pdsl.Reference) bool {
+	key := referenceKey(ref)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if i, ok := c.index[key]; ok {
+		if c.resources[i].Virtual() {
+			// Resources are immutable so a realized copy replaces the virtual resource
+			r := copyResource(c.resources[i])
+			r.virtual = false
+			c.resources[i] = r
+			return true
+		}
+	}
+	return false
+}
+
+func (c *catalog) Resource(typeName, title string) (r pdsl.Resource, ok bool) {
+	var i int
+	c.lock.RLock()
+	if i, ok = c.index[resourceKey(typeName, title)]; ok {
+		r = c.resources[i]
+	}
+	c.lock.RUnlock()
+	return
+}
+
+func (c *catalog) Resources() []pdsl.Resource {
+	c.lock.RLock()
+	rs := make([]pdsl.Resource, len(c.resources))
+	copy(rs, c.resources)
+	c.lock.RUnlock()
+	return rs
+}
+
+func (c *catalog) SetAttributes(ref pdsl.Reference, attributes px.OrderedMap) bool {
+	key := referenceKey(ref)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if i, ok := c.index[key]; ok {
+		r := copyResource(c.resources[i])
+		r.attributes = attributes
+		c.resources[i] = r
+		return true
+	}
+	return false
+}
+
+func (c *catalog) Tag(ref pdsl.Reference, tags ...string) bool {
+	key := referenceKey(ref)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if i, ok := c.index[key]; ok {
+		r := copyResource(c.resources[i])
+		ts := make([]string, len(r.tags), len(r.tags)+len(tags))
+		copy(ts, r.tags)
+	nextTag:
+		for _, tag := range tags {
+			tag = strings.ToLower(tag)
+			for _, t := range ts {
+				if t == tag {
+					continue nextTag
+				}
+			}
+			ts = append(ts, tag)
+		}
+		r.tags = ts
+		c.resources[i] = r
+		return true
+	}
+	return false
+}
+
+func (r *resource) Attributes() px.OrderedMap {
+	return r.attributes
+}
+
+func (r *resource) Exported() bool {
+	return r.exported
+}
+
+func (r *resource) Location() issue.Location {
+	return r.location
+}
+
+func (r *resource) Tags() []string {
+	return r.tags
+}
+
+func (r *resource) Title() string {
+	return r.title
+}
+
+func (r *resource) Type() string {
+	return r.typeName
+}
+
+func (r *resource) String() string {
+	return resourceKey(r.typeName, r.title)
+}
+
+func (r *resource) Virtual() bool {
+	return r.virtual
+}
+
+func (e *edge) Location() issue.Location {
+	return e.location
+}
+
+func (e *edge) Source() pdsl.Reference {
+	return e.source
+}
+
+func (e *edge) Subscribe() bool {
+	return e.subscribe
+}
+
+func (e *edge) Target() pdsl.Reference {
+	return e.target
+}
+
+func (e *edge) String() string {
+	arrow := ` -> `
+	if e.subscribe {
+		arrow = ` ~> `
+	}
+	return referenceKey(e.source) + arrow + referenceKey(e.target)
+}
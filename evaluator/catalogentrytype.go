@@ -0,0 +1,72 @@
+package evaluator
+
+import (
+	"io"
+
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/pcore/utils"
+)
+
+// catalogEntryType is the abstract type of all resources and classes
+type catalogEntryType struct{}
+
+var catalogEntryMetaType px.ObjectType
+
+var catalogEntryTypeDefault = &catalogEntryType{}
+
+func init() {
+	catalogEntryMetaType = px.NewObjectType(`Pcore::CatalogEntryType`, `Pcore::AnyType {}`,
+		func(ctx px.Context, args []px.Value) px.Value {
+			return catalogEntryTypeDefault
+		})
+
+	px.RegisterResolvableType(catalogEntryTypeDefault)
+}
+
+func (t *catalogEntryType) Accept(v px.Visitor, g px.Guard) {
+	v(t)
+}
+
+func (t *catalogEntryType) Equals(o interface{}, g px.Guard) bool {
+	_, ok := o.(*catalogEntryType)
+	return ok
+}
+
+func (t *catalogEntryType) IsAssignable(o px.Type, g px.Guard) bool {
+	switch o.(type) {
+	case *catalogEntryType, *resourceType:
+		return true
+	default:
+		return false
+	}
+}
+
+func (t *catalogEntryType) IsInstance(o px.Value, g px.Guard) bool {
+	return false
+}
+
+func (t *catalogEntryType) MetaType() px.ObjectType {
+	return catalogEntryMetaType
+}
+
+func (t *catalogEntryType) Name() string {
+	return `CatalogEntry`
+}
+
+func (t *catalogEntryType) PType() px.Type {
+	return types.NewTypeType(t)
+}
+
+// Resolve returns the receiver. It enables the CatalogEntry type to be registered with the loader.
+func (t *catalogEntryType) Resolve(c px.Context) px.Type {
+	return t
+}
+
+func (t *catalogEntryType) String() string {
+	return `CatalogEntry`
+}
+
+func (t *catalogEntryType) ToString(b io.Writer, s px.FormatContext, g px.RDetect) {
+	utils.WriteString(b, `CatalogEntry`)
+}
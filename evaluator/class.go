@@ -0,0 +1,184 @@
+package evaluator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/puppet-evaluator/hiera"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+type puppetClass struct {
+	expression *parser.HostClassDefinition
+	parameters []px.Parameter
+
+	// loader is the loader that the class body is evaluated with
+	loader px.Loader
+}
+
+// NewPuppetClass creates a class from the given definition. The body of the class is evaluated with the
+// given loader.
+func NewPuppetClass(expr *parser.HostClassDefinition, loader px.Loader) *puppetClass {
+	return &puppetClass{expression: expr, loader: loader}
+}
+
+// DeclareClass declares the class with the given name and returns a reference to it. A class that has
+// already been declared is not evaluated again unless the declaration is resource like, in which case
+// the declaration will fail with a duplicate resource issue.
+//
+// The given parameters are bound to the class parameters prior to the evaluation of the class body. Only
+// resource like declarations can have parameters. Values for parameters that are not given are found using
+// automatic parameter lookup.
+func DeclareClass(c pdsl.EvaluationContext, name string, parameters px.OrderedMap, resourceLike bool, location issue.Location) pdsl.Reference {
+	name = strings.ToLower(strings.TrimPrefix(name, `::`))
+	ref := newResourceType(`Class`, classTitle(name))
+	catalog := c.Catalog()
+	if !resourceLike {
+		if _, ok := catalog.Resource(ref.typeName, ref.title); ok {
+			return ref
+		}
+	}
+
+	pc, ok := px.Load(c, px.NewTypedName2(pdsl.NsClass, name, c.Loader().NameAuthority()))
+	if !ok {
+		panic(evalError(pdsl.UnknownClass, location, issue.H{`name`: name}))
+	}
+	cl := pc.(*puppetClass)
+	if parent := cl.expression.ParentClass(); parent != `` {
+		DeclareClass(c, parent, px.EmptyMap, false, location)
+	}
+
+	parameters = hiera.LookupParameters(c, name, cl.Parameters(), parameters)
+
+	// The class is added to the catalog prior to evaluation of its body to ensure that
+	// it's only evaluated once.
+	r := NewResource(ref.typeName, ref.title, parameters, location)
+	catalog.AddResource(r)
+	applyMetaParameters(c, r)
+	addMetaParameterEdges(c, r)
+	cl.evaluate(c, ref, parameters, location)
+	return ref
+}
+
+// classInherits returns true if the class with the given name inherits, directly or indirectly, from the
+// given ancestor
+func classInherits(c pdsl.EvaluationContext, name, ancestor string) bool {
+	for {
+		pc, ok := px.Load(c, px.NewTypedName2(pdsl.NsClass, strings.ToLower(name), c.Loader().NameAuthority()))
+		if !ok {
+			return false
+		}
+		if name = strings.TrimPrefix(pc.(*puppetClass).expression.ParentClass(), `::`); name == `` {
+			return false
+		}
+		if strings.EqualFold(name, ancestor) {
+			return true
+		}
+	}
+}
+
+func (pc *puppetClass) Name() string {
+	return pc.expression.Name()
+}
+
+func (pc *puppetClass) Parameters() []px.Parameter {
+	return pc.parameters
+}
+
+func (pc *puppetClass) Resolve(c px.Context) {
+	if pc.parameters != nil {
+		panic(fmt.Sprintf(`Attempt to resolve already resolved class %s`, pc.Name()))
+	}
+	pc.parameters = resolveParameters(c.(pdsl.EvaluationContext), pc.expression.Parameters())
+}
+
+func (pc *puppetClass) String() string {
+	return `class ` + pc.Name()
+}
+
+// evaluate evaluates the class body in a new named scope. The scope of a class that inherits another class
+// is parented by the scope of that class so that the variables of the parent class are visible.
+func (pc *puppetClass) evaluate(c pdsl.EvaluationContext, ref pdsl.Reference, args px.OrderedMap, location issue.Location) {
+	parent := globalScope(c.Scope().(pdsl.Scope))
+	if pn := pc.expression.ParentClass(); pn != `` {
+		if ps, ok := c.ClassScope(strings.ToLower(strings.TrimPrefix(pn, `::`))); ok {
+			parent = ps
+		}
+	}
+	scope := NewNamedScope(pc.Name(), parent)
+	c.SetClassScope(strings.ToLower(pc.Name()), scope)
+	c.DoWithLoader(pc.loader, func() {
+		c.DoWithScope(scope, func() {
+			c.DoWithContainer(ref, func() {
+				bindParameters(c, ref, pc.Name(), pc.parameters, args, location)
+				pdsl.Evaluate(c, pc.expression.Body())
+			})
+		})
+	})
+}
+
+// bindParameters assigns the variables $title and $name and all parameters to the current scope of the
+// given context. The $name variable is assigned the title unless it's given as an argument. It is an error if
+// an argument has no corresponding parameter.
+func bindParameters(c pdsl.EvaluationContext, ref pdsl.Reference, title string, params []px.Parameter, args px.OrderedMap, location issue.Location) {
+	hasName := false
+	for _, p := range params {
+		if p.Name() == `name` {
+			hasName = true
+			break
+		}
+	}
+
+	args.EachKey(func(k px.Value) {
+		n := k.String()
+		if n == `name` || isMetaParameter(n) {
+			// The name and the metaparameters are always valid arguments
+			return
+		}
+		for _, p := range params {
+			if p.Name() == n {
+				return
+			}
+		}
+		panic(evalError(pdsl.UnknownParameter, location, issue.H{`resource`: ref, `name`: n}))
+	})
+
+	scope := c.Scope().(pdsl.Scope)
+	scope.Set(`title`, types.WrapString(title))
+	if !hasName {
+		scope.Set(`name`, args.Get5(`name`, types.WrapString(title)))
+	}
+	assignParameters(c, ref, params, args, location)
+}
+
+// assignParameters assigns all parameters to the current scope of the given context. The value of a parameter
+// is taken from the given arguments or, when no argument is present, from the default value of the parameter.
+// The owner of the parameters is used in error messages.
+func assignParameters(c pdsl.EvaluationContext, owner interface{}, params []px.Parameter, args px.OrderedMap, location issue.Location) {
+	scope := c.Scope().(pdsl.Scope)
+	for _, p := range params {
+		v, ok := args.Get4(p.Name())
+		if !ok {
+			switch {
+			case p.HasValue():
+				v = p.Value()
+				if df, ok := v.(types.Deferred); ok {
+					v = df.Resolve(c, scope)
+				}
+			case px.IsInstance(p.Type(), px.Undef):
+				v = px.Undef
+			default:
+				panic(evalError(pdsl.MissingParameter, location, issue.H{`resource`: owner, `name`: p.Name()}))
+			}
+		}
+		if !px.IsInstance(p.Type(), v) {
+			panic(evalError(pdsl.ParameterTypeMismatch, location,
+				issue.H{`resource`: owner, `name`: p.Name(), `expected`: p.Type(), `actual`: px.DetailedValueType(v)}))
+		}
+		scope.Set(p.Name(), v)
+	}
+}
@@ -0,0 +1,135 @@
+package evaluator_test
+
+import (
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+	"github.com/lyraproj/puppet-evaluator/puppet"
+)
+
+// containerOf returns the reference of the container of the given resource, or an empty string if it has none
+func containerOf(cat pdsl.Catalog, typeName, title string) string {
+	r, ok := cat.Resource(typeName, title)
+	if !ok {
+		return ``
+	}
+	if container, ok := cat.Container(r); ok {
+		return container.Type() + `[` + container.Title() + `]`
+	}
+	return ``
+}
+
+func TestClassDeclaredOnce(t *testing.T) {
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		cat := compile(c, `
+class once { notify { 'once': } }
+class twice { include once }
+include once
+include once, twice
+contain once
+require ::once
+class { 'twice::inner': }
+class twice::inner { include [once, 'twice'] }`)
+		if actual := resourceList(cat); actual != `Class[Once], Notify[once], Class[Twice], Class[Twice::Inner]` {
+			t.Errorf(`unexpected resources %s`, actual)
+		}
+	})
+}
+
+func TestClassParameters(t *testing.T) {
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		cat := compile(c, `
+class params(Integer $x, String $y = "y${x}", Optional[String] $z) {
+  $local = $x + 1
+  notify { "${title} ${name} ${x} ${y}": }
+}
+class { 'params': x => 1 }
+notify { 'vars': message => [$params::x, $params::y, $params::local, $::params::z] }`)
+		if _, ok := cat.Resource(`Notify`, `params params 1 y1`); !ok {
+			t.Errorf(`expected Notify[params params 1 y1] in %s`, resourceList(cat))
+		}
+		expectAttributes(t, cat, `Class`, `Params`, `{'x' => 1}`)
+		expectAttributes(t, cat, `Notify`, `vars`, `{'message' => [1, 'y1', 2, undef]}`)
+	})
+}
+
+func TestClassErrors(t *testing.T) {
+	for source, code := range map[string]issue.Code{
+		`include missing`:                                                      pdsl.UnknownClass,
+		`class a {} class { 'a': } class { 'a': }`:                             pdsl.DuplicateResource,
+		`class a {} include a class { 'a': }`:                                  pdsl.DuplicateResource,
+		`class a(Integer $x) {} include a`:                                     pdsl.MissingParameter,
+		`class a(Integer $x) {} class { 'a': x => 'one' }`:                     pdsl.ParameterTypeMismatch,
+		`class a {} class { 'a': x => 1 }`:                                     pdsl.UnknownParameter,
+		`class a { $x = 1 $x = 2 } include a`:                                  pdsl.IllegalReassignment,
+		`class a { notify { 'n': } } class b { notify { 'n': } } include a, b`: pdsl.DuplicateResource,
+	} {
+		puppet.Do(func(c pdsl.EvaluationContext) {
+			expectIssue(t, code, func() { compile(c, source) })
+		})
+	}
+}
+
+func TestContainAndRequire(t *testing.T) {
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		cat := compile(c, `
+class inner { notify { 'i': } }
+class included { }
+class required { }
+class outer {
+  contain inner
+  include included
+  require required
+}
+include outer`)
+		for _, tt := range []struct{ typeName, title, container string }{
+			{`Notify`, `i`, `Class[Inner]`},
+			{`Class`, `Inner`, `Class[Outer]`},
+			{`Class`, `Included`, ``},
+			{`Class`, `Required`, ``},
+		} {
+			if actual := containerOf(cat, tt.typeName, tt.title); actual != tt.container {
+				t.Errorf(`%s[%s]: expected the container %q, got %q`, tt.typeName, tt.title, tt.container, actual)
+			}
+		}
+		if actual := edgeList(cat); actual != `Class[Required] -> Class[Outer]` {
+			t.Errorf(`unexpected edges %s`, actual)
+		}
+	})
+}
+
+func TestInheritedClassScope(t *testing.T) {
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		cat := compile(c, `
+$top = 'top'
+class base($p = 'param') { $v = 'base' }
+class other { notify { 'other': message => $top } }
+class derived inherits base {
+  notify { 'derived': message => [$top, $v, $p, $base::v] }
+  include other
+}
+include derived`)
+
+		// The variables and parameters of the parent class are visible in the inheriting class
+		expectAttributes(t, cat, `Notify`, `derived`, `{'message' => ['top', 'base', 'param', 'base']}`)
+		expectAttributes(t, cat, `Notify`, `other`, `{'message' => 'top'}`)
+
+		// The parent class is declared before the inheriting class
+		if actual := resourceList(cat); actual != `Class[Base], Class[Derived], Notify[derived], Class[Other], Notify[other]` {
+			t.Errorf(`unexpected resources %s`, actual)
+		}
+	})
+
+	// The variables of the parent class are neither visible in a class that is declared by the inheriting class
+	// nor in the global scope
+	for _, source := range []string{
+		`class base { $v = 1 } class other { $x = $v } class derived inherits base { include other } include derived`,
+		`class base { $v = 1 } class derived inherits base {} include derived $x = $v`,
+	} {
+		puppet.Do(func(c pdsl.EvaluationContext) {
+			expectIssue(t, px.UnknownVariable, func() { compile(c, source) })
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package evaluator
+
+import (
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+// collector is created by a collect expression such as File <| owner == root |> { mode => '0644' }
+type collector struct {
+	typeName   string
+	exported   bool
+	matches    func(r pdsl.Resource) bool
+	operations []*attributeOperation
+}
+
+func evalCollectExpression(e pdsl.Evaluator, expr *parser.CollectExpression) px.Value {
+	cl := &collector{typeName: resourceTypeName(e, expr.ResourceType())}
+	if _, ok := expr.Query().(*parser.ExportedQuery); ok {
+		cl.exported = true
+	}
+	cl.matches = queryMatcher(e, expr.Query().(parser.QueryExpression).Expr())
+	cl.operations = evalAttributeOperationList(e, expr.Operations())
+
+	// The collector is added first so that it applies to resources that are declared when
+	// realized defined types are evaluated
+	e.AddCollector(cl)
+	refs := make([]px.Value, 0)
+	if cl.exported {
+		refs = importResources(e, cl, refs)
+	}
+	for _, r := range e.Catalog().Resources() {
+		if cl.Collect(e, r) {
+			refs = append(refs, newResourceType(r.Type(), r.Title()))
+		}
+	}
+	return types.WrapValues(refs)
+}
+
+func (cl *collector) Collect(c pdsl.EvaluationContext, r pdsl.Resource) bool {
+	if r.Type() != cl.typeName || r.Exported() != cl.exported || !cl.matches(r) {
+		return false
+	}
+	if len(cl.operations) > 0 {
+		overrideResource(c, r, cl.operations, true)
+	}
+	realizeResource(c, r)
+	return true
+}
+
+// importResources adds the resources that have been exported by other nodes and match the given collector
+// to the catalog and appends references to them to the given slice. Resources are imported from the
+// ResourceStore found in the context. Nothing is imported when no such store exists.
+func importResources(c pdsl.EvaluationContext, cl *collector, refs []px.Value) []px.Value {
+	store, ok := c.Get(pdsl.ResourceStoreKey)
+	if !ok {
+		return refs
+	}
+	certname := ``
+	if cn, ok := c.Get(pdsl.CertnameKey); ok {
+		certname = cn.(string)
+	}
+	catalog := c.Catalog()
+	dt := loadDefinedType(c, cl.typeName)
+	for _, r := range store.(pdsl.ResourceStore).Exported(cl.typeName, certname) {
+		if _, found := catalog.Resource(r.Type(), r.Title()); found || !cl.matches(r) {
+			continue
+		}
+		ir := NewResource(r.Type(), r.Title(), r.Attributes(), r.Location())
+		if len(cl.operations) > 0 {
+			ir = NewResource(r.Type(), r.Title(), overrideAttributes(ir, cl.operations, true), r.Location())
+		}
+		refs = append(refs, declareResource(c, ir, dt))
+	}
+	return refs
+}
+
+// realizeResource realizes the given resource if it is virtual. A realized instance of a defined type
+// is evaluated.
+func realizeResource(c pdsl.EvaluationContext, r pdsl.Resource) {
+	catalog := c.Catalog()
+	if !catalog.Realize(r) {
+		return
+	}
+	rr, _ := catalog.Resource(r.Type(), r.Title())
+	addMetaParameterEdges(c, rr)
+	if dt := loadDefinedType(c, r.Type()); dt != nil {
+		dt.evaluate(c, newResourceType(r.Type(), r.Title()), rr.Attributes(), r.Location())
+	}
+}
+
+// queryMatcher returns a function that matches resources against the given query. The values of the query
+// are evaluated once, when the function is created.
+func queryMatcher(e pdsl.Evaluator, query parser.Expression) func(r pdsl.Resource) bool {
+	switch q := query.(type) {
+	case nil, *parser.Nop:
+		return func(r pdsl.Resource) bool { return true }
+	case *parser.ParenthesizedExpression:
+		return queryMatcher(e, q.Expr())
+	case *parser.AndExpression:
+		lhs := queryMatcher(e, q.Lhs())
+		rhs := queryMatcher(e, q.Rhs())
+		return func(r pdsl.Resource) bool { return lhs(r) && rhs(r) }
+	case *parser.OrExpression:
+		lhs := queryMatcher(e, q.Lhs())
+		rhs := queryMatcher(e, q.Rhs())
+		return func(r pdsl.Resource) bool { return lhs(r) || rhs(r) }
+	case *parser.ComparisonExpression:
+		var name string
+		if qn, ok := q.Lhs().(*parser.QualifiedName); ok {
+			name = qn.Name()
+		} else {
+			name = e.Eval(q.Lhs()).String()
+		}
+		value := e.Eval(q.Rhs())
+		if q.Operator() == `!=` {
+			return func(r pdsl.Resource) bool { return !attributeMatches(r, name, value) }
+		}
+		return func(r pdsl.Resource) bool { return attributeMatches(r, name, value) }
+	default:
+		panic(evalError(pdsl.UnhandledExpression, query, issue.H{`expression`: query}))
+	}
+}
+
+// attributeMatches returns true if the given attribute of the resource is equal to the given value or, when
+// the attribute is an array, if one of its elements is equal to the given value. The tag attribute matches
+// all tags of the resource.
+func attributeMatches(r pdsl.Resource, name string, value px.Value) bool {
+	var av px.Value
+	switch name {
+	case `title`:
+		av = types.WrapString(r.Title())
+	case `tag`:
+		tags := make([]px.Value, len(r.Tags()))
+		for i, tag := range r.Tags() {
+			tags[i] = types.WrapString(tag)
+		}
+		av = types.WrapValues(tags)
+	default:
+		var ok bool
+		if av, ok = r.Attributes().Get4(name); !ok {
+			return false
+		}
+	}
+	if a, ok := av.(*types.Array); ok {
+		return a.Any(func(v px.Value) bool { return px.PuppetEquals(v, value) })
+	}
+	return px.PuppetEquals(av, value)
+}
@@ -0,0 +1,121 @@
+package evaluator_test
+
+import (
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/evaluator"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+	"github.com/lyraproj/puppet-evaluator/puppet"
+)
+
+func TestResourceDefaults(t *testing.T) {
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		cat := compile(c, `
+File { mode => '0644', owner => 'root' }
+file { '/a': }
+file { '/b': mode => '0600' }
+class cl {
+  File { owner => 'app' }
+  file { '/c': }
+}
+include cl
+file { '/d': }`)
+		expectAttributes(t, cat, `File`, `/a`, `{'mode' => '0644', 'owner' => 'root'}`)
+		expectAttributes(t, cat, `File`, `/b`, `{'mode' => '0600', 'owner' => 'root'}`)
+
+		// Defaults of the declaring scope apply in a class, and the defaults of the class do not leak out of it
+		expectAttributes(t, cat, `File`, `/c`, `{'mode' => '0644', 'owner' => 'app'}`)
+		expectAttributes(t, cat, `File`, `/d`, `{'mode' => '0644', 'owner' => 'root'}`)
+	})
+}
+
+func TestResourceOverrides(t *testing.T) {
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		cat := compile(c, `
+file { ['/a', '/b']: mode => '0644' }
+File['/a'] { owner => 'root' }
+File['/a', '/b'] { group => 'wheel' }
+class base { file { '/c': mode => '0644', owner => 'root', tag => [x] } }
+class derived inherits base {
+  File['/c'] { mode => '0600', owner => undef, tag +> y }
+}
+include derived`)
+		expectAttributes(t, cat, `File`, `/a`, `{'mode' => '0644', 'owner' => 'root', 'group' => 'wheel'}`)
+		expectAttributes(t, cat, `File`, `/b`, `{'mode' => '0644', 'group' => 'wheel'}`)
+
+		// A class that inherits the class that contains a resource may redefine its attributes
+		expectAttributes(t, cat, `File`, `/c`, `{'mode' => '0600', 'tag' => ['x', 'y']}`)
+	})
+	for source, code := range map[string]issue.Code{
+		`file { '/a': mode => '0644' } File['/a'] { mode => '0600' }`:                                                       pdsl.AttributeAlreadySet,
+		`class a { file { '/a': mode => '1' } } include a File['/a'] { mode => '2' }`:                                       pdsl.AttributeAlreadySet,
+		`File['/missing'] { mode => '0600' }`:                                                                               pdsl.UnknownResource,
+		`class a { } class b { file { '/b': mode => '1' } } class c inherits a { File['/b'] { mode => '2' } } include b, c`: pdsl.AttributeAlreadySet,
+	} {
+		puppet.Do(func(c pdsl.EvaluationContext) {
+			expectIssue(t, code, func() { compile(c, source) })
+		})
+	}
+}
+
+func TestVirtualResources(t *testing.T) {
+	for source, expected := range map[string]string{
+		`@notify { ['a', 'b']: }`:                                                                    ``,
+		`@notify { ['a', 'b']: } Notify <| |>`:                                                       `Notify[a], Notify[b]`,
+		`@notify { ['a', 'b']: } Notify <| title == b |>`:                                            `Notify[b]`,
+		`@notify { ['a', 'b']: } Notify <| title != b |>`:                                            `Notify[a]`,
+		`Notify <| |> @notify { ['a', 'b']: }`:                                                       `Notify[a], Notify[b]`,
+		`@notify { a: tag => [x] } @notify { b: } Notify <| tag == x |>`:                             `Notify[a]`,
+		`@notify { a: message => m } @notify { b: } Notify <| message == m or title == b |>`:         `Notify[a], Notify[b]`,
+		`@notify { a: message => [m, n] } @notify { b: } Notify <| message == n and (title == a) |>`: `Notify[a]`,
+		`@@notify { a: } Notify <| |>`:                                                               ``,
+		`@@notify { a: } Notify <<| |>>`:                                                             `Notify[a]`,
+
+		// A realized defined type is evaluated
+		`define d { notify { "in ${title}": } } @d { x: } @d { y: } D <| title == y |>`: `D[y], Notify[in y]`,
+	} {
+		puppet.Do(func(c pdsl.EvaluationContext) {
+			if actual := resourceList(compile(c, source)); actual != expected {
+				t.Errorf(`%s: expected %q, got %q`, source, expected, actual)
+			}
+		})
+	}
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		cat := compile(c, `
+@file { '/a': mode => '0644' }
+file { '/b': }
+File <| title == '/a' or title == '/b' |> { owner => 'root', mode => '0600' }`)
+
+		// A collector may change attributes that have already been set
+		expectAttributes(t, cat, `File`, `/a`, `{'mode' => '0600', 'owner' => 'root'}`)
+		expectAttributes(t, cat, `File`, `/b`, `{'owner' => 'root', 'mode' => '0600'}`)
+	})
+}
+
+func TestExportedResources(t *testing.T) {
+	store := evaluator.NewLocalResourceStore()
+	for _, tt := range []struct{ certname, manifest, expected string }{
+		{`a`, `@@notify { 'from a': tag => [shared] } @@file { '/a': }`, ``},
+		{`b`, `@@notify { 'from b': tag => [shared] } @@notify { 'private b': }`, ``},
+
+		// A node collects resources exported by other nodes and its own exported resources
+		{`c`, `@@notify { 'from c': tag => [shared] } Notify <<| tag == shared |>> { message => collected }`,
+			`Notify[from c], Notify[from a], Notify[from b]`},
+
+		// Exported resources of a node are replaced when its catalog is evaluated again
+		{`a`, `@@file { '/a': }`, ``},
+		{`d`, `Notify <<| |>>`, `Notify[from b], Notify[private b], Notify[from c]`},
+	} {
+		puppet.Do(func(c pdsl.EvaluationContext) {
+			c.Set(pdsl.ResourceStoreKey, store)
+			cat := evaluateSite(c, tt.certname, tt.manifest)
+			if actual := resourceList(cat); actual != tt.expected {
+				t.Errorf(`%s: expected %q, got %q`, tt.certname, tt.expected, actual)
+			}
+			if tt.certname == `c` {
+				expectAttributes(t, cat, `Notify`, `from a`, `{'tag' => ['shared'], 'message' => 'collected'}`)
+			}
+		})
+	}
+}
@@ -1,7 +1,6 @@
 package evaluator
 
 import (
-	"regexp"
 	"strings"
 
 	"github.com/lyraproj/issue/issue"
@@ -97,18 +96,33 @@ func compareMagnitude(expr parser.Expression, op string, a px.Value, b px.Value,
 			}
 		}
 
-	case px.Number:
-		if rhv, ok := b.(px.Number); ok {
-			cmp := a.(px.Number).Float() - rhv.Float()
+	case *types.Timestamp, types.Timespan:
+		if cmp, ok := compareTimes(a, b); ok {
 			switch op {
 			case `<`:
-				return cmp < 0.0
+				return cmp < 0
 			case `<=`:
-				return cmp <= 0.0
+				return cmp <= 0
 			case `>`:
-				return cmp > 0.0
+				return cmp > 0
 			case `>=`:
-				return cmp >= 0.0
+				return cmp >= 0
+			default:
+				panic(evalError(pdsl.OperatorNotApplicable, expr, issue.H{`operator`: op, `left`: a.PType()}))
+			}
+		}
+
+	case px.Number, *BigInteger:
+		if cmp, ok := compareNumbers(a, b); ok {
+			switch op {
+			case `<`:
+				return cmp < 0
+			case `<=`:
+				return cmp <= 0
+			case `>`:
+				return cmp > 0
+			case `>=`:
+				return cmp >= 0
 			default:
 				panic(evalError(pdsl.OperatorNotApplicable, expr, issue.H{`operator`: op, `left`: a.PType()}))
 			}
@@ -120,19 +134,74 @@ func compareMagnitude(expr parser.Expression, op string, a px.Value, b px.Value,
 	panic(evalError(pdsl.OperatorNotApplicableWhen, expr, issue.H{`operator`: op, `left`: a.PType(), `right`: b.PType()}))
 }
 
+// compareNumbers returns -1, 0, or 1 depending on whether a is less than, equal to, or greater than b. Two
+// integers are compared exactly. The returned boolean is false when b is not a number.
+func compareNumbers(a px.Value, b px.Value) (int, bool) {
+	if ab, ok := bigOf(a); ok {
+		if bb, ok := bigOf(b); ok {
+			return ab.Cmp(bb), true
+		}
+	}
+	af, ok := floatOf(a)
+	if !ok {
+		return 0, false
+	}
+	bf, ok := floatOf(b)
+	if !ok {
+		return 0, false
+	}
+	switch {
+	case af < bf:
+		return -1, true
+	case af > bf:
+		return 1, true
+	}
+	return 0, true
+}
+
+// compareTimes compares a Timestamp or a Timespan with a value of the same type or with a number of seconds.
+// The returned boolean is false when b is of any other type.
+func compareTimes(a px.Value, b px.Value) (int, bool) {
+	switch b := b.(type) {
+	case *types.Timestamp:
+		if at, ok := a.(*types.Timestamp); ok {
+			switch t := at.Time(); {
+			case t.Before(b.Time()):
+				return -1, true
+			case t.After(b.Time()):
+				return 1, true
+			}
+			return 0, true
+		}
+	case types.Timespan:
+		if at, ok := a.(types.Timespan); ok {
+			switch {
+			case at < b:
+				return -1, true
+			case at > b:
+				return 1, true
+			}
+			return 0, true
+		}
+	case px.Integer, px.Float, *BigInteger:
+		return compareNumbers(a, b)
+	}
+	return 0, false
+}
+
 func match(c px.Context, lhs parser.Expression, rhs parser.Expression, operator string, a px.Value, b px.Value) bool {
 	result := false
 	switch b := b.(type) {
 	case px.StringValue, *types.Regexp:
-		var rx *regexp.Regexp
+		var rx pdsl.Regexp
 		if s, ok := b.(px.StringValue); ok {
 			var err error
-			rx, err = regexp.Compile(s.String())
+			rx, err = pdsl.CompileRegexp(s.String())
 			if err != nil {
 				panic(px.Error2(rhs, px.MatchNotRegexp, issue.H{`detail`: err.Error()}))
 			}
 		} else {
-			rx = b.(*types.Regexp).Regexp()
+			rx = pdsl.RegexpOf(b.(*types.Regexp))
 		}
 
 		sv, ok := a.(px.StringValue)
@@ -143,6 +212,19 @@ func match(c px.Context, lhs parser.Expression, rhs parser.Expression, operator
 			c.Scope().(pdsl.Scope).RxSet(group)
 			result = true
 		}
+	case *types.PatternType:
+		if pdsl.RubyRegexps() {
+			sv, ok := a.(px.StringValue)
+			result = ok && pdsl.MatchPattern(b, sv.String())
+		} else {
+			result = px.PuppetMatch(a, b)
+		}
+	case px.Type:
+		if bi, ok := a.(*BigInteger); ok {
+			result = matchesBigInteger(bi, b)
+		} else {
+			result = px.PuppetMatch(a, b)
+		}
 	default:
 		result = px.PuppetMatch(a, b)
 	}
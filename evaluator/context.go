@@ -1,7 +1,9 @@
 package evaluator
 
 import (
+	"bytes"
 	"fmt"
+	"sync"
 
 	"github.com/lyraproj/issue/issue"
 	"github.com/lyraproj/pcore/pcore"
@@ -17,9 +19,28 @@ type (
 		px.Context
 
 		evaluator   pdsl.Evaluator
+		catalog     pdsl.Catalog
+		collectors  *collectorList
+		classScopes *scopeMap
+		container   pdsl.Reference
+		defaults    map[string]px.OrderedMap
 		scope       pdsl.Scope
 		static      bool
 		definitions []interface{}
+		nodes       []*parser.NodeDefinition
+		output      *bytes.Buffer
+	}
+
+	// collectorList is shared between forked contexts
+	collectorList struct {
+		lock       sync.RWMutex
+		collectors []pdsl.Collector
+	}
+
+	// scopeMap holds the scopes of evaluated classes. It is shared between forked contexts.
+	scopeMap struct {
+		lock   sync.RWMutex
+		scopes map[string]pdsl.Scope
 	}
 
 	Resolvable interface {
@@ -28,7 +49,7 @@ type (
 )
 
 func NewContext(evaluatorCtor func(c pdsl.EvaluationContext) pdsl.Evaluator, loader px.Loader, logger px.Logger) pdsl.EvaluationContext {
-	c := &evalCtx{Context: pcore.NewContext(loader, logger)}
+	c := &evalCtx{Context: pcore.NewContext(loader, logger), catalog: NewCatalog(), collectors: &collectorList{}, classScopes: newScopeMap()}
 	c.evaluator = evaluatorCtor(c)
 	return c
 }
@@ -39,12 +60,23 @@ func WithParent(parent px.Context, evaluatorCtor func(c pdsl.EvaluationContext)
 		c = cp.clone()
 		c.Context = pcore.WithParent(cp.Context, cp.Loader(), cp.Logger(), cp.ImplementationRegistry())
 	} else {
-		c = &evalCtx{Context: parent}
+		c = &evalCtx{Context: parent, catalog: NewCatalog(), collectors: &collectorList{}, classScopes: newScopeMap()}
 	}
 	c.evaluator = evaluatorCtor(c)
 	return c
 }
 
+func newScopeMap() *scopeMap {
+	return &scopeMap{scopes: make(map[string]pdsl.Scope)}
+}
+
+func (c *evalCtx) AddCollector(collector pdsl.Collector) {
+	cl := c.collectors
+	cl.lock.Lock()
+	cl.collectors = append(cl.collectors, collector)
+	cl.lock.Unlock()
+}
+
 func (c *evalCtx) AddDefinitions(expr parser.Expression) {
 	if p, ok := expr.(*parser.Program); ok {
 		dl := c.DefiningLoader()
@@ -54,6 +86,31 @@ func (c *evalCtx) AddDefinitions(expr parser.Expression) {
 	}
 }
 
+func (c *evalCtx) Catalog() pdsl.Catalog {
+	return c.catalog
+}
+
+func (c *evalCtx) ClassScope(name string) (pdsl.Scope, bool) {
+	m := c.classScopes
+	m.lock.RLock()
+	s, ok := m.scopes[name]
+	m.lock.RUnlock()
+	return s, ok
+}
+
+func (c *evalCtx) Collectors() []pdsl.Collector {
+	cl := c.collectors
+	cl.lock.RLock()
+	cs := make([]pdsl.Collector, len(cl.collectors))
+	copy(cs, cl.collectors)
+	cl.lock.RUnlock()
+	return cs
+}
+
+func (c *evalCtx) Container() pdsl.Reference {
+	return c.container
+}
+
 func (c *evalCtx) DoStatic(doer px.Doer) {
 	if c.static {
 		doer()
@@ -67,10 +124,30 @@ func (c *evalCtx) DoStatic(doer px.Doer) {
 	doer()
 }
 
+func (c *evalCtx) DoWithContainer(container pdsl.Reference, doer px.Doer) {
+	saveContainer := c.container
+	defer func() {
+		c.container = saveContainer
+	}()
+	c.container = container
+	doer()
+}
+
+func (c *evalCtx) DoWithRenderBuffer(buffer *bytes.Buffer, doer px.Doer) {
+	saveOutput := c.output
+	defer func() {
+		c.output = saveOutput
+	}()
+	c.output = buffer
+	doer()
+}
+
 func (c *evalCtx) DoWithScope(scope pdsl.Scope, doer px.Doer) {
 	saveScope := c.scope
+	saveDefaults := c.defaults
 	defer func() {
 		c.scope = saveScope
+		c.defaults = saveDefaults
 	}()
 	c.scope = scope
 	doer()
@@ -99,7 +176,14 @@ func (c *evalCtx) Fork() px.Context {
 }
 
 func (c *evalCtx) ParseAndValidate(filename, str string, singleExpression bool) parser.Expression {
-	var parserOptions []parser.Option
+	return c.parseAndValidate(filename, str, singleExpression)
+}
+
+func (c *evalCtx) ParseAndValidateEpp(filename, str string) parser.Expression {
+	return c.parseAndValidate(filename, str, true, parser.EppMode)
+}
+
+func (c *evalCtx) parseAndValidate(filename, str string, singleExpression bool, parserOptions ...parser.Option) parser.Expression {
 	if pcore.Get(`workflow`, func() px.Value { return types.BooleanFalse }).(px.Boolean).Bool() {
 		parserOptions = append(parserOptions, parser.WorkflowEnabled)
 	}
@@ -116,7 +200,7 @@ func (c *evalCtx) ParseAndValidate(filename, str string, singleExpression bool)
 	if len(issues) > 0 {
 		severity := issue.SeverityIgnore
 		for _, i := range issues {
-			c.Logger().Log(px.LogLevel(i.Severity()), types.WrapString(i.String()))
+			c.Logger().Log(px.LogLevelFromSeverity(i.Severity()), types.WrapString(i.String()))
 			if i.Severity() > severity {
 				severity = i.Severity()
 			}
@@ -128,6 +212,10 @@ func (c *evalCtx) ParseAndValidate(filename, str string, singleExpression bool)
 	return expr
 }
 
+func (c *evalCtx) RenderBuffer() *bytes.Buffer {
+	return c.output
+}
+
 func (c *evalCtx) ResolveDefinitions() []interface{} {
 	if len(c.definitions) == 0 {
 		return []interface{}{}
@@ -161,6 +249,13 @@ func (c *evalCtx) ResolveType(expr parser.Expression) px.Type {
 	panic(fmt.Sprintf(`Expression "%s" does no resolve to a Type`, expr.String()))
 }
 
+func (c *evalCtx) ResourceDefaults(typeName string) px.OrderedMap {
+	if d, ok := c.defaults[typeName]; ok {
+		return d
+	}
+	return px.EmptyMap
+}
+
 func (c *evalCtx) Scope() px.Keyed {
 	if c.scope == nil {
 		c.scope = NewScope(false)
@@ -168,6 +263,23 @@ func (c *evalCtx) Scope() px.Keyed {
 	return c.scope
 }
 
+func (c *evalCtx) SetClassScope(name string, scope pdsl.Scope) {
+	m := c.classScopes
+	m.lock.Lock()
+	m.scopes[name] = scope
+	m.lock.Unlock()
+}
+
+func (c *evalCtx) SetResourceDefaults(typeName string, defaults px.OrderedMap) {
+	// The map is copied so that the defaults of an outer scope can be restored by DoWithScope
+	nd := make(map[string]px.OrderedMap, len(c.defaults)+1)
+	for k, v := range c.defaults {
+		nd[k] = v
+	}
+	nd[typeName] = c.ResourceDefaults(typeName).Merge(defaults)
+	c.defaults = nd
+}
+
 func (c *evalCtx) Static() bool {
 	return c.static
 }
@@ -176,15 +288,25 @@ func (c *evalCtx) define(loader px.DefiningLoader, d parser.Definition) {
 	var ta interface{}
 	var tn px.TypedName
 	switch d := d.(type) {
+	case *parser.NodeDefinition:
+		// Node definitions are not named and cannot be loaded. They are selected by EvaluateSiteManifest
+		c.nodes = append(c.nodes, d)
+		return
 	case *parser.StepExpression:
 		tn = px.NewTypedName2(px.NsStep, d.Name(), loader.NameAuthority())
 		ta = NewPuppetStep(c, d)
 	case *parser.PlanDefinition:
 		tn = px.NewTypedName2(px.NsPlan, d.Name(), loader.NameAuthority())
-		ta = NewPuppetPlan(d)
+		ta = NewPuppetPlan(d, c.Loader())
 	case *parser.FunctionDefinition:
 		tn = px.NewTypedName2(px.NsFunction, d.Name(), loader.NameAuthority())
-		ta = NewPuppetFunction(d)
+		ta = NewPuppetFunction(d, c.Loader())
+	case *parser.HostClassDefinition:
+		tn = px.NewTypedName2(pdsl.NsClass, d.Name(), loader.NameAuthority())
+		ta = NewPuppetClass(d, c.Loader())
+	case *parser.ResourceTypeDefinition:
+		tn = px.NewTypedName2(pdsl.NsDefinedType, d.Name(), loader.NameAuthority())
+		ta = NewPuppetDefinedType(d, c.Loader())
 	default:
 		ta, tn = CreateTypeDefinition(c.evaluator, d, loader.NameAuthority())
 	}
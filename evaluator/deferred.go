@@ -14,7 +14,27 @@ import (
 var deferredExprType px.ObjectType
 
 func init() {
-	deferredExprType = px.NewObjectType(`DeferredExpression`, `{}`)
+	deferredExprType = px.NewObjectType(`DeferredExpression`, `{
+	attributes => {
+	  # The source of the expression
+	  expression => String
+	}
+}`,
+		func(ctx px.Context, args []px.Value) px.Value {
+			return newDeferredExpression(parseSource(args[0].String()))
+		},
+		func(ctx px.Context, args []px.Value) px.Value {
+			return newDeferredExpression(parseSource(args[0].(px.OrderedMap).Get5(`expression`, px.EmptyString).String()))
+		})
+}
+
+// parseSource parses the source of a single expression that has been validated when it was first parsed
+func parseSource(source string) parser.Expression {
+	expr, err := parser.CreateParser().Parse(``, source, true)
+	if err != nil {
+		panic(err)
+	}
+	return expr
 }
 
 func newDeferredExpression(expression parser.Expression) types.Deferred {
@@ -38,7 +58,21 @@ func (d *deferredExpr) String() string {
 }
 
 func (d *deferredExpr) Equals(other interface{}, guard px.Guard) bool {
-	return d == other
+	if o, ok := other.(*deferredExpr); ok {
+		return d == o || d.expression.String() == o.expression.String()
+	}
+	return false
+}
+
+func (d *deferredExpr) Get(key string) (value px.Value, ok bool) {
+	if key == `expression` {
+		return types.WrapString(d.expression.String()), true
+	}
+	return nil, false
+}
+
+func (d *deferredExpr) InitHash() px.OrderedMap {
+	return deferredExprType.InstanceHash(d)
 }
 
 func (d *deferredExpr) ToString(b io.Writer, s px.FormatContext, g px.RDetect) {
@@ -0,0 +1,66 @@
+package evaluator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+type puppetDefinedType struct {
+	expression *parser.ResourceTypeDefinition
+	parameters []px.Parameter
+
+	// loader is the loader that the body of the defined type is evaluated with
+	loader px.Loader
+}
+
+// NewPuppetDefinedType creates a defined type from the given definition. The body of the defined type is
+// evaluated with the given loader.
+func NewPuppetDefinedType(expr *parser.ResourceTypeDefinition, loader px.Loader) *puppetDefinedType {
+	return &puppetDefinedType{expression: expr, loader: loader}
+}
+
+// loadDefinedType returns the defined type with the given name or nil if no such type can be found
+func loadDefinedType(c pdsl.EvaluationContext, typeName string) *puppetDefinedType {
+	if dt, ok := px.Load(c, px.NewTypedName2(pdsl.NsDefinedType, strings.ToLower(typeName), c.Loader().NameAuthority())); ok {
+		return dt.(*puppetDefinedType)
+	}
+	return nil
+}
+
+func (dt *puppetDefinedType) Name() string {
+	return dt.expression.Name()
+}
+
+func (dt *puppetDefinedType) Parameters() []px.Parameter {
+	return dt.parameters
+}
+
+func (dt *puppetDefinedType) Resolve(c px.Context) {
+	if dt.parameters != nil {
+		panic(fmt.Sprintf(`Attempt to resolve already resolved defined type %s`, dt.Name()))
+	}
+	dt.parameters = resolveParameters(c.(pdsl.EvaluationContext), dt.expression.Parameters())
+}
+
+func (dt *puppetDefinedType) String() string {
+	return `define ` + dt.Name()
+}
+
+// evaluate evaluates the body of the defined type for the instance with the given reference. The resources
+// declared by the body are contained by the instance.
+func (dt *puppetDefinedType) evaluate(c pdsl.EvaluationContext, ref pdsl.Reference, args px.OrderedMap, location issue.Location) {
+	scope := NewNamedScope(``, globalScope(c.Scope().(pdsl.Scope)))
+	c.DoWithLoader(dt.loader, func() {
+		c.DoWithScope(scope, func() {
+			c.DoWithContainer(ref, func() {
+				bindParameters(c, ref, ref.Title(), dt.parameters, args, location)
+				pdsl.Evaluate(c, dt.expression.Body())
+			})
+		})
+	})
+}
@@ -0,0 +1,92 @@
+package evaluator_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+	"github.com/lyraproj/puppet-evaluator/puppet"
+)
+
+func TestDefinedTypeExpansion(t *testing.T) {
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		cat := compile(c, `
+define vhost(Integer $port, String $docroot = "/var/www/${title}") {
+  file { $docroot: ensure => directory }
+  notify { "${title} ${name} ${port}": }
+}
+vhost { ['a', 'b']: port => 80 }
+vhost { 'c': name => 'renamed', port => 8080, docroot => '/srv/c' }`)
+
+		if actual := resourceList(cat); actual != `Vhost[a], File[/var/www/a], Notify[a a 80], Vhost[b], File[/var/www/b], Notify[b b 80], `+
+			`Vhost[c], File[/srv/c], Notify[c renamed 8080]` {
+			t.Errorf(`unexpected resources %s`, actual)
+		}
+		expectAttributes(t, cat, `Vhost`, `a`, `{'port' => 80}`)
+
+		// The resources of an instance are contained by the instance
+		for _, tt := range []struct{ typeName, title, container string }{
+			{`File`, `/var/www/a`, `Vhost[a]`},
+			{`Notify`, `b b 80`, `Vhost[b]`},
+			{`File`, `/srv/c`, `Vhost[c]`},
+			{`Vhost`, `a`, ``},
+		} {
+			if actual := containerOf(cat, tt.typeName, tt.title); actual != tt.container {
+				t.Errorf(`%s[%s]: expected the container %q, got %q`, tt.typeName, tt.title, tt.container, actual)
+			}
+		}
+	})
+}
+
+func TestDefinedTypeInClass(t *testing.T) {
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		cat := compile(c, `
+define wrapper { inner { "${title}/inner": } }
+define inner { notify { $title: } }
+class holder { wrapper { 'w': } }
+include holder`)
+		for _, tt := range []struct{ typeName, title, container string }{
+			{`Wrapper`, `w`, `Class[Holder]`},
+			{`Inner`, `w/inner`, `Wrapper[w]`},
+			{`Notify`, `w/inner`, `Inner[w/inner]`},
+		} {
+			if actual := containerOf(cat, tt.typeName, tt.title); actual != tt.container {
+				t.Errorf(`%s[%s]: expected the container %q, got %q`, tt.typeName, tt.title, tt.container, actual)
+			}
+		}
+	})
+}
+
+func TestDefinedTypeFromModule(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		`modules/web/manifests/vhost.pp`:       `define web::vhost(Integer $port) { notify { "${title}:${port}": } }`,
+		`modules/web/manifests/site/config.pp`: `define web::site::config { notify { "config ${title}": } }`,
+		`modules/web/manifests/init.pp`:        `class web { web::vhost { 'default': port => 80 } }`,
+	})
+	defer os.RemoveAll(dir)
+	puppet.DoWithEnvironment(dir, func(c pdsl.EvaluationContext) {
+		cat := compile(c, `
+web::vhost { 'x': port => 8080 }
+Web::Site::Config { 'y': }
+include web`)
+		if actual := resourceList(cat); actual != `Web::Vhost[x], Notify[x:8080], Web::Site::Config[y], Notify[config y], `+
+			`Class[Web], Web::Vhost[default], Notify[default:80]` {
+			t.Errorf(`unexpected resources %s`, actual)
+		}
+	})
+}
+
+func TestDefinedTypeErrors(t *testing.T) {
+	for source, code := range map[string]issue.Code{
+		`define d(Integer $x) {} d { 'a': }`:             pdsl.MissingParameter,
+		`define d(Integer $x) {} d { 'a': x => '1' }`:    pdsl.ParameterTypeMismatch,
+		`define d {} d { 'a': y => 1 }`:                  pdsl.UnknownParameter,
+		`define d { notify { 'n': } } d { ['a', 'b']: }`: pdsl.DuplicateResource,
+		`define d {} d { 'a': } d { 'a': }`:              pdsl.DuplicateResource,
+	} {
+		puppet.Do(func(c pdsl.EvaluationContext) {
+			expectIssue(t, code, func() { compile(c, source) })
+		})
+	}
+}
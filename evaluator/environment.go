@@ -0,0 +1,182 @@
+package evaluator
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+// EnvironmentKey is the context variable that holds the *Environment that is being evaluated
+const EnvironmentKey = `puppet.environment`
+
+// EnvironmentConfFile is the name of the configuration file of an environment
+const EnvironmentConfFile = `environment.conf`
+
+// An Environment is a directory that contains the site manifest, the modules, and the data of a Puppet
+// environment
+type Environment struct {
+	// Name is the name of the environment, i.e. the name of its directory
+	Name string
+
+	// Path is the directory of the environment
+	Path string
+
+	// ModulePath is the modulepath of the environment. Its directories are absolute.
+	ModulePath string
+
+	// Manifest is the absolute path of the site manifest, which is either a file or a directory
+	Manifest string
+
+	// ConfigVersion is the command that outputs the version of the catalogs compiled in the environment,
+	// or an empty string
+	ConfigVersion string
+}
+
+// environmentPathTypes are the kinds of content that are loaded from the environment directory. Unlike a
+// module, the manifests directory of an environment holds the site manifest.
+var environmentPathTypes = []px.PathType{px.PuppetFunctionPath, px.PuppetDataTypePath, px.PlanPath, px.TaskPath}
+
+// LoadEnvironment reads the environment.conf of the environment in the given directory. The file is optional.
+// Its settings are modulepath, which defaults to "modules:$basemodulepath", manifest, which defaults to
+// "manifests", and config_version. Relative paths are relative to the environment directory. The variable
+// $basemodulepath expands to the value of the modulepath setting. Other settings are ignored.
+func LoadEnvironment(path string) *Environment {
+	path, err := filepath.Abs(path)
+	if err == nil {
+		var fi os.FileInfo
+		if fi, err = os.Stat(path); err == nil && !fi.IsDir() {
+			err = &os.PathError{Op: `stat`, Path: path, Err: os.ErrInvalid}
+		}
+	}
+	if err != nil {
+		panic(px.Error(pdsl.UnknownEnvironment, issue.H{`path`: path, `detail`: err.Error()}))
+	}
+
+	conf := map[string]string{
+		`modulepath`: `modules` + string(filepath.ListSeparator) + `$basemodulepath`,
+		`manifest`:   `manifests`}
+	readEnvironmentConf(filepath.Join(path, EnvironmentConfFile), conf)
+
+	base, _ := pdsl.ModulePath()
+	dirs := make([]string, 0)
+	for _, dir := range filepath.SplitList(strings.Replace(conf[`modulepath`], `$basemodulepath`, base, -1)) {
+		if dir != `` {
+			dirs = append(dirs, absolute(path, dir))
+		}
+	}
+	return &Environment{
+		Name:          filepath.Base(path),
+		Path:          path,
+		ModulePath:    strings.Join(dirs, string(filepath.ListSeparator)),
+		Manifest:      absolute(path, conf[`manifest`]),
+		ConfigVersion: conf[`config_version`]}
+}
+
+// readEnvironmentConf reads the settings of the given environment.conf into the given map. A missing file is
+// not an error.
+func readEnvironmentConf(path string, conf map[string]string) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return
+		}
+		panic(px.Error(pdsl.BadEnvironmentConf, issue.H{`path`: path, `detail`: err.Error()}))
+	}
+	s := bufio.NewScanner(bytes.NewReader(content))
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == `` || text[0] == '#' || text[0] == ';' || text[0] == '[' {
+			continue
+		}
+		eq := strings.IndexByte(text, '=')
+		if eq <= 0 {
+			panic(px.Error2(issue.NewLocation(path, line, 0), pdsl.BadEnvironmentConf,
+				issue.H{`path`: path, `detail`: `expected a setting in the form <name> = <value>`}))
+		}
+		conf[strings.TrimSpace(text[:eq])] = strings.Trim(strings.TrimSpace(text[eq+1:]), `"'`)
+	}
+}
+
+func absolute(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(dir, path)
+}
+
+// NewLoader creates the loader of the environment. It loads functions, types, plans, and tasks from the
+// environment directory and sees all modules on the modulepath of the environment.
+func (e *Environment) NewLoader(c px.Context, parent px.Loader) px.Loader {
+	return px.NewFileBasedLoader(NewModulePathLoader(c, parent, e.ModulePath), e.Path, `environment`, environmentPathTypes...)
+}
+
+// SiteManifest parses and validates the site manifest of the environment. When the manifest is a directory,
+// its .pp files and the files of its subdirectories are parsed in sorted order and combined into one
+// program. A missing manifest is an empty program.
+func (e *Environment) SiteManifest(c pdsl.EvaluationContext) parser.Expression {
+	files := make([]string, 0)
+	err := filepath.Walk(e.Manifest, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && (path == e.Manifest || strings.HasSuffix(path, `.pp`)) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		panic(px.Error(px.UnableToReadFile, issue.H{`path`: e.Manifest, `detail`: err.Error()}))
+	}
+	sort.Strings(files)
+
+	if len(files) == 1 {
+		return parseFile(c, files[0])
+	}
+	bodies := make([]parser.Expression, len(files))
+	definitions := make([]parser.Definition, 0)
+	for i, file := range files {
+		program := parseFile(c, file).(*parser.Program)
+		bodies[i] = program.Body()
+		definitions = append(definitions, program.Definitions()...)
+	}
+	f := parser.DefaultFactory()
+	locator := parser.NewLocator(e.Manifest, ``)
+	return f.Program(f.Block(bodies, locator, 0, 0), definitions, locator, 0, 0)
+}
+
+func parseFile(c pdsl.EvaluationContext, path string) parser.Expression {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		panic(px.Error(px.UnableToReadFile, issue.H{`path`: path, `detail`: err.Error()}))
+	}
+	return c.ParseAndValidate(path, string(content), false)
+}
+
+// Version returns the version of a catalog compiled in the environment. It is the output of the
+// config_version command when the environment has one, and otherwise the current time in seconds since the
+// epoch. The command is executed in the environment directory.
+func (e *Environment) Version() px.Value {
+	args := strings.Fields(e.ConfigVersion)
+	if len(args) == 0 {
+		return types.WrapInteger(time.Now().Unix())
+	}
+	cmd := exec.Command(absolute(e.Path, args[0]), args[1:]...)
+	cmd.Dir = e.Path
+	out, err := cmd.Output()
+	if err != nil {
+		panic(px.Error(pdsl.ConfigVersionFailed, issue.H{`environment`: e.Name, `command`: e.ConfigVersion, `detail`: err.Error()}))
+	}
+	return types.WrapString(strings.TrimSpace(string(out)))
+}
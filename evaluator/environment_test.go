@@ -0,0 +1,139 @@
+package evaluator_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/puppet-evaluator/evaluator"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+	"github.com/lyraproj/puppet-evaluator/puppet"
+)
+
+// evaluateEnvironment evaluates the site manifest of the environment of the given context for the given
+// certname and returns the resulting catalog
+func evaluateEnvironment(c pdsl.EvaluationContext, certname string) pdsl.Catalog {
+	env, _ := c.Get(evaluator.EnvironmentKey)
+	return c.EvaluateSiteManifest(certname, env.(*evaluator.Environment).SiteManifest(c))
+}
+
+func TestEnvironmentConf(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		`production/environment.conf`: `
+# The modules of the site come first
+[main]
+modulepath = site:modules:/opt/modules:$basemodulepath
+manifest = "site.pp"
+  config_version = '/bin/echo v1'
+unknown_setting = ignored
+`,
+		`empty/manifests/site.pp`: ``,
+	})
+	defer os.RemoveAll(dir)
+
+	env := evaluator.LoadEnvironment(filepath.Join(dir, `production`))
+	path := filepath.Join(dir, `production`)
+	for _, tt := range []struct{ name, expected, actual string }{
+		{`Name`, `production`, env.Name},
+		{`Path`, path, env.Path},
+		{`ModulePath`, strings.Join([]string{filepath.Join(path, `site`), filepath.Join(path, `modules`), `/opt/modules`},
+			string(filepath.ListSeparator)), env.ModulePath},
+		{`Manifest`, filepath.Join(path, `site.pp`), env.Manifest},
+		{`ConfigVersion`, `/bin/echo v1`, env.ConfigVersion},
+	} {
+		if tt.actual != tt.expected {
+			t.Errorf(`%s: expected %q, got %q`, tt.name, tt.expected, tt.actual)
+		}
+	}
+
+	// The environment.conf is optional
+	env = evaluator.LoadEnvironment(filepath.Join(dir, `empty`))
+	path = filepath.Join(dir, `empty`)
+	if env.ModulePath != filepath.Join(path, `modules`) || env.Manifest != filepath.Join(path, `manifests`) || env.ConfigVersion != `` {
+		t.Errorf(`unexpected defaults %q, %q, %q`, env.ModulePath, env.Manifest, env.ConfigVersion)
+	}
+}
+
+func TestEnvironmentErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		`bad/environment.conf`: "modulepath = modules\n\nnot a setting\n",
+		`file`:                 ``,
+	})
+	defer os.RemoveAll(dir)
+
+	ri := expectIssue(t, pdsl.BadEnvironmentConf, func() { evaluator.LoadEnvironment(filepath.Join(dir, `bad`)) })
+	if ri != nil && ri.Location().Line() != 3 {
+		t.Errorf(`expected %s on line 3, got line %d`, pdsl.BadEnvironmentConf, ri.Location().Line())
+	}
+	for _, name := range []string{`missing`, `file`} {
+		expectIssue(t, pdsl.UnknownEnvironment, func() { evaluator.LoadEnvironment(filepath.Join(dir, name)) })
+	}
+}
+
+func TestEnvironmentManifestDirectory(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		`manifests/b.pp`:               `notify { b: } include k`,
+		`manifests/a.pp`:               `notify { a: } $x = 1`,
+		`manifests/sub/c.pp`:           `class k { notify { "k${x}": } } notify { c: }`,
+		`manifests/a.pp.orig`:          `notify { orig: }`,
+		`manifests/nodes.pp`:           `node default { notify { in_node: } }`,
+		`functions/env.pp`:             `function env() { mod::mod() }`,
+		`modules/mod/functions/mod.pp`: `function mod::mod() { 'from mod' }`,
+	})
+	defer os.RemoveAll(dir)
+	puppet.DoWithEnvironment(dir, func(c pdsl.EvaluationContext) {
+		// The .pp files of the manifest directory and its subdirectories are evaluated in sorted order and
+		// the definitions of all files are visible to each file. The matching node is evaluated last.
+		expected := `Notify[a], Notify[b], Class[K], Notify[k1], Notify[c], Node[default], Notify[in_node]`
+		if actual := resourceList(evaluateEnvironment(c, `x`)); actual != expected {
+			t.Errorf("expected %s\ngot %s", expected, actual)
+		}
+
+		// The environment loads functions from the environment directory and from its modules
+		expectValues(t, c, map[string]string{`env()`: `from mod`, `mod::mod()`: `from mod`})
+	})
+}
+
+func TestEnvironmentManifestFile(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		`environment.conf`:   `manifest = site.pp`,
+		`site.pp`:            `notify { 'site': }`,
+		`manifests/other.pp`: `notify { other: }`,
+	})
+	defer os.RemoveAll(dir)
+	puppet.DoWithEnvironment(dir, func(c pdsl.EvaluationContext) {
+		if actual := resourceList(evaluateEnvironment(c, `x`)); actual != `Notify[site]` {
+			t.Errorf(`expected Notify[site], got %s`, actual)
+		}
+	})
+
+	// A missing manifest is an empty program
+	dir = writeFiles(t, map[string]string{})
+	defer os.RemoveAll(dir)
+	puppet.DoWithEnvironment(dir, func(c pdsl.EvaluationContext) {
+		if actual := resourceList(evaluateEnvironment(c, `x`)); actual != `` {
+			t.Errorf(`expected no resources, got %s`, actual)
+		}
+	})
+}
+
+func TestEnvironmentVersion(t *testing.T) {
+	dir := writeFiles(t, map[string]string{`environment.conf`: `config_version = /bin/echo v1`})
+	defer os.RemoveAll(dir)
+	if actual := evaluator.LoadEnvironment(dir).Version().String(); actual != `v1` {
+		t.Errorf(`expected v1, got %s`, actual)
+	}
+
+	// The version is the time of the compilation when no config_version is set
+	dir = writeFiles(t, map[string]string{})
+	defer os.RemoveAll(dir)
+	if _, ok := evaluator.LoadEnvironment(dir).Version().(px.Integer); !ok {
+		t.Errorf(`expected an Integer version when no config_version is set`)
+	}
+
+	dir = writeFiles(t, map[string]string{`environment.conf`: `config_version = /no/such/command`})
+	defer os.RemoveAll(dir)
+	expectIssue(t, pdsl.ConfigVersionFailed, func() { evaluator.LoadEnvironment(dir).Version() })
+}
@@ -0,0 +1,85 @@
+package evaluator
+
+import (
+	"bytes"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+// RenderTemplate parses the given EPP source and renders it using the given arguments. When callerScope is
+// false, the template is evaluated in a scope that has access to global variables but not to the local
+// variables of the caller. This is how the epp function renders a template file. When callerScope is true,
+// the template is evaluated in a local scope of the caller, like a lambda, and has access to the variables
+// of the caller. This is how the inline_epp function renders a template. Variables that are assigned by the
+// template are not visible to the caller in either case.
+//
+// When the template declares parameters, the arguments are bound to those parameters and missing arguments
+// are replaced by the default values of the parameters. Otherwise, each argument is assigned to a variable
+// with the same name.
+func RenderTemplate(c pdsl.EvaluationContext, name, source string, args px.OrderedMap, callerScope bool) px.Value {
+	template := c.ParseAndValidateEpp(name, source).(*parser.LambdaExpression)
+	params := resolveParameters(c, template.Parameters())
+	render := func() px.Value {
+		if len(params) == 0 {
+			scope := c.Scope().(pdsl.Scope)
+			args.EachPair(func(k, v px.Value) {
+				if reservedVariables[k.String()] {
+					panic(evalError(pdsl.IllegalReservedAssignment, template, issue.H{`var`: k.String()}))
+				}
+				scope.Set(k.String(), v)
+			})
+		} else {
+			owner := `EPP template '` + name + `'`
+			args.EachKey(func(k px.Value) {
+				for _, p := range params {
+					if p.Name() == k.String() {
+						return
+					}
+				}
+				panic(evalError(pdsl.UnknownParameter, template, issue.H{`resource`: owner, `name`: k.String()}))
+			})
+			assignParameters(c, owner, params, args, template)
+		}
+		return pdsl.Evaluate(c, template.Body())
+	}
+
+	if callerScope {
+		return c.Scope().(pdsl.Scope).WithLocalScope(render)
+	}
+	var result px.Value
+	c.DoWithScope(NewNamedScope(``, globalScope(c.Scope().(pdsl.Scope))), func() { result = render() })
+	return result
+}
+
+func evalEppExpression(e pdsl.Evaluator, expr *parser.EppExpression) px.Value {
+	buffer := bytes.NewBufferString(``)
+	e.DoWithRenderBuffer(buffer, func() {
+		e.Eval(expr.Body())
+	})
+	return types.WrapString(buffer.String())
+}
+
+func evalRenderExpression(e pdsl.Evaluator, expr *parser.RenderExpression) px.Value {
+	if v := e.Eval(expr.Expr()); v != px.Undef {
+		render(e, expr, v.String())
+	}
+	return px.Undef
+}
+
+func evalRenderStringExpression(e pdsl.Evaluator, expr *parser.RenderStringExpression) px.Value {
+	render(e, expr, expr.StringValue())
+	return px.Undef
+}
+
+// render writes the given text to the render buffer of the evaluator
+func render(e pdsl.Evaluator, expr parser.Expression, text string) {
+	buffer := e.RenderBuffer()
+	if buffer == nil {
+		panic(evalError(pdsl.EppRenderOutsideTemplate, expr, issue.NoArgs))
+	}
+	buffer.WriteString(text)
+}
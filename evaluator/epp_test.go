@@ -0,0 +1,99 @@
+package evaluator_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/puppet-evaluator/evaluator"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+	"github.com/lyraproj/puppet-evaluator/puppet"
+)
+
+// withModules writes the given files to a temporary modules directory and calls the given function with an
+// evaluation context that loads from the modules in that directory. The path of the directory is passed
+// to the function.
+func withModules(t *testing.T, files map[string]string, f func(c pdsl.EvaluationContext, dir string)) {
+	t.Helper()
+	dir := writeFiles(t, files)
+	defer os.RemoveAll(dir)
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		c.DoWithLoader(evaluator.NewModulePathLoader(c, c.Loader(), dir), func() { f(c, dir) })
+	})
+}
+
+func TestEppParameters(t *testing.T) {
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		typed := `'<%- | String $x, Integer $y = 2 | -%><%= $x %>-<%= $y %>'`
+		expectValues(t, c, map[string]string{
+			`inline_epp(` + typed + `, { x => a })`:                   `a-2`,
+			`inline_epp(` + typed + `, { x => a, y => 3 })`:           `a-3`,
+			`inline_epp('<%= $x %>', { x => a })`:                     `a`,
+			`inline_epp('<%- |$x| -%>[<%= $x %>]', {x => 1})`:         `[1]`,
+			"inline_epp('a\n<%- 1 -%>\nb')":                           "a\nb",
+			`inline_epp('<%# comment %>x<%% y %%>')`:                  `x<% y %>`,
+			`inline_epp('<% [1, 2].each |$i| { %><%= $i %>,<% } %>')`: `1,2,`,
+		})
+		for source, code := range map[string]issue.Code{
+			`inline_epp(` + typed + `)`:                     pdsl.MissingParameter,
+			`inline_epp(` + typed + `, { x => 1 })`:         pdsl.ParameterTypeMismatch,
+			`inline_epp(` + typed + `, { x => a, y => b })`: pdsl.ParameterTypeMismatch,
+			`inline_epp(` + typed + `, { x => a, z => 1 })`: pdsl.UnknownParameter,
+			`inline_epp('<%= $x %>', { trusted => 1 })`:     pdsl.IllegalReservedAssignment,
+		} {
+			expectIssue(t, code, func() { evaluate(c, source) })
+		}
+	})
+}
+
+func TestEppScope(t *testing.T) {
+	withModules(t, map[string]string{
+		`m/templates/show.epp`: `<%= $v %>`,
+	}, func(c pdsl.EvaluationContext, dir string) {
+		for source, expected := range map[string]string{
+			// An inline template sees the variables of the caller
+			`function f1() { $v = local inline_epp('<%= $v %>') } f1()`: `local`,
+
+			// A template file sees global variables but not the variables of the caller
+			`$v = global function f2() { $v = local epp('m/show.epp') } f2()`:   `global`,
+			`function f3() { $v = local epp('m/show.epp', { v => arg }) } f3()`: `arg`,
+
+			// The arguments of a template do not change the variables of the caller
+			`function f4() { $v = local $r = epp('m/show.epp', { v => arg }) "${r}:${v}" } f4()`:       `arg:local`,
+			`function f5() { $v = local $r = inline_epp('<%= $v %>', { v => arg }) "${r}:${v}" } f5()`: `arg:local`,
+		} {
+			if actual := evaluateProgram(c, source).String(); actual != expected {
+				t.Errorf(`%s: expected %s, got %s`, source, expected, actual)
+			}
+		}
+
+		// The arguments of a template are not visible to the caller
+		for _, source := range []string{
+			`function f6() { epp('m/show.epp', { v => 1, w => 2 }) $w } f6()`,
+			`function f7() { inline_epp('<%= $w %>', { w => 1 }) $w } f7()`,
+			`function f8() { inline_epp('<%- |$x| -%><%= $x %>', { x => 1 }) $x } f8()`,
+		} {
+			expectIssue(t, px.UnknownVariable, func() { evaluateProgram(c, source) })
+		}
+	})
+}
+
+func TestEppTemplateFiles(t *testing.T) {
+	withModules(t, map[string]string{
+		`m/templates/greeting.epp`: `<%- | String $name | -%>Hello <%= $name %>`,
+		`m/templates/sub/x.epp`:    `x`,
+		`n/templates/n.epp`:        `n`,
+	}, func(c pdsl.EvaluationContext, dir string) {
+		expectValues(t, c, map[string]string{
+			`epp('m/greeting.epp', { name => world })`: `Hello world`,
+			`epp('m/sub/x.epp')`:                       `x`,
+			`epp('n/n.epp')`:                           `n`,
+			`epp('` + filepath.ToSlash(filepath.Join(dir, `n`, `templates`, `n.epp`)) + `')`: `n`,
+		})
+		for _, name := range []string{`m/missing.epp`, `missing/x.epp`, `x.epp`, `/no/such/template.epp`} {
+			expectIssue(t, pdsl.UnknownTemplate, func() { evaluate(c, `epp('`+name+`')`) })
+		}
+	})
+}
@@ -301,7 +301,7 @@ func evalQualifiedReference(e pdsl.Evaluator, expr *parser.QualifiedReference) p
 }
 
 func evalRegexpExpression(expr *parser.RegexpExpression) px.Value {
-	return types.WrapRegexp(expr.PatternString())
+	return pdsl.WrapRegexp(expr.PatternString())
 }
 
 func evalCaseExpression(e pdsl.Evaluator, expr *parser.CaseExpression) px.Value {
@@ -479,8 +479,12 @@ func BasicEval(e pdsl.Evaluator, expr parser.Expression) px.Value {
 		return evalCallNamedFunctionExpression(e, ex)
 	case *parser.CaseExpression:
 		return evalCaseExpression(e, ex)
+	case *parser.CollectExpression:
+		return evalCollectExpression(e, ex)
 	case *parser.ConcatenatedString:
 		return evalConcatenatedString(e, ex)
+	case *parser.EppExpression:
+		return evalEppExpression(e, ex)
 	case *parser.IfExpression:
 		return evalIfExpression(e, ex)
 	case *parser.LambdaExpression:
@@ -491,9 +495,21 @@ func BasicEval(e pdsl.Evaluator, expr parser.Expression) px.Value {
 		return evalParameter(e, ex)
 	case *parser.Program:
 		return evalProgram(e, ex)
+	case *parser.RelationshipExpression:
+		return evalRelationshipExpression(e, ex)
+	case *parser.RenderExpression:
+		return evalRenderExpression(e, ex)
+	case *parser.RenderStringExpression:
+		return evalRenderStringExpression(e, ex)
+	case *parser.ResourceDefaultsExpression:
+		return evalResourceDefaultsExpression(e, ex)
+	case *parser.ResourceExpression:
+		return evalResourceExpression(e, ex)
+	case *parser.ResourceOverrideExpression:
+		return evalResourceOverrideExpression(e, ex)
 	case *parser.SelectorExpression:
 		return evalSelectorExpression(e, ex)
-	case *parser.FunctionDefinition, *parser.PlanDefinition, *parser.StepExpression, *parser.TypeAlias, *parser.TypeMapping:
+	case *parser.FunctionDefinition, *parser.HostClassDefinition, *parser.NodeDefinition, *parser.PlanDefinition, *parser.ResourceTypeDefinition, *parser.StepExpression, *parser.TypeAlias, *parser.TypeMapping:
 		// All definitions must be processed at this time
 		return px.Undef
 	case *parser.UnfoldExpression:
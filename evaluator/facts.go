@@ -0,0 +1,102 @@
+package evaluator
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/serialization"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/pcore/yaml"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+)
+
+// rubyObjectTag matches the tag that 'puppet facts --render-as yaml' adds to the document
+var rubyObjectTag = regexp.MustCompile(`\A---\s+!ruby/object:\S+`)
+
+func (c *evalCtx) LoadFacts(path string, trusted px.OrderedMap, legacy bool) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		panic(px.Error(pdsl.BadFactsFile, issue.H{`path`: path, `detail`: err}))
+	}
+	var data px.Value
+	switch strings.ToLower(filepath.Ext(path)) {
+	case `.yaml`, `.yml`:
+		data = yaml.Unmarshal(c, rubyObjectTag.ReplaceAll(content, []byte(`---`)))
+	default:
+		collector := px.NewCollector()
+		serialization.JsonToData(path, bytes.NewReader(content), collector)
+		data = collector.Value()
+	}
+	facts, ok := data.(px.OrderedMap)
+	if !ok {
+		panic(px.Error(pdsl.BadFactsFile, issue.H{`path`: path, `detail`: `the file must contain a hash`}))
+	}
+	if values, ok := facts.Get4(`values`); ok && facts.IncludesKey2(`name`) {
+		// Output from 'puppet facts' has the facts in a values hash
+		if facts, ok = values.(px.OrderedMap); !ok {
+			panic(px.Error(pdsl.BadFactsFile, issue.H{`path`: path, `detail`: `the 'values' entry must be a hash`}))
+		}
+	}
+	c.SetFacts(facts, trusted, legacy)
+}
+
+func (c *evalCtx) SetFacts(facts px.OrderedMap, trusted px.OrderedMap, legacy bool) {
+	if trusted == nil {
+		trusted = trustedInformation(c.certname(facts))
+	}
+	scope := c.Scope().(pdsl.Scope)
+	setGlobal(scope, `facts`, facts)
+	setGlobal(scope, `trusted`, trusted)
+	if legacy {
+		facts.EachPair(func(k, v px.Value) {
+			if name := k.String(); !reservedVariables[name] {
+				// A fact never replaces a global variable that has already been assigned
+				scope.Set(`::`+name, v)
+			}
+		})
+	}
+}
+
+// certname returns the certname of the node that is being evaluated. Unless it has been assigned to
+// this context, it is derived from the given facts.
+func (c *evalCtx) certname(facts px.OrderedMap) string {
+	if cn, ok := c.Get(pdsl.CertnameKey); ok {
+		return cn.(string)
+	}
+	for _, path := range [][]string{{`clientcert`}, {`networking`, `fqdn`}, {`fqdn`}} {
+		var v px.Value = facts
+		for _, key := range path {
+			h, ok := v.(px.OrderedMap)
+			if !ok {
+				v = nil
+				break
+			}
+			v = h.Get5(key, nil)
+		}
+		if s, ok := v.(px.StringValue); ok && s.String() != `` {
+			return s.String()
+		}
+	}
+	return ``
+}
+
+// trustedInformation returns the $trusted hash that Puppet creates for a node that is evaluated locally
+func trustedInformation(certname string) px.OrderedMap {
+	hostname, domain := certname, ``
+	if i := strings.IndexByte(certname, '.'); i >= 0 {
+		hostname, domain = certname[:i], certname[i+1:]
+	}
+	return types.WrapHash([]*types.HashEntry{
+		types.WrapHashEntry2(`authenticated`, types.WrapString(`local`)),
+		types.WrapHashEntry2(`certname`, types.WrapString(certname)),
+		types.WrapHashEntry2(`domain`, types.WrapString(domain)),
+		types.WrapHashEntry2(`extensions`, px.EmptyMap),
+		types.WrapHashEntry2(`external`, px.EmptyMap),
+		types.WrapHashEntry2(`hostname`, types.WrapString(hostname)),
+	})
+}
@@ -0,0 +1,153 @@
+package evaluator_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+	"github.com/lyraproj/puppet-evaluator/puppet"
+)
+
+// writeFiles creates a temporary directory that contains the given files and returns its path. The keys
+// are slash separated paths relative to the directory.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir(``, `evaluator`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err = ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// expectIssue calls the given function and asserts that it panics with an issue that has the given code.
+// The issue is returned.
+func expectIssue(t *testing.T, code issue.Code, f func()) (ri issue.Reported) {
+	t.Helper()
+	defer func() {
+		var ok bool
+		r := recover()
+		if ri, ok = r.(issue.Reported); !ok || ri.Code() != code {
+			t.Errorf(`expected %s, got %v`, code, r)
+		}
+	}()
+	f()
+	return
+}
+
+// evaluateProgram returns the result of evaluating the given source after adding the definitions that it
+// contains
+func evaluateProgram(c pdsl.EvaluationContext, source string) px.Value {
+	expr := c.ParseAndValidate(`test.pp`, source, false)
+	c.AddDefinitions(expr)
+	return pdsl.TopEvaluate(c, expr)
+}
+
+// expectValues asserts that each of the given sources evaluates to a value with the expected string form
+func expectValues(t *testing.T, c pdsl.EvaluationContext, expected map[string]string) {
+	t.Helper()
+	for source, e := range expected {
+		if actual := evaluate(c, source); actual.String() != e {
+			t.Errorf(`%s: expected %s, got %s`, source, e, actual)
+		}
+	}
+}
+
+func TestLoadFactsJSON(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		`facts.json`: `{"kernel": "Linux", "os": {"family": "Debian"}, "networking": {"fqdn": "web01.example.com"}}`,
+	})
+	defer os.RemoveAll(dir)
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		c.LoadFacts(filepath.Join(dir, `facts.json`), nil, false)
+		expectValues(t, c, map[string]string{
+			`$facts['os']['family']`:    `Debian`,
+			`$facts['kernel']`:          `Linux`,
+			`$trusted['authenticated']`: `local`,
+			`$trusted['certname']`:      `web01.example.com`,
+			`$trusted['hostname']`:      `web01`,
+			`$trusted['domain']`:        `example.com`,
+		})
+
+		// Facts are not assigned to global variables unless legacy is true
+		expectIssue(t, px.UnknownVariable, func() { evaluate(c, `$::kernel`) })
+	})
+}
+
+func TestLoadFactsYAML(t *testing.T) {
+	// The output of 'puppet facts --render-as yaml'
+	dir := writeFiles(t, map[string]string{
+		`facts.yaml`: "--- !ruby/object:Puppet::Node::Facts\nname: db01.example.org\nvalues:\n  kernel: Linux\n  clientcert: db01.example.org\n  os:\n    family: RedHat\n",
+	})
+	defer os.RemoveAll(dir)
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		c.LoadFacts(filepath.Join(dir, `facts.yaml`), nil, true)
+		expectValues(t, c, map[string]string{
+			`$facts['os']['family']`: `RedHat`,
+			`$facts['name']`:         `undef`,
+			`$trusted['certname']`:   `db01.example.org`,
+			`$kernel`:                `Linux`,
+			`$::os['family']`:        `RedHat`,
+		})
+	})
+}
+
+func TestLoadFactsErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		`array.json`:  `[1, 2]`,
+		`values.json`: `{"name": "web01", "values": 3}`,
+	})
+	defer os.RemoveAll(dir)
+	for _, name := range []string{`missing.json`, `array.json`, `values.json`} {
+		puppet.Do(func(c pdsl.EvaluationContext) {
+			expectIssue(t, pdsl.BadFactsFile, func() { c.LoadFacts(filepath.Join(dir, name), nil, false) })
+		})
+	}
+}
+
+func TestSetFacts(t *testing.T) {
+	facts := types.WrapStringToInterfaceMap(nil, map[string]interface{}{
+		`kernel`:  `Linux`,
+		`trusted`: `spoofed`,
+	})
+	trusted := types.WrapStringToInterfaceMap(nil, map[string]interface{}{`certname`: `given`})
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		// A legacy fact never replaces a global variable that has already been assigned
+		c.Scope().(pdsl.Scope).Set(`::kernel`, types.WrapString(`mine`))
+		c.SetFacts(facts, trusted, true)
+		expectValues(t, c, map[string]string{
+			`$facts['kernel']`:     `Linux`,
+			`$kernel`:              `mine`,
+			`$trusted['certname']`: `given`,
+			`$facts['trusted']`:    `spoofed`,
+		})
+	})
+}
+
+func TestReservedVariables(t *testing.T) {
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		c.SetFacts(px.EmptyMap, nil, false)
+		expectIssue(t, pdsl.IllegalReservedAssignment, func() { evaluate(c, `$facts = {}`) })
+		expectIssue(t, pdsl.IllegalReservedAssignment, func() { evaluate(c, `$::trusted = {}`) })
+		expectIssue(t, pdsl.IllegalReservedAssignment, func() { evaluate(c, `[$a, $facts] = [1, 2]`) })
+		expectIssue(t, pdsl.ReservedParameter, func() { evaluateProgram(c, `function f($trusted) {} f(1)`) })
+		expectIssue(t, pdsl.IllegalReservedAssignment, func() { evaluate(c, `inline_epp('<%= 1 %>', { facts => 1 })`) })
+
+		// Assignments that bypass the evaluator are rejected by the scope
+		expectIssue(t, pdsl.IllegalReservedAssignment, func() { c.Scope().(pdsl.Scope).Set(`facts`, px.EmptyMap) })
+		expectIssue(t, pdsl.IllegalReservedAssignment, func() { c.Scope().(pdsl.Scope).Set(`::trusted`, px.EmptyMap) })
+	})
+}
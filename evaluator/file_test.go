@@ -0,0 +1,54 @@
+package evaluator_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+)
+
+func TestFileFunctions(t *testing.T) {
+	withModules(t, map[string]string{
+		`m/files/a.txt`:     `a in m`,
+		`m/files/sub/b.txt`: `b in m`,
+		`n/files/a.txt`:     `a in n`,
+		`n/templates/t.txt`: `template`,
+	}, func(c pdsl.EvaluationContext, dir string) {
+		abs := filepath.ToSlash(filepath.Join(dir, `n`, `files`, `a.txt`))
+		mA := filepath.ToSlash(filepath.Join(dir, `m`, `files`, `a.txt`))
+		expectValues(t, c, map[string]string{
+			// A module relative name denotes a file in the files directory of the module
+			`find_file('m/a.txt')`:     mA,
+			`find_file('m/sub/b.txt')`: filepath.ToSlash(filepath.Join(dir, `m`, `files`, `sub`, `b.txt`)),
+			`file('m/sub/b.txt')`:      `b in m`,
+
+			// The first name that denotes an existing file is used
+			`find_file('m/missing.txt', 'n/a.txt', 'm/a.txt')`:               abs,
+			`find_file(['x/a.txt', 'm/missing.txt'], 'm/a.txt')`:             mA,
+			`file('missing/a.txt', ['m/missing.txt', 'n/a.txt'], 'm/a.txt')`: `a in n`,
+
+			// An absolute path is used as is
+			`find_file('` + abs + `')`:                 abs,
+			`file('/no/such/file', '` + abs + `')`:     `a in n`,
+			`binary_file('m/a.txt') =~ Binary`:         `true`,
+			`String(binary_file('` + abs + `'), '%s')`: `a in n`,
+
+			// A name without a module, or a file outside of the files directory, is not found
+			`find_file('/no/such/file')`:          `undef`,
+			`find_file('a.txt', 'missing/a.txt')`: `undef`,
+			`find_file('n/t.txt')`:                `undef`,
+		})
+		for source, code := range map[string]issue.Code{
+			`file('m/missing.txt', 'missing/a.txt')`: pdsl.UnknownFile,
+			`file('a.txt')`:                          pdsl.UnknownFile,
+			`binary_file('n/t.txt')`:                 pdsl.UnknownFile,
+			`file()`:                                 px.IllegalArguments,
+			`find_file()`:                            px.IllegalArguments,
+			`binary_file('m/a.txt', 'n/a.txt')`:      px.IllegalArguments,
+		} {
+			expectIssue(t, code, func() { evaluate(c, source) })
+		}
+	})
+}
@@ -50,6 +50,9 @@ type (
 		signature  *types.CallableType
 		expression *parser.FunctionDefinition
 		parameters []px.Parameter
+
+		// loader is the loader that the function body is evaluated with
+		loader px.Loader
 	}
 
 	puppetPlan struct {
@@ -107,8 +110,10 @@ func (l *puppetLambda) PType() px.Type {
 	return l.signature
 }
 
-func NewPuppetFunction(expr *parser.FunctionDefinition) *puppetFunction {
-	return &puppetFunction{expression: expr}
+// NewPuppetFunction creates a function from the given definition. The body of the function is evaluated
+// with the given loader.
+func NewPuppetFunction(expr *parser.FunctionDefinition, loader px.Loader) *puppetFunction {
+	return &puppetFunction{expression: expr, loader: loader}
 }
 
 func (f *puppetFunction) Call(c px.Context, block px.Lambda, args ...px.Value) (v px.Value) {
@@ -127,7 +132,9 @@ func (f *puppetFunction) Call(c px.Context, block px.Lambda, args ...px.Value) (
 			}
 		}
 	}()
-	v = CallBlock(c.(pdsl.EvaluationContext), f.Name(), f.parameters, f.signature, f.expression.Body(), args)
+	c.DoWithLoader(f.loader, func() {
+		v = CallBlock(c.(pdsl.EvaluationContext), f.Name(), f.parameters, f.signature, f.expression.Body(), args)
+	})
 	return
 }
 
@@ -251,8 +258,8 @@ func (f *puppetFunction) PType() px.Type {
 	return f.signature
 }
 
-func NewPuppetPlan(expr *parser.PlanDefinition) *puppetPlan {
-	return &puppetPlan{puppetFunction{expression: &expr.FunctionDefinition}}
+func NewPuppetPlan(expr *parser.PlanDefinition, loader px.Loader) *puppetPlan {
+	return &puppetPlan{puppetFunction{expression: &expr.FunctionDefinition, loader: loader}}
 }
 
 func (p *puppetPlan) ToString(bld io.Writer, format px.FormatContext, g px.RDetect) {
@@ -290,7 +297,11 @@ func resolveReturnType(c pdsl.EvaluationContext, typeExpr parser.Expression) px.
 func resolveParameters(c pdsl.EvaluationContext, eps []parser.Expression) []px.Parameter {
 	pps := make([]px.Parameter, len(eps))
 	for idx, ep := range eps {
-		pps[idx] = pdsl.Evaluate(c, ep).(px.Parameter)
+		p := pdsl.Evaluate(c, ep).(px.Parameter)
+		if reservedVariables[p.Name()] {
+			panic(evalError(pdsl.ReservedParameter, ep, issue.H{`name`: p.Name()}))
+		}
+		pps[idx] = p
 	}
 	return pps
 }
@@ -16,7 +16,9 @@ import (
 
 func init() {
 	loader.SmartPathFactories[px.PuppetFunctionPath] = newPuppetFunctionPath
+	loader.SmartPathFactories[pdsl.PuppetManifestPath] = newPuppetManifestPath
 	loader.SmartPathFactories[px.PlanPath] = newPuppetPlanPath
+	loader.SmartPathFactories[px.PuppetDataTypePath] = newPuppetTypePath
 	loader.SmartPathFactories[px.TaskPath] = newPuppetTaskPath
 }
 
@@ -24,10 +26,18 @@ func newPuppetFunctionPath(ml px.ModuleLoader, moduleNameRelative bool) loader.S
 	return loader.NewSmartPath(`functions`, `.pp`, ml, []px.Namespace{px.NsFunction}, moduleNameRelative, false, InstantiatePuppetFunction)
 }
 
+func newPuppetManifestPath(ml px.ModuleLoader, moduleNameRelative bool) loader.SmartPath {
+	return loader.NewSmartPath(`manifests`, `.pp`, ml, []px.Namespace{pdsl.NsClass, pdsl.NsDefinedType}, moduleNameRelative, false, InstantiatePuppetManifest)
+}
+
 func newPuppetPlanPath(ml px.ModuleLoader, moduleNameRelative bool) loader.SmartPath {
 	return loader.NewSmartPath(`plans`, `.pp`, ml, []px.Namespace{px.NsPlan}, moduleNameRelative, false, InstantiatePuppetPlan)
 }
 
+func newPuppetTypePath(ml px.ModuleLoader, moduleNameRelative bool) loader.SmartPath {
+	return loader.NewSmartPath(`types`, `.pp`, ml, []px.Namespace{px.NsType}, moduleNameRelative, false, InstantiatePuppetType)
+}
+
 func newPuppetTaskPath(ml px.ModuleLoader, moduleNameRelative bool) loader.SmartPath {
 	return loader.NewSmartPath(`tasks`, ``, ml, []px.Namespace{px.NsTask}, moduleNameRelative, true, InstantiatePuppetTask)
 }
@@ -50,6 +60,18 @@ func InstantiatePuppetFunction(ctx px.Context, loader loader.ContentProvidingLoa
 	instantiatePuppetFunction(ctx, loader, tn, sources)
 }
 
+// InstantiatePuppetManifest instantiates the class or defined type that is declared in a manifest. The
+// manifest must contain only that definition.
+func InstantiatePuppetManifest(ctx px.Context, loader loader.ContentProvidingLoader, tn px.TypedName, sources []string) {
+	instantiatePuppetFunction(ctx, loader, tn, sources)
+}
+
+// InstantiatePuppetType instantiates the type alias that is declared in a types/*.pp file. The file must
+// contain only that declaration.
+func InstantiatePuppetType(ctx px.Context, loader loader.ContentProvidingLoader, tn px.TypedName, sources []string) {
+	instantiatePuppetFunction(ctx, loader, tn, sources)
+}
+
 func InstantiatePuppetPlan(ctx px.Context, loader loader.ContentProvidingLoader, tn px.TypedName, sources []string) {
 	instantiatePuppetFunction(ctx, loader, tn, sources)
 }
@@ -60,7 +82,7 @@ func instantiatePuppetFunction(ctx px.Context, loader loader.ContentProvidingLoa
 	content := string(loader.GetContent(ctx, source))
 	expr := ec.ParseAndValidate(source, content, false)
 	name := tn.Name()
-	fd, ok := getDefinition(expr, tn.Namespace(), name).(parser.NamedDefinition)
+	fd, ok := getDefinition(expr, tn.Namespace(), name).(issue.Named)
 	if !ok {
 		panic(ctx.Error(expr, px.NoDefinition, issue.H{`source`: expr.File(), `type`: tn.Namespace(), `name`: name}))
 	}
@@ -2,7 +2,10 @@ package evaluator
 
 import (
 	"io"
+	"math"
+	"reflect"
 
+	"github.com/lyraproj/issue/issue"
 	"github.com/lyraproj/pcore/px"
 	"github.com/lyraproj/pcore/types"
 	"github.com/lyraproj/pcore/utils"
@@ -29,8 +32,11 @@ type (
 		base      pdsl.Iterator
 	}
 
+	// iteratorValue is an Iterator value. The value produces its values once unless it has a start
+	// function, in which case every use of the value, except Next, obtains a new iterator from that function.
 	iteratorValue struct {
 		iterator pdsl.Iterator
+		start    func() pdsl.Iterator
 	}
 )
 
@@ -232,7 +238,13 @@ func (ai *indexedIterator) Select(predicate px.Predicate) px.IteratorValue {
 }
 
 func (ai *indexedIterator) AsArray() px.List {
-	return ai.indexed.(px.Arrayable).AsArray()
+	switch indexed := ai.indexed.(type) {
+	case px.Arrayable:
+		return indexed.AsArray()
+	case px.List:
+		return indexed
+	}
+	return asArray(ai)
 }
 
 func (ai *predicateIterator) All(predicate px.Predicate) bool {
@@ -387,11 +399,17 @@ func WrapIterable(it px.Indexed) pdsl.Iterator {
 }
 
 func WrapIterator(iter pdsl.Iterator) px.IteratorValue {
-	return &iteratorValue{iter}
+	return &iteratorValue{iterator: iter}
+}
+
+// restartable returns an Iterator value that produces all of its values every time it is used. The given
+// function must return a new iterator each time it is called.
+func restartable(start func() pdsl.Iterator) px.IteratorValue {
+	return &iteratorValue{iterator: start(), start: start}
 }
 
 func (it *iteratorValue) AsArray() px.List {
-	return it.iterator.AsArray()
+	return Iterate(it).AsArray()
 }
 
 func (it *iteratorValue) ElementType() px.Type {
@@ -423,3 +441,357 @@ func (it *iteratorValue) ToString(b io.Writer, s px.FormatContext, g px.RDetect)
 		utils.WriteString(b, `Iterator-Value`)
 	}
 }
+
+// generatingIterator is an iterator that obtains its values from a function. It is used by the iterators
+// that reverse, step, slice, or remove duplicates from the values of another iterator.
+type generatingIterator struct {
+	elementType px.Type
+	next        func() (px.Value, bool)
+}
+
+func (ai *generatingIterator) All(predicate px.Predicate) bool {
+	return all(ai, predicate)
+}
+
+func (ai *generatingIterator) Any(predicate px.Predicate) bool {
+	return any(ai, predicate)
+}
+
+func (ai *generatingIterator) Next() (px.Value, bool) {
+	return ai.next()
+}
+
+func (ai *generatingIterator) Each(consumer px.Consumer) {
+	each(ai, consumer)
+}
+
+func (ai *generatingIterator) EachWithIndex(consumer px.BiConsumer) {
+	eachWithIndex(ai, consumer)
+}
+
+func (ai *generatingIterator) ElementType() px.Type {
+	return ai.elementType
+}
+
+func (ai *generatingIterator) Find(predicate px.Predicate) px.Value {
+	return find(ai, predicate, px.Undef, nil)
+}
+
+func (ai *generatingIterator) Find2(predicate px.Predicate, dflt px.Value) px.Value {
+	return find(ai, predicate, dflt, nil)
+}
+
+func (ai *generatingIterator) Find3(predicate px.Predicate, dflt px.Producer) px.Value {
+	return find(ai, predicate, nil, dflt)
+}
+
+func (ai *generatingIterator) Map(elementType px.Type, mapFunc px.Mapper) px.IteratorValue {
+	return WrapIterator(&mappingIterator{elementType, mapFunc, ai})
+}
+
+func (ai *generatingIterator) Reduce(redactor px.BiMapper) px.Value {
+	return reduce(ai, redactor)
+}
+
+func (ai *generatingIterator) Reduce2(initialValue px.Value, redactor px.BiMapper) px.Value {
+	return reduce2(ai, initialValue, redactor)
+}
+
+func (ai *generatingIterator) Reject(predicate px.Predicate) px.IteratorValue {
+	return WrapIterator(&predicateIterator{predicate, false, ai})
+}
+
+func (ai *generatingIterator) Select(predicate px.Predicate) px.IteratorValue {
+	return WrapIterator(&predicateIterator{predicate, true, ai})
+}
+
+func (ai *generatingIterator) AsArray() px.List {
+	return asArray(ai)
+}
+
+// Iterate returns an iterator for the given Iterable, Iterator, Integer, or Integer type value. An Integer n
+// produces the integers 0 to n - 1 and an Integer type produces the integers of its range. The iterator of
+// an Iterator value that was created by WrapIterator is shared with the value. The function panics if the
+// value cannot be iterated.
+func Iterate(v px.Value) pdsl.Iterator {
+	switch v := v.(type) {
+	case *iteratorValue:
+		if v.start != nil {
+			return v.start()
+		}
+		return v.iterator
+	case px.IteratorValue:
+		return &generatingIterator{v.ElementType(), v.Next}
+	case px.Indexed:
+		return WrapIterable(v)
+	case px.Integer, *types.IntegerType:
+		from, to := integerRange(v)
+		return rangeIterator(from, to, 1)
+	default:
+		panic(px.Error(pdsl.NotIterable, issue.H{`actual`: v.PType()}))
+	}
+}
+
+// integerRange returns the first and the last integer that the given Integer or Integer type produces when
+// iterated. The range is empty when the first integer is greater than the last. The function panics if the
+// type is unbounded.
+func integerRange(v px.Value) (int64, int64) {
+	if it, ok := v.(*types.IntegerType); ok {
+		if it.Min() == math.MinInt64 || it.Max() == math.MaxInt64 {
+			panic(px.Error(pdsl.NotIterableRange, issue.H{`type`: it}))
+		}
+		return it.Min(), it.Max()
+	}
+	return 0, v.(px.Integer).Int() - 1
+}
+
+// rangeIterator returns an iterator that produces the integers from the first to the last integer, adding
+// the given increment of 1 or -1 to get from one integer to the next. Nothing is produced when the last
+// integer is not reached by moving from the first in the direction of the increment.
+func rangeIterator(first, last, increment int64) pdsl.Iterator {
+	done := first < last
+	if increment > 0 {
+		done = first > last
+	}
+	min, max := first, last
+	if done {
+		min, max = 0, 0
+	} else if increment < 0 {
+		min, max = last, first
+	}
+	n := first
+	return &generatingIterator{types.NewIntegerType(min, max), func() (px.Value, bool) {
+		if done {
+			return px.Undef, false
+		}
+		v := n
+		if n == last {
+			done = true
+		} else {
+			n += increment
+		}
+		return types.WrapInteger(v), true
+	}}
+}
+
+// ReverseIterator returns an Iterator value that produces the elements of the given Iterable, Iterator,
+// Integer, or Integer type value in reverse order. The elements of an Iterator are collected when the first
+// element is requested.
+func ReverseIterator(v px.Value) px.IteratorValue {
+	switch v.(type) {
+	case px.Integer, *types.IntegerType:
+		from, to := integerRange(v)
+		return restartable(func() pdsl.Iterator { return rangeIterator(to, from, -1) })
+	}
+	return restartable(func() pdsl.Iterator {
+		var indexed px.Indexed
+		var base pdsl.Iterator
+		if iv, ok := v.(px.Indexed); ok {
+			indexed = iv
+		} else {
+			base = Iterate(v)
+		}
+		pos := -1
+		ai := &generatingIterator{next: func() (px.Value, bool) {
+			if pos < 0 {
+				if indexed == nil {
+					indexed = base.AsArray()
+				}
+				pos = indexed.Len()
+			}
+			if pos == 0 {
+				return px.Undef, false
+			}
+			pos--
+			return indexed.At(pos), true
+		}}
+		if indexed != nil {
+			ai.elementType = indexed.ElementType()
+		} else {
+			ai.elementType = base.ElementType()
+		}
+		return ai
+	})
+}
+
+// StepIterator returns an Iterator value that produces the first element of the given value and then
+// every step'th element after that. The value can be anything that Iterate accepts.
+func StepIterator(v px.Value, step int64) px.IteratorValue {
+	return restartable(func() pdsl.Iterator { return stepIterator(Iterate(v), step) })
+}
+
+func stepIterator(base pdsl.Iterator, step int64) pdsl.Iterator {
+	first := true
+	return &generatingIterator{base.ElementType(), func() (px.Value, bool) {
+		if first {
+			first = false
+		} else {
+			for i := int64(1); i < step; i++ {
+				if _, ok := base.Next(); !ok {
+					return px.Undef, false
+				}
+			}
+		}
+		return base.Next()
+	}}
+}
+
+// SliceIterator returns an Iterator value that produces arrays of size consecutive elements of the given
+// value. The last array is shorter when the number of elements is not evenly divisible by size. The value
+// can be anything that Iterate accepts.
+func SliceIterator(v px.Value, size int64) px.IteratorValue {
+	return restartable(func() pdsl.Iterator { return sliceIterator(Iterate(v), size) })
+}
+
+func sliceIterator(base pdsl.Iterator, size int64) pdsl.Iterator {
+	return &generatingIterator{types.NewArrayType(base.ElementType(), types.NewIntegerType(1, size)), func() (px.Value, bool) {
+		slice := make([]px.Value, 0, size)
+		for int64(len(slice)) < size {
+			v, ok := base.Next()
+			if !ok {
+				break
+			}
+			slice = append(slice, v)
+		}
+		if len(slice) == 0 {
+			return px.Undef, false
+		}
+		return types.WrapValues(slice), true
+	}}
+}
+
+// UniqueIterator returns an Iterator value that produces the elements of the given value, skipping elements
+// that are equal to an element that has already been produced. When keyFunc is not nil, elements are
+// compared using the keys that it produces. The value can be anything that Iterate accepts.
+func UniqueIterator(v px.Value, keyFunc px.Mapper) px.IteratorValue {
+	return restartable(func() pdsl.Iterator { return uniqueIterator(Iterate(v), keyFunc) })
+}
+
+func uniqueIterator(base pdsl.Iterator, keyFunc px.Mapper) pdsl.Iterator {
+	seen := make(map[px.HashKey]bool)
+	return &generatingIterator{base.ElementType(), func() (px.Value, bool) {
+		for {
+			v, ok := base.Next()
+			if !ok {
+				return px.Undef, false
+			}
+			key := v
+			if keyFunc != nil {
+				key = keyFunc(v)
+			}
+			hk := px.ToKey(key)
+			if !seen[hk] {
+				seen[hk] = true
+				return v, true
+			}
+		}
+	}}
+}
+
+// TreeOptions controls which values a tree iterator produces and in what order
+type TreeOptions struct {
+	// BreadthFirst selects breadth first order. The default is depth first order.
+	BreadthFirst bool
+
+	// IncludeContainers and IncludeValues select if the containers and the values that are not containers
+	// are produced. IncludeRoot selects if the root container is produced when containers are produced.
+	IncludeContainers bool
+	IncludeValues     bool
+	IncludeRoot       bool
+
+	// Containers is the type that an Array, Hash, or Object must be an instance of to be considered a
+	// container. A nil type means that all of them are containers.
+	Containers px.Type
+}
+
+type treeNode struct {
+	path      []px.Value
+	value     px.Value
+	ancestors []px.Value
+}
+
+// TreeIterator returns an iterator that produces a [path, value] pair for each value of the tree with the
+// given root. The path is an array of the indexes, keys, and attribute names that lead from the root to
+// the value. Containers are expanded when their values are needed so the tree is never copied.
+//
+// An Object that is found among its own ancestors is produced as a value that is not a container, since
+// expanding it would never end.
+func TreeIterator(root px.Value, options *TreeOptions) px.IteratorValue {
+	if _, ok := root.(px.IteratorValue); ok {
+		root = Iterate(root).AsArray()
+	}
+	return restartable(func() pdsl.Iterator { return treeIterator(root, options) })
+}
+
+func treeIterator(root px.Value, options *TreeOptions) pdsl.Iterator {
+	pending := []*treeNode{{path: []px.Value{}, value: root}}
+	return &generatingIterator{types.NewTupleType([]px.Type{types.DefaultArrayType(), types.DefaultAnyType()}, nil), func() (px.Value, bool) {
+		for len(pending) > 0 {
+			var n *treeNode
+			if options.BreadthFirst {
+				n = pending[0]
+				pending = pending[1:]
+			} else {
+				n = pending[len(pending)-1]
+				pending = pending[:len(pending)-1]
+			}
+			children := treeChildren(n, options.Containers)
+			if options.BreadthFirst {
+				pending = append(pending, children...)
+			} else {
+				for i := len(children) - 1; i >= 0; i-- {
+					pending = append(pending, children[i])
+				}
+			}
+			var include bool
+			switch {
+			case len(n.path) == 0:
+				include = options.IncludeContainers && options.IncludeRoot
+			case children != nil:
+				include = options.IncludeContainers
+			default:
+				include = options.IncludeValues
+			}
+			if include {
+				return types.WrapValues([]px.Value{types.WrapValues(n.path), n.value}), true
+			}
+		}
+		return px.Undef, false
+	}}
+}
+
+// treeChildren returns the nodes of the values contained in the given node, or nil when the value of the
+// node is not a container. The children of an empty container is an empty slice.
+func treeChildren(n *treeNode, containers px.Type) []*treeNode {
+	if containers != nil && !containers.IsInstance(n.value, nil) {
+		return nil
+	}
+	ancestors := n.ancestors
+	children := make([]*treeNode, 0)
+	add := func(k, v px.Value) {
+		path := make([]px.Value, len(n.path), len(n.path)+1)
+		copy(path, n.path)
+		children = append(children, &treeNode{append(path, k), v, ancestors})
+	}
+	switch v := n.value.(type) {
+	case *types.Array:
+		v.EachWithIndex(func(e px.Value, i int) { add(types.WrapInteger(int64(i)), e) })
+	case *types.Hash:
+		v.EachPair(add)
+	case px.PuppetObject:
+		for _, a := range ancestors {
+			if sameObject(a, v) {
+				return nil
+			}
+		}
+		ancestors = append(ancestors[:len(ancestors):len(ancestors)], v)
+		v.InitHash().EachPair(add)
+	default:
+		return nil
+	}
+	return children
+}
+
+func sameObject(a, b px.Value) bool {
+	return reflect.TypeOf(a).Comparable() && a == b
+}
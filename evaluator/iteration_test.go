@@ -0,0 +1,100 @@
+package evaluator_test
+
+import (
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+	"github.com/lyraproj/puppet-evaluator/puppet"
+)
+
+// all appends a map call to the given iterator expression so that its values are returned as an array
+func all(iterator string) string {
+	return `(` + iterator + `).map |$x| { $x }`
+}
+
+func TestIterationFunctions(t *testing.T) {
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		for source, expected := range map[string]string{
+			// step
+			all(`[1, 2, 3, 4, 5].step(2)`):        `[1, 3, 5]`,
+			all(`step(10, 3)`):                    `[0, 3, 6, 9]`,
+			all(`Integer[3, 8].step(2)`):          `[3, 5, 7]`,
+			all(`step(0, 2)`):                     `[]`,
+			all(`'abc'.step(2)`):                  `['a', 'c']`,
+			all(`{ a => 1, b => 2 }.step(2)`):     `[['a', 1]]`,
+			all(`[1, 2, 3].reverse_each.step(2)`): `[3, 1]`,
+
+			// A large range is iterated lazily
+			`Integer[1, 10000000000].step(2).map |$x| { if $x > 7 { break() } $x }`: `[1, 3, 5, 7]`,
+
+			// slice
+			all(`[1, 2, 3, 4, 5].slice(2)`):   `[[1, 2], [3, 4], [5]]`,
+			all(`slice(5, 2)`):                `[[0, 1], [2, 3], [4]]`,
+			all(`Integer[1, 4].slice(3)`):     `[[1, 2, 3], [4]]`,
+			`[1, 2, 3].slice(2) |$a, $b| { }`: `[1, 2, 3]`,
+
+			// unique
+			`[1, 2, 1, 3].unique`:                       `[1, 2, 3]`,
+			`[1, 2, 4, 5].unique |$x| { $x % 3 }`:       `[1, 2]`,
+			`'abacus'.unique`:                           `abcus`,
+			`{ a => 1, b => 2, c => 1 }.unique`:         `{['a', 'c'] => 1, ['b'] => 2}`,
+			all(`[1, 1, 2].reverse_each.unique`):        `[2, 1]`,
+			all(`Integer[1, 6].unique |$x| { $x % 3 }`): `[1, 2, 3]`,
+			all(`unique(3)`):                            `[0, 1, 2]`,
+
+			// reverse_each
+			all(`[1, 2, 3].reverse_each`):         `[3, 2, 1]`,
+			all(`reverse_each(4)`):                `[3, 2, 1, 0]`,
+			all(`Integer[3, 6].reverse_each`):     `[6, 5, 4, 3]`,
+			all(`reverse_each(0)`):                `[]`,
+			all(`[1, 2, 3].step(2).reverse_each`): `[3, 1]`,
+			`[1, 2].reverse_each |$x| { }`:        `undef`,
+		} {
+			if actual := evaluate(c, source); actual.String() != expected {
+				t.Errorf(`%s: expected %s, got %s`, source, expected, actual)
+			}
+		}
+	})
+}
+
+func TestIteratorValuesRestart(t *testing.T) {
+	for _, source := range []string{
+		`[1, 2]`,
+		`[2, 1].reverse_each`,
+		`[1, 3, 2].step(2).reverse_each.reverse_each`,
+		`[1, 1, 2].unique`,
+		`Integer[1, 2].unique`,
+	} {
+		puppet.Do(func(c pdsl.EvaluationContext) {
+			// Every use of the value produces all of its values
+			actual := evaluate(c, `$a = `+source+` [$a.map |$x| { $x }, $a.map |$x| { $x }, $a.filter |$x| { true }]`)
+			if actual.String() != `[[1, 2], [1, 2], [1, 2]]` {
+				t.Errorf(`%s: expected [[1, 2], [1, 2], [1, 2]], got %s`, source, actual)
+			}
+		})
+	}
+}
+
+func TestIterationErrors(t *testing.T) {
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		for source, code := range map[string]issue.Code{
+			`Integer.step(2)`:              pdsl.NotIterableRange,
+			`Integer[1].reverse_each`:      pdsl.NotIterableRange,
+			`Integer[default, 1].slice(2)`: pdsl.NotIterableRange,
+		} {
+			func() {
+				defer func() {
+					if r, ok := recover().(issue.Reported); !ok || r.Code() != code {
+						t.Errorf(`%s: expected %s, got %v`, source, code, r)
+					}
+				}()
+				evaluate(c, source)
+			}()
+		}
+		if _, ok := evaluate(c, `step(3, 1)`).(px.IteratorValue); !ok {
+			t.Error(`expected step to return an Iterator`)
+		}
+	})
+}
@@ -0,0 +1,124 @@
+package evaluator
+
+import (
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+)
+
+// metaParameterTypes contains the types of the metaparameters that are valid for all resources
+var metaParameterTypes map[string]px.Type
+
+func init() {
+	ref := types.NewTypeType(catalogEntryTypeDefault)
+	refs := types.NewVariantType(ref, types.NewArrayType(types.NewVariantType(ref, types.NewArrayType(ref, nil)), nil))
+	str := types.DefaultStringType()
+	strs := types.NewVariantType(str, types.NewArrayType(str, nil))
+	tag := types.NewPatternType([]*types.RegexpType{types.NewRegexpType(`\A[[:alnum:]_][[:alnum:]_:.-]*\z`)})
+
+	metaParameterTypes = map[string]px.Type{
+		`alias`:     strs,
+		`audit`:     strs,
+		`before`:    refs,
+		`loglevel`:  types.NewEnumType([]string{`debug`, `info`, `notice`, `warning`, `err`, `alert`, `emerg`, `crit`, `verbose`}, false),
+		`noop`:      types.DefaultBooleanType(),
+		`notify`:    refs,
+		`require`:   refs,
+		`schedule`:  str,
+		`stage`:     str,
+		`subscribe`: refs,
+		`tag`:       types.NewVariantType(tag, types.NewArrayType(tag, nil)),
+	}
+}
+
+// isMetaParameter returns true if the given name is the name of a metaparameter
+func isMetaParameter(name string) bool {
+	_, ok := metaParameterTypes[name]
+	return ok
+}
+
+// applyMetaParameters validates the metaparameters of the given resource, which must have been added to
+// the catalog, and applies its tags and aliases. The resource is tagged with the name of its type, the
+// tags given by the tag metaparameter, and all tags of its container. A class is also tagged with its name.
+func applyMetaParameters(c pdsl.EvaluationContext, r pdsl.Resource) {
+	ref := newResourceType(r.Type(), r.Title())
+	attributes := r.Attributes()
+	attributes.EachPair(func(k, v px.Value) {
+		name := k.String()
+		if t, ok := metaParameterTypes[name]; ok && !px.IsInstance(t, v) {
+			panic(evalError(pdsl.ParameterTypeMismatch, r.Location(),
+				issue.H{`resource`: ref, `name`: name, `expected`: t, `actual`: px.DetailedValueType(v)}))
+		}
+	})
+	if attributes.IncludesKey2(`stage`) && r.Type() != `Class` {
+		panic(evalError(pdsl.IllegalStage, r.Location(), issue.H{`resource`: ref}))
+	}
+
+	catalog := c.Catalog()
+	tags := nameTags(r.Type())
+	if r.Type() == `Class` {
+		tags = append(tags, nameTags(r.Title())...)
+	}
+	tags = append(tags, metaParameterStrings(attributes, `tag`)...)
+	if container, ok := catalog.Container(ref); ok {
+		if cr, ok := catalog.Resource(container.Type(), container.Title()); ok {
+			tags = append(tags, cr.Tags()...)
+		}
+	}
+	catalog.Tag(ref, tags...)
+
+	for _, alias := range metaParameterStrings(attributes, `alias`) {
+		catalog.Alias(ref, alias)
+	}
+}
+
+// addMetaParameterEdges adds the edges given by the before, notify, require, and subscribe metaparameters
+// of the given resource to the catalog
+func addMetaParameterEdges(c pdsl.EvaluationContext, r pdsl.Resource) {
+	ref := newResourceType(r.Type(), r.Title())
+	attributes := r.Attributes()
+	catalog := c.Catalog()
+	add := func(name string, forward, subscribe bool) {
+		if v, ok := attributes.Get4(name); ok {
+			for _, other := range resourceReferences(r.Location(), pdsl.IllegalResourceReference, v, nil) {
+				if forward {
+					catalog.AddEdge(NewEdge(ref, other, subscribe, r.Location()))
+				} else {
+					catalog.AddEdge(NewEdge(other, ref, subscribe, r.Location()))
+				}
+			}
+		}
+	}
+	add(`before`, true, false)
+	add(`notify`, true, true)
+	add(`require`, false, false)
+	add(`subscribe`, false, true)
+}
+
+// metaParameterStrings returns the value of the given metaparameter as a slice of strings
+func metaParameterStrings(attributes px.OrderedMap, name string) []string {
+	switch v := attributes.Get5(name, px.Undef).(type) {
+	case px.StringValue:
+		return []string{v.String()}
+	case *types.Array:
+		ss := make([]string, 0, v.Len())
+		v.Each(func(e px.Value) { ss = append(ss, e.String()) })
+		return ss
+	default:
+		return nil
+	}
+}
+
+// nameTags returns the tags for the given qualified name, i.e. the lower case name itself followed by each
+// segment of the name when the name has more than one segment
+func nameTags(name string) []string {
+	name = strings.ToLower(strings.TrimPrefix(name, `::`))
+	segments := strings.Split(name, `::`)
+	if len(segments) == 1 {
+		return segments
+	}
+	return append([]string{name}, segments...)
+}
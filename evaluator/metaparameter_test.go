@@ -0,0 +1,133 @@
+package evaluator_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+	"github.com/lyraproj/puppet-evaluator/puppet"
+)
+
+func TestMetaParameterEdges(t *testing.T) {
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		cat := compile(c, `
+notify { [a, b, c, d]: }
+notify { x:
+  before    => Notify[a],
+  notify    => [Notify[b], [Notify[c]]],
+  require   => Notify[d],
+  subscribe => Resource[notify, e],
+}
+notify { e: }
+define wrapper { }
+wrapper { w: require => Notify[a] }`)
+		expected := `Notify[x] -> Notify[a], Notify[x] ~> Notify[b], Notify[x] ~> Notify[c], Notify[d] -> Notify[x], ` +
+			`Notify[e] ~> Notify[x], Notify[a] -> Wrapper[w]`
+		if actual := edgeList(cat); actual != expected {
+			t.Errorf("expected %s\ngot %s", expected, actual)
+		}
+	})
+}
+
+func TestMetaParameterTags(t *testing.T) {
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		cat := compile(c, `
+class app::web { notify { n: tag => ['Extra', 'x::y'] } }
+include app::web
+file { '/a': tag => single }`)
+		for _, tt := range []struct{ typeName, title, tags string }{
+			{`Class`, `App::Web`, `class app::web app web`},
+			{`Notify`, `n`, `notify extra x::y class app::web app web`},
+			{`File`, `/a`, `file single`},
+		} {
+			r, ok := cat.Resource(tt.typeName, tt.title)
+			if !ok {
+				t.Errorf(`%s[%s] is not in the catalog`, tt.typeName, tt.title)
+				continue
+			}
+			if actual := strings.Join(r.Tags(), ` `); actual != tt.tags {
+				t.Errorf(`%s[%s]: expected the tags %s, got %s`, tt.typeName, tt.title, tt.tags, actual)
+			}
+		}
+	})
+}
+
+func TestMetaParameterAlias(t *testing.T) {
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		cat := compile(c, `
+file { '/etc/ssh/sshd_config': alias => [sshd_config, sshd] }
+notify { x: require => File[sshd] }`)
+		if r, ok := cat.Resource(`File`, `sshd_config`); !ok || r.Title() != `/etc/ssh/sshd_config` {
+			t.Errorf(`expected File[sshd_config] to be an alias of File[/etc/ssh/sshd_config]`)
+		}
+		if actual := edgeList(cat); actual != `File[sshd] -> Notify[x]` {
+			t.Errorf(`unexpected edges %s`, actual)
+		}
+	})
+	for _, source := range []string{
+		`file { '/a': alias => x } file { '/b': alias => x }`,
+		`file { '/a': } file { '/b': alias => '/a' }`,
+	} {
+		puppet.Do(func(c pdsl.EvaluationContext) {
+			expectIssue(t, pdsl.DuplicateAlias, func() { compile(c, source) })
+		})
+	}
+}
+
+func TestMetaParameterValidation(t *testing.T) {
+	for source, code := range map[string]issue.Code{
+		`notify { a: noop => 'yes' }`:             pdsl.ParameterTypeMismatch,
+		`notify { a: loglevel => bogus }`:         pdsl.ParameterTypeMismatch,
+		`notify { a: tag => 'not a tag' }`:        pdsl.ParameterTypeMismatch,
+		`notify { a: tag => [ok, 1] }`:            pdsl.ParameterTypeMismatch,
+		`notify { a: before => 'Notify[b]' }`:     pdsl.ParameterTypeMismatch,
+		`notify { a: require => [Notify[b], 1] }`: pdsl.ParameterTypeMismatch,
+		`notify { a: schedule => 1 }`:             pdsl.ParameterTypeMismatch,
+		`notify { a: audit => [true] }`:           pdsl.ParameterTypeMismatch,
+		`notify { a: alias => 1 }`:                pdsl.ParameterTypeMismatch,
+		`notify { a: stage => main }`:             pdsl.IllegalStage,
+		`class cl {} class { cl: stage => 1 }`:    pdsl.ParameterTypeMismatch,
+		`define d {} d { a: noop => 1 }`:          pdsl.ParameterTypeMismatch,
+		`notify { a: before => Notify[a] }`:       pdsl.DependencyCycle,
+		`Notify { before => 1 } notify { a: }`:    pdsl.ParameterTypeMismatch,
+	} {
+		puppet.Do(func(c pdsl.EvaluationContext) {
+			expectIssue(t, code, func() { compile(c, source) })
+		})
+	}
+
+	// Valid metaparameters, including metaparameters of classes and defined types
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		compile(c, `
+class cl {}
+class { cl: stage => main, tag => [a] }
+define d {}
+d { x: noop => true, loglevel => debug, schedule => daily, audit => [mode], tag => 'b::c' }`)
+	})
+}
+
+func TestCatalogEntryTypes(t *testing.T) {
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		compile(c, `notify { x: } class k {} include k`)
+		expectValues(t, c, map[string]string{
+			`Resource['file', '/x']`:                             `File['/x']`,
+			`Resource[file]`:                                     `File`,
+			`Resource[notify, x] == Notify[x]`:                   `true`,
+			`Notify[x] =~ Type[Resource]`:                        `true`,
+			`Notify[x] =~ Type[Resource[notify]]`:                `true`,
+			`Notify[x] =~ Type[CatalogEntry]`:                    `true`,
+			`Notify[x] =~ Type[Class]`:                           `false`,
+			`Class[k] =~ Type[Class]`:                            `true`,
+			`Class[k] =~ Type[CatalogEntry]`:                     `true`,
+			`Class[k] =~ Type[Resource]`:                         `false`,
+			`'x' =~ Type[CatalogEntry]`:                          `false`,
+			`Resource < CatalogEntry`:                            `true`,
+			`Class < CatalogEntry`:                               `true`,
+			`Notify[x] < Resource`:                               `true`,
+			`Notify[x] < Resource[notify]`:                       `true`,
+			`Notify[x] < Resource[file]`:                         `false`,
+			`[Notify[x], Class[k]] =~ Array[Type[CatalogEntry]]`: `true`,
+		})
+	})
+}
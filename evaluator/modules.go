@@ -0,0 +1,189 @@
+package evaluator
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+)
+
+type (
+	// A Module is a module found on the modulepath
+	Module struct {
+		// Name is the name of the module, i.e. the name of its directory
+		Name string
+
+		// Path is the directory of the module
+		Path string
+
+		// Dependencies are the names of the modules that the module depends on. It is nil if the module
+		// has no metadata.json, in which case the module can see all modules.
+		Dependencies []string
+	}
+
+	// moduleMetadata is the part of a module's metadata.json that the loaders are concerned with
+	moduleMetadata struct {
+		Name         string `json:"name"`
+		Dependencies []struct {
+			Name string `json:"name"`
+		} `json:"dependencies"`
+	}
+
+	fileLoader interface {
+		px.ModuleLoader
+		px.DefiningLoader
+		px.ParentedLoader
+	}
+
+	// publicLoader is the loader that other modules use to load the content of a module. The content is
+	// instantiated with the private loader of the module.
+	publicLoader struct {
+		fileLoader
+		private *privateLoader
+	}
+
+	// privateLoader is the loader that the content of a module is evaluated with. It sees the content
+	// of the module itself and of the modules that it depends on.
+	privateLoader struct {
+		fileLoader
+		public       *publicLoader
+		dependencies px.Loader
+	}
+)
+
+// modulePathTypes are the kinds of content that are loaded from a module
+var modulePathTypes = []px.PathType{px.PuppetFunctionPath, px.PuppetDataTypePath, pdsl.PuppetManifestPath, px.PlanPath, px.TaskPath}
+
+// ReadModulePath returns the modules found in the directories of the given modulepath. The directories
+// are separated by the OS specific path list separator. Directories that don't exist are ignored. When
+// two directories contain a module with the same name, the first one is used and the clash is logged as a
+// warning.
+func ReadModulePath(c px.Context, modulePath string) []*Module {
+	modules := make([]*Module, 0)
+	found := make(map[string]*Module)
+	for _, dir := range filepath.SplitList(modulePath) {
+		if dir == `` {
+			continue
+		}
+		fis, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, fi := range fis {
+			name := fi.Name()
+			if !(fi.IsDir() && px.IsValidModuleName(name)) {
+				continue
+			}
+			path := filepath.Join(dir, name)
+			if first, ok := found[name]; ok {
+				c.Logger().LogIssue(issue.NewReported(pdsl.ModuleNameClash, issue.SeverityWarning,
+					issue.H{`name`: name, `path`: path, `first`: first.Path}, issue.NewLocation(path, 0, 0)))
+				continue
+			}
+			m := &Module{Name: name, Path: path, Dependencies: readDependencies(name, path)}
+			found[name] = m
+			modules = append(modules, m)
+		}
+	}
+	return modules
+}
+
+// readDependencies returns the names of the dependencies declared in the metadata.json of the module in
+// the given directory, or nil if the module has no metadata.json
+func readDependencies(name, path string) []string {
+	mdPath := filepath.Join(path, `metadata.json`)
+	content, err := ioutil.ReadFile(mdPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		panic(px.Error(pdsl.BadModuleMetadata, issue.H{`module`: name, `path`: mdPath, `detail`: err.Error()}))
+	}
+	md := &moduleMetadata{}
+	if err = json.Unmarshal(content, md); err != nil {
+		panic(px.Error(pdsl.BadModuleMetadata, issue.H{`module`: name, `path`: mdPath, `detail`: err.Error()}))
+	}
+	deps := make([]string, len(md.Dependencies))
+	for i, d := range md.Dependencies {
+		// A dependency is named <author>-<name> or <author>/<name>
+		deps[i] = d.Name[strings.LastIndexAny(d.Name, `-/`)+1:]
+	}
+	return deps
+}
+
+// NewModulePathLoader creates a loader for the modules found on the given modulepath. Each module gets a
+// private loader that its functions, classes, defined types, and type aliases are evaluated with. The
+// private loader sees the module itself and the modules that are declared as dependencies in its
+// metadata.json, or all modules if the module has no metadata.json. A dependency that cannot be found is
+// logged as a warning.
+//
+// The returned loader sees all modules. It is the given parent when no modules are found.
+func NewModulePathLoader(c px.Context, parent px.Loader, modulePath string) px.Loader {
+	modules := ReadModulePath(c, modulePath)
+	if len(modules) == 0 {
+		return parent
+	}
+
+	loaders := make(map[string]*publicLoader, len(modules))
+	all := make([]px.ModuleLoader, len(modules))
+	for i, m := range modules {
+		fl := px.NewFileBasedLoader(parent, m.Path, m.Name, modulePathTypes...).(fileLoader)
+		pl := &publicLoader{fileLoader: fl}
+		pl.private = &privateLoader{fileLoader: fl, public: pl}
+		loaders[m.Name] = pl
+		all[i] = pl
+	}
+	allModules := px.NewDependencyLoader(all)
+
+	for _, m := range modules {
+		pl := loaders[m.Name]
+		if m.Dependencies == nil {
+			pl.private.dependencies = allModules
+			continue
+		}
+		deps := make([]px.ModuleLoader, 0, len(m.Dependencies))
+		for _, dn := range m.Dependencies {
+			if dl, ok := loaders[dn]; ok {
+				deps = append(deps, dl)
+			} else {
+				c.Logger().LogIssue(issue.NewReported(pdsl.MissingModuleDependency, issue.SeverityWarning,
+					issue.H{`module`: m.Name, `dependency`: dn}, issue.NewLocation(filepath.Join(m.Path, `metadata.json`), 0, 0)))
+			}
+		}
+		if len(deps) > 0 {
+			pl.private.dependencies = px.NewDependencyLoader(deps)
+		}
+	}
+	return allModules
+}
+
+func (l *publicLoader) LoadEntry(c px.Context, name px.TypedName) (entry px.LoaderEntry) {
+	c.DoWithLoader(l.private, func() {
+		entry = l.fileLoader.LoadEntry(c, name)
+	})
+	return
+}
+
+func (l *privateLoader) LoadEntry(c px.Context, name px.TypedName) px.LoaderEntry {
+	entry := l.public.LoadEntry(c, name)
+	if (entry == nil || entry.Value() == nil) && l.dependencies != nil {
+		entry = l.dependencies.LoadEntry(c, name)
+	}
+	return entry
+}
+
+// LoaderFor returns the public loader of the module itself or of one of its dependencies
+func (l *privateLoader) LoaderFor(moduleName string) px.ModuleLoader {
+	if moduleName == l.ModuleName() {
+		return l.public
+	}
+	if dl, ok := l.dependencies.(px.DependencyLoader); ok {
+		return dl.LoaderFor(moduleName)
+	}
+	return nil
+}
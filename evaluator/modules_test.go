@@ -0,0 +1,134 @@
+package evaluator_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/puppet-evaluator/evaluator"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+	"github.com/lyraproj/puppet-evaluator/puppet"
+)
+
+// modules are modules where a depends on b, b has no dependencies, c depends on b and on a module that
+// doesn't exist, and d has no metadata.json
+var modules = map[string]string{
+	`a/metadata.json`:        `{"name": "example-a", "dependencies": [{"name": "example-b"}]}`,
+	`a/functions/b.pp`:       `function a::b() { b::name() }`,
+	`a/functions/c.pp`:       `function a::c() { c::name() }`,
+	`a/functions/port.pp`:    `function a::port(A::Port $p) { $p }`,
+	`a/types/port.pp`:        `type A::Port = Integer[1, 65535]`,
+	`a/types/name.pp`:        `type A::Name = B::Name`,
+	`a/manifests/init.pp`:    `class a { notify { b::name(): } }`,
+	`b/metadata.json`:        `{"name": "example/b"}`,
+	`b/functions/name.pp`:    `function b::name() { 'from b' }`,
+	`b/functions/a.pp`:       `function b::a() { a::b() }`,
+	`b/types/name.pp`:        `type B::Name = Pattern[/\A[a-z]+\z/]`,
+	`c/metadata.json`:        `{"name": "example-c", "dependencies": [{"name": "example-b"}, {"name": "example-missing"}]}`,
+	`c/functions/name.pp`:    `function c::name() { b::name() }`,
+	`d/functions/all.pp`:     `function d::all() { [a::b(), c::name()] }`,
+	`d/manifests/init.pp`:    `class d { include a }`,
+	`not-a-module/README`:    ``,
+	`Invalid/functions/x.pp`: `function invalid::x() { }`,
+}
+
+// withLogger calls the given function with an evaluation context that logs to the returned logger
+func withLogger(f func(c pdsl.EvaluationContext)) *px.ArrayLogger {
+	logger := px.NewArrayLogger()
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		f(evaluator.NewContext(evaluator.NewEvaluator, c.Loader(), logger))
+	})
+	return logger
+}
+
+// warnings returns the codes of the issues that were logged as warnings to the given logger
+func warnings(logger *px.ArrayLogger) []issue.Code {
+	codes := make([]issue.Code, 0)
+	for _, e := range logger.Entries(px.WARNING) {
+		if re, ok := e.(*px.ReportedEntry); ok {
+			codes = append(codes, re.Issue().Code())
+		}
+	}
+	return codes
+}
+
+func TestModuleLoading(t *testing.T) {
+	withModules(t, modules, func(c pdsl.EvaluationContext, dir string) {
+		expectValues(t, c, map[string]string{
+			// A module sees the modules that it depends on
+			`a::b()`:    `from b`,
+			`c::name()`: `from b`,
+
+			// A module without metadata.json sees all modules
+			`d::all()`: `['from b', 'from b']`,
+
+			// Type aliases are loaded from the types directory and are visible to dependent modules
+			`8080 =~ A::Port`:  `true`,
+			`0 =~ A::Port`:     `false`,
+			`a::port(80)`:      `80`,
+			`'abc' =~ A::Name`: `true`,
+			`'ABC' =~ A::Name`: `false`,
+		})
+		cat := compile(c, `include d`)
+		if actual := resourceList(cat); actual != `Class[D], Class[A], Notify[from b]` {
+			t.Errorf(`unexpected resources %s`, actual)
+		}
+
+		// A module doesn't see modules that it doesn't depend on
+		for _, source := range []string{`a::c()`, `b::a()`} {
+			expectIssue(t, px.UnknownFunction, func() { evaluate(c, source) })
+		}
+		expectIssue(t, px.IllegalArgumentType, func() { evaluate(c, `a::port(0)`) })
+
+		// Directories that aren't valid module names are not modules
+		expectIssue(t, px.UnknownFunction, func() { evaluate(c, `invalid::x()`) })
+	})
+}
+
+func TestModulePathWarnings(t *testing.T) {
+	first := writeFiles(t, modules)
+	defer os.RemoveAll(first)
+	second := writeFiles(t, map[string]string{
+		`b/functions/name.pp`: `function b::name() { 'from second b' }`,
+		`e/functions/name.pp`: `function e::name() { 'from e' }`,
+	})
+	defer os.RemoveAll(second)
+	modulePath := strings.Join([]string{first, filepath.Join(first, `missing`), second}, string(filepath.ListSeparator))
+
+	var names []string
+	logger := withLogger(func(c pdsl.EvaluationContext) {
+		for _, m := range evaluator.ReadModulePath(c, modulePath) {
+			names = append(names, m.Name+`:`+strings.Join(m.Dependencies, `,`))
+		}
+	})
+
+	// The first module with a given name is used and the clash is logged
+	expected := `a:b b: c:b,missing d: e:`
+	if actual := strings.Join(names, ` `); actual != expected {
+		t.Errorf(`expected the modules %s, got %s`, expected, actual)
+	}
+	if actual := warnings(logger); len(actual) != 1 || actual[0] != pdsl.ModuleNameClash {
+		t.Errorf(`expected a %s warning, got %v`, pdsl.ModuleNameClash, actual)
+	}
+
+	// A dependency that can't be found is logged when the loaders are created
+	logger = withLogger(func(c pdsl.EvaluationContext) {
+		c.DoWithLoader(evaluator.NewModulePathLoader(c, c.Loader(), modulePath), func() {
+			expectValues(t, c, map[string]string{`b::name()`: `from b`, `c::name()`: `from b`, `e::name()`: `from e`})
+		})
+	})
+	if actual := warnings(logger); len(actual) != 2 || actual[0] != pdsl.ModuleNameClash || actual[1] != pdsl.MissingModuleDependency {
+		t.Errorf(`expected %s and %s warnings, got %v`, pdsl.ModuleNameClash, pdsl.MissingModuleDependency, actual)
+	}
+}
+
+func TestBadModuleMetadata(t *testing.T) {
+	dir := writeFiles(t, map[string]string{`a/metadata.json`: `{"name": `})
+	defer os.RemoveAll(dir)
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		expectIssue(t, pdsl.BadModuleMetadata, func() { evaluator.ReadModulePath(c, dir) })
+	})
+}
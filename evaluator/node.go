@@ -0,0 +1,109 @@
+package evaluator
+
+import (
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+// Node matches in order of precedence
+const (
+	exactNodeMatch = iota
+	regexpNodeMatch
+	defaultNodeMatch
+	noNodeMatch
+)
+
+type nodeMatch struct {
+	node     *parser.NodeDefinition
+	title    string
+	captures []string
+}
+
+func (c *evalCtx) EvaluateSiteManifest(certname string, manifest parser.Expression) pdsl.Catalog {
+	c.Set(pdsl.CertnameKey, certname)
+	c.AddDefinitions(manifest)
+	pdsl.TopEvaluate(c, manifest)
+	if len(c.nodes) > 0 {
+		selectNode(c, c.nodes, certname, manifest).evaluate(c)
+	}
+	if store, ok := c.Get(pdsl.ResourceStoreKey); ok {
+		exportResources(c.catalog, store.(pdsl.ResourceStore), certname)
+	}
+	return c.catalog
+}
+
+// exportResources replaces the resources that the given node has exported to the given store with the
+// exported resources of the given catalog
+func exportResources(catalog pdsl.Catalog, store pdsl.ResourceStore, certname string) {
+	exported := make([]pdsl.Resource, 0)
+	for _, r := range catalog.Resources() {
+		if r.Exported() {
+			exported = append(exported, r)
+		}
+	}
+	store.Export(certname, exported)
+}
+
+// selectNode returns the most specific match for the given certname among the given node definitions. It
+// is an error if no definition matches or if more than one definition is an equally specific match.
+func selectNode(c pdsl.EvaluationContext, nodes []*parser.NodeDefinition, certname string, location issue.Location) *nodeMatch {
+	bestRank := noNodeMatch
+	var best []*nodeMatch
+	add := func(rank int, m *nodeMatch) {
+		switch {
+		case rank < bestRank:
+			bestRank = rank
+			best = []*nodeMatch{m}
+		case rank == bestRank && best[len(best)-1].node != m.node:
+			best = append(best, m)
+		}
+	}
+
+	for _, node := range nodes {
+		for _, hm := range node.HostMatches() {
+			switch hv := pdsl.Evaluate(c, hm).(type) {
+			case *types.DefaultValue:
+				add(defaultNodeMatch, &nodeMatch{node: node, title: `default`})
+			case *types.Regexp:
+				if group := pdsl.RegexpOf(hv).FindStringSubmatch(certname); group != nil {
+					add(regexpNodeMatch, &nodeMatch{node: node, title: certname, captures: group})
+				}
+			default:
+				if strings.EqualFold(hv.String(), certname) {
+					add(exactNodeMatch, &nodeMatch{node: node, title: strings.ToLower(certname)})
+				}
+			}
+		}
+	}
+
+	switch len(best) {
+	case 0:
+		panic(evalError(pdsl.UnknownNode, location, issue.H{`name`: certname}))
+	case 1:
+		return best[0]
+	default:
+		first := best[0].node
+		panic(evalError(pdsl.DuplicateNode, best[1].node, issue.H{`name`: certname, `file`: first.File(), `line`: first.Line()}))
+	}
+}
+
+// evaluate adds the matched node to the catalog and evaluates its body. The resources declared by the
+// body are contained by the node.
+func (m *nodeMatch) evaluate(c pdsl.EvaluationContext) {
+	ref := newResourceType(`Node`, m.title)
+	c.Catalog().AddResource(NewResource(ref.typeName, ref.title, px.EmptyMap, m.node))
+	scope := NewNamedScope(``, globalScope(c.Scope().(pdsl.Scope)))
+	if m.captures != nil {
+		scope.RxSet(m.captures)
+	}
+	c.DoWithScope(scope, func() {
+		c.DoWithContainer(ref, func() {
+			pdsl.Evaluate(c, m.node.Body())
+		})
+	})
+}
@@ -0,0 +1,94 @@
+package evaluator_test
+
+import (
+	"testing"
+
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+	"github.com/lyraproj/puppet-evaluator/puppet"
+)
+
+const site = `
+node 'web01.example.com' { notify { 'exact': } }
+node /^web(\d+)\./ { notify { 'regexp': message => [$0, $1] } }
+node /^db\d+/, /\.db\./ { notify { 'database': } }
+node default { notify { 'default': } }
+notify { 'top': }
+`
+
+// evaluateSite evaluates the given site manifest for the given certname and returns the resulting catalog
+func evaluateSite(c pdsl.EvaluationContext, certname, manifest string) pdsl.Catalog {
+	return c.EvaluateSiteManifest(certname, c.ParseAndValidate(`site.pp`, manifest, false))
+}
+
+func TestNodeMatching(t *testing.T) {
+	for certname, expected := range map[string]string{
+		// An exact match takes precedence over a regexp match, which takes precedence over the default node
+		`web01.example.com`: `Notify[top], Node[web01.example.com], Notify[exact]`,
+		`WEB01.example.com`: `Notify[top], Node[web01.example.com], Notify[exact]`,
+		`web02.example.com`: `Notify[top], Node[web02.example.com], Notify[regexp]`,
+		`db01.example.com`:  `Notify[top], Node[db01.example.com], Notify[database]`,
+		`x.db.example.com`:  `Notify[top], Node[x.db.example.com], Notify[database]`,
+		`mail.example.com`:  `Notify[top], Node[default], Notify[default]`,
+	} {
+		puppet.Do(func(c pdsl.EvaluationContext) {
+			cat := evaluateSite(c, certname, site)
+			if actual := resourceList(cat); actual != expected {
+				t.Errorf(`%s: expected %s, got %s`, certname, expected, actual)
+			}
+		})
+	}
+
+	// The captures of a regexp match are available to the body of the node
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		expectAttributes(t, evaluateSite(c, `web02.example.com`, site), `Notify`, `regexp`, `{'message' => ['web02.', '02']}`)
+	})
+}
+
+func TestNodeContainment(t *testing.T) {
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		cat := evaluateSite(c, `web01.example.com`, `
+class base { notify { 'base': } }
+node /^web(\d+)/ { $x = $1 contain base notify { 'web': message => $x } }`)
+		for _, tt := range []struct{ typeName, title, container string }{
+			{`Notify`, `web`, `Node[web01.example.com]`},
+			{`Class`, `Base`, `Node[web01.example.com]`},
+			{`Notify`, `base`, `Class[Base]`},
+		} {
+			if actual := containerOf(cat, tt.typeName, tt.title); actual != tt.container {
+				t.Errorf(`%s[%s]: expected the container %q, got %q`, tt.typeName, tt.title, tt.container, actual)
+			}
+		}
+		expectAttributes(t, cat, `Notify`, `web`, `{'message' => '01'}`)
+	})
+
+	// A manifest without node definitions is evaluated as is
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		if actual := resourceList(evaluateSite(c, `web01`, `notify { 'x': }`)); actual != `Notify[x]` {
+			t.Errorf(`expected Notify[x], got %s`, actual)
+		}
+	})
+}
+
+func TestNodeErrors(t *testing.T) {
+	for _, tt := range []struct {
+		certname, manifest string
+
+		// line is the line of the second matching definition
+		line int
+	}{
+		{`web01`, "node 'web01' {}\nnode 'web01' {}", 2},
+		{`web01`, "node /web/ {}\n\nnode /01$/ {}", 3},
+		{`web01`, "node 'WEB01' {}\nnode 'x', 'web01' {}", 2},
+		{`web01`, "node default {}\nnode default {}", 2},
+	} {
+		puppet.Do(func(c pdsl.EvaluationContext) {
+			ri := expectIssue(t, pdsl.DuplicateNode, func() { evaluateSite(c, tt.certname, tt.manifest) })
+			if ri != nil && ri.Location().Line() != tt.line {
+				t.Errorf(`%s: expected %s on line %d, got line %d`, tt.manifest, pdsl.DuplicateNode, tt.line, ri.Location().Line())
+			}
+		})
+	}
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		expectIssue(t, pdsl.UnknownNode, func() { evaluateSite(c, `mail`, `node 'web01' {} node /^db/ {}`) })
+	})
+}
@@ -0,0 +1,42 @@
+package evaluator_test
+
+import (
+	"testing"
+
+	"github.com/lyraproj/pcore/pcore"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+	"github.com/lyraproj/puppet-evaluator/puppet"
+)
+
+// withRubyRegexps runs the given function with the ruby_regexp setting enabled
+func withRubyRegexps(f func(c pdsl.EvaluationContext)) {
+	pcore.Set(pdsl.RubyRegexpSetting, types.WrapBoolean(true))
+	defer pcore.Set(pdsl.RubyRegexpSetting, types.WrapBoolean(false))
+	puppet.Do(f)
+}
+
+func TestRubyRegexpValues(t *testing.T) {
+	withRubyRegexps(func(c pdsl.EvaluationContext) {
+		for source, expected := range map[string]string{
+			`'xa ba' =~ /(?<=x)a/`:                                 `true`,
+			`'ba' =~ /(?<=x)a/`:                                    `false`,
+			`$r = /(\w)\1/ 'abccd' =~ $r`:                          `true`,
+			`'abccd' =~ /(\w)\1/ $0`:                               `cc`,
+			`'xa' =~ Pattern[/(?<=x)a/]`:                           `true`,
+			`'ba' =~ Pattern[/(?<=x)a/]`:                           `false`,
+			`split('xa1ba', /(?<=a)/)`:                             `['xa', '1ba']`,
+			`match('abccd', /(\w)\1/)`:                             `['cc', 'c']`,
+			`['xa', 'ba'].filter |$s| { $s =~ /(?<=x)a/ }`:         `['xa']`,
+			`case 'xa' { /(?<=x)a/: { 'ok' } default: { 'bad' } }`: `ok`,
+			`'xa' ? { /(?<=x)a/ => 'ok', default => 'bad' }`:       `ok`,
+			`/(?<=x)a/`:              `/(?<=x)a/`,
+			`/(?<=x)a/ == /(?<=x)a/`: `true`,
+			`'0fx' =~ /^\h+x$/`:      `true`,
+		} {
+			if actual := evaluate(c, source); actual.String() != expected {
+				t.Errorf(`%s: expected %s, got %s`, source, expected, actual)
+			}
+		}
+	})
+}
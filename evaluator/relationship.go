@@ -0,0 +1,35 @@
+package evaluator
+
+import (
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+func evalRelationshipExpression(e pdsl.Evaluator, expr *parser.RelationshipExpression) px.Value {
+	lhs := e.Eval(expr.Lhs())
+	rhs := e.Eval(expr.Rhs())
+	sources := resourceReferences(expr.Lhs(), pdsl.IllegalRelationshipOperand, lhs, nil)
+	targets := resourceReferences(expr.Rhs(), pdsl.IllegalRelationshipOperand, rhs, nil)
+
+	subscribe := false
+	switch expr.Operator() {
+	case `~>`:
+		subscribe = true
+	case `<-`:
+		sources, targets = targets, sources
+	case `<~`:
+		sources, targets = targets, sources
+		subscribe = true
+	}
+
+	catalog := e.Catalog()
+	for _, source := range sources {
+		for _, target := range targets {
+			catalog.AddEdge(NewEdge(source, target, subscribe, expr))
+		}
+	}
+
+	// The result is the right hand side so that relationships can be chained
+	return rhs
+}
@@ -0,0 +1,81 @@
+package evaluator_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+	"github.com/lyraproj/puppet-evaluator/puppet"
+)
+
+// edgeList returns the edges of the given catalog, separated by commas
+func edgeList(cat pdsl.Catalog) string {
+	edges := make([]string, 0)
+	for _, e := range cat.Edges() {
+		arrow := ` -> `
+		if e.Subscribe() {
+			arrow = ` ~> `
+		}
+		edges = append(edges, e.Source().Type()+`[`+e.Source().Title()+`]`+arrow+e.Target().Type()+`[`+e.Target().Title()+`]`)
+	}
+	return strings.Join(edges, `, `)
+}
+
+func TestRelationshipOperators(t *testing.T) {
+	for source, expected := range map[string]string{
+		`Notify[a] -> Notify[b]`: `Notify[a] -> Notify[b]`,
+		`Notify[a] ~> Notify[b]`: `Notify[a] ~> Notify[b]`,
+		`Notify[a] <- Notify[b]`: `Notify[b] -> Notify[a]`,
+		`Notify[a] <~ Notify[b]`: `Notify[b] ~> Notify[a]`,
+
+		// Relationships are chained using the right hand side
+		`Notify[a] -> Notify[b] ~> Notify[c]`: `Notify[a] -> Notify[b], Notify[b] ~> Notify[c]`,
+
+		// Arrays of references and resource expressions
+		`[Notify[a], Notify[b]] -> Notify[c]`: `Notify[a] -> Notify[c], Notify[b] -> Notify[c]`,
+		`Notify[a] -> Notify[b, c]`:           `Notify[a] -> Notify[b], Notify[a] -> Notify[c]`,
+		`notify { d: } -> Notify[a]`:          `Notify[d] -> Notify[a]`,
+		`Notify[a] -> notify { [d, e]: }`:     `Notify[a] -> Notify[d], Notify[a] -> Notify[e]`,
+	} {
+		puppet.Do(func(c pdsl.EvaluationContext) {
+			cat := compile(c, `notify { [a, b, c]: }`+"\n"+source)
+			if actual := edgeList(cat); actual != expected {
+				t.Errorf(`%s: expected %s, got %s`, source, expected, actual)
+			}
+		})
+	}
+}
+
+func TestDependencyCycle(t *testing.T) {
+	for _, tt := range []struct {
+		source, cycle string
+
+		// line is the line of the relationship that closes the cycle
+		line int
+	}{
+		{`Notify[a] -> Notify[a]`, `(Notify[a] => Notify[a])`, 2},
+		{`Notify[a] -> Notify[b] -> Notify[a]`, `(Notify[b] => Notify[a] => Notify[b])`, 2},
+		{"Notify[a] -> Notify[b]\nNotify[b] ~> Notify[c]\nNotify[c] <- Notify[a]\nNotify[a] <- Notify[c]",
+			`(Notify[c] => Notify[a] => Notify[b] => Notify[c])`, 5},
+	} {
+		puppet.Do(func(c pdsl.EvaluationContext) {
+			ri := expectIssue(t, pdsl.DependencyCycle, func() { compile(c, `notify { [a, b, c]: }`+"\n"+tt.source) })
+			if ri == nil {
+				return
+			}
+			if !strings.Contains(ri.Error(), tt.cycle) {
+				t.Errorf(`%s: expected the cycle %s, got %s`, tt.source, tt.cycle, ri.Error())
+			}
+			if ri.Location().Line() != tt.line {
+				t.Errorf(`%s: expected the cycle on line %d, got line %d`, tt.source, tt.line, ri.Location().Line())
+			}
+		})
+	}
+}
+
+func TestIllegalRelationshipOperand(t *testing.T) {
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		expectIssue(t, pdsl.IllegalRelationshipOperand, func() { compile(c, `notify { a: } -> 'a'`) })
+		expectIssue(t, pdsl.IllegalRelationshipOperand, func() { compile(c, `[1] -> Notify[a]`) })
+	})
+}
@@ -0,0 +1,128 @@
+package evaluator
+
+import (
+	"fmt"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+)
+
+// A FunctionRegistry is the restricted set of functions that Deferred values can call when they are
+// resolved by ResolveDeferred
+type FunctionRegistry interface {
+	// Function returns the function with the given name, or false if the registry has no such function
+	Function(name string) (px.Function, bool)
+}
+
+type functionRegistry map[string]px.Function
+
+// NewFunctionRegistry returns a registry that contains the given functions
+func NewFunctionRegistry(functions ...px.Function) FunctionRegistry {
+	r := make(functionRegistry, len(functions))
+	for _, f := range functions {
+		r[f.Name()] = f
+	}
+	return r
+}
+
+// LoadFunctionRegistry returns a registry that contains the functions with the given names. The functions
+// are loaded using the loader of the given context.
+func LoadFunctionRegistry(c px.Context, names ...string) FunctionRegistry {
+	functions := make([]px.Function, len(names))
+	for i, name := range names {
+		f, ok := px.Load(c, px.NewTypedName(px.NsFunction, name))
+		if !ok {
+			panic(px.Error(px.UnknownFunction, issue.H{`name`: name}))
+		}
+		functions[i] = f.(px.Function)
+	}
+	return NewFunctionRegistry(functions...)
+}
+
+func (r functionRegistry) Function(name string) (px.Function, bool) {
+	f, ok := r[name]
+	return f, ok
+}
+
+// ResolveDeferred returns the given value with every Deferred value that it contains replaced by the
+// result of calling its function. The keys and values of hashes, the values of arrays and Sensitive values,
+// and the attributes of objects are resolved recursively, as are the arguments of each Deferred value. A Deferred value can only
+// call the functions of the given registry. A Deferred value that names a variable is resolved using the
+// scope of the given context.
+//
+// A Sensitive value remains Sensitive after its value has been resolved. The result of a Deferred value
+// is Sensitive when one of its arguments is Sensitive. The function is then called with the unwrapped
+// arguments.
+//
+// The function panics with an error that includes the path to the failing value when a Deferred value
+// cannot be resolved or when the given value contains itself.
+func ResolveDeferred(c px.Context, value px.Value, registry FunctionRegistry) px.Value {
+	r := &deferredResolver{registry: registry}
+	r.transformer = transformer{ctx: c, replacer: r.replace}
+	return r.transform(value)
+}
+
+type deferredResolver struct {
+	transformer
+	registry FunctionRegistry
+}
+
+func (r *deferredResolver) replace(v px.Value) px.Value {
+	if d, ok := v.(types.Deferred); ok {
+		return r.resolveDeferred(d)
+	}
+	return nil
+}
+
+func (r *deferredResolver) resolveDeferred(d types.Deferred) (result px.Value) {
+	defer func() {
+		if err := recover(); err != nil {
+			detail := fmt.Sprint(err)
+			if ir, ok := err.(issue.Reported); ok {
+				if ir.Code() == pdsl.CyclicValue || ir.Code() == pdsl.DeferredResolutionFailed {
+					panic(err)
+				}
+				detail = ir.WithLocation(nil).Error()
+			}
+			panic(px.Error(pdsl.DeferredResolutionFailed, issue.H{`path`: r.pathString(), `detail`: detail}))
+		}
+	}()
+
+	name := d.Name()
+	if name == `` {
+		// A DeferredExpression would be evaluated without restrictions
+		panic(px.Error(pdsl.UnresolvableDeferred, issue.H{`type`: d.PType().Name()}))
+	}
+
+	sensitive := false
+	var args []px.Value
+	if da := d.Arguments(); da != nil {
+		args = make([]px.Value, da.Len())
+		da.EachWithIndex(func(a px.Value, i int) {
+			a = r.transform(a)
+			if s, ok := a.(*types.Sensitive); ok {
+				sensitive = true
+				a = s.Unwrap()
+			}
+			args[i] = a
+		})
+	}
+
+	if name[0] == '$' {
+		result = types.NewDeferred(name, args...).Resolve(r.ctx, r.ctx.Scope())
+	} else {
+		f, ok := r.registry.Function(name)
+		if !ok {
+			panic(px.Error(pdsl.RestrictedFunction, issue.H{`name`: name}))
+		}
+		result = f.Call(r.ctx, nil, args...)
+	}
+	if sensitive {
+		if _, ok := result.(*types.Sensitive); !ok {
+			result = types.WrapSensitive(result)
+		}
+	}
+	return result
+}
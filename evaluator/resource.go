@@ -0,0 +1,294 @@
+package evaluator
+
+import (
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/pcore/utils"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+// attributeOperation is an evaluated operation in a resource body
+type attributeOperation struct {
+	name     string
+	value    px.Value
+	append   bool
+	location issue.Location
+}
+
+func evalResourceExpression(e pdsl.Evaluator, expr *parser.ResourceExpression) px.Value {
+	typeName := resourceTypeName(e, expr.TypeName())
+	var dt *puppetDefinedType
+	var defaults px.OrderedMap
+	if typeName != `Class` {
+		dt = loadDefinedType(e, typeName)
+		defaults = e.ResourceDefaults(typeName)
+	}
+	refs := make([]px.Value, 0, len(expr.Bodies()))
+	for _, b := range expr.Bodies() {
+		body := b.(*parser.ResourceBody)
+		titles := resourceTitles(body.Title(), e.Eval(body.Title()))
+		attributes := evalAttributeOperations(e, body.Operations())
+		for _, title := range titles {
+			if typeName == `Class` {
+				refs = append(refs, DeclareClass(e, title, attributes, true, body).(px.Value))
+				continue
+			}
+			var r pdsl.Resource
+			switch expr.Form() {
+			case parser.VIRTUAL:
+				r = NewVirtualResource(typeName, title, defaults.Merge(attributes), false, body)
+			case parser.EXPORTED:
+				r = NewVirtualResource(typeName, title, defaults.Merge(attributes), true, body)
+			default:
+				r = NewResource(typeName, title, defaults.Merge(attributes), body)
+			}
+			refs = append(refs, declareResource(e, r, dt))
+		}
+	}
+	return types.WrapValues(refs)
+}
+
+// declareResource adds the given resource to the catalog, contains it in the current container, applies
+// its metaparameters, and applies all collectors to it. The given defined type, if any, is evaluated for
+// the resource unless the resource is virtual.
+func declareResource(c pdsl.EvaluationContext, r pdsl.Resource, dt *puppetDefinedType) *resourceType {
+	ref := newResourceType(r.Type(), r.Title())
+	catalog := c.Catalog()
+	catalog.AddResource(r)
+	if container := c.Container(); container != nil {
+		catalog.Contain(container, ref)
+	}
+	applyMetaParameters(c, r)
+	for _, cl := range c.Collectors() {
+		// A previous collector may have changed the resource
+		cr, _ := catalog.Resource(ref.typeName, ref.title)
+		cl.Collect(c, cr)
+	}
+	if !r.Virtual() {
+		cr, _ := catalog.Resource(ref.typeName, ref.title)
+		addMetaParameterEdges(c, cr)
+		if dt != nil {
+			dt.evaluate(c, ref, cr.Attributes(), r.Location())
+		}
+	}
+	return ref
+}
+
+func evalResourceDefaultsExpression(e pdsl.Evaluator, expr *parser.ResourceDefaultsExpression) px.Value {
+	e.SetResourceDefaults(resourceTypeName(e, expr.TypeRef()), evalAttributeOperations(e, expr.Operations()))
+	return px.Undef
+}
+
+func evalResourceOverrideExpression(e pdsl.Evaluator, expr *parser.ResourceOverrideExpression) px.Value {
+	refs := resourceReferences(expr.Resources(), pdsl.IllegalResourceReference, e.Eval(expr.Resources()), nil)
+	ops := evalAttributeOperationList(e, expr.Operations())
+	catalog := e.Catalog()
+	for _, ref := range refs {
+		r, ok := catalog.Resource(ref.Type(), ref.Title())
+		if !ok {
+			panic(evalError(pdsl.UnknownResource, expr, issue.H{`resource`: ref}))
+		}
+		overrideResource(e, r, ops, mayRedefine(e, r))
+	}
+	return px.Undef
+}
+
+// mayRedefine returns true if the current container is a class that inherits the class that contains
+// the given resource. Such a class may change attributes of the resource that have already been set.
+func mayRedefine(c pdsl.EvaluationContext, r pdsl.Resource) bool {
+	owner, ok := c.Catalog().Container(r)
+	if !ok || owner.Type() != `Class` {
+		return false
+	}
+	container := c.Container()
+	if container == nil || container.Type() != `Class` {
+		return false
+	}
+	return classInherits(c, container.Title(), owner.Title())
+}
+
+// overrideResource applies the given attribute operations to the given resource in the catalog
+func overrideResource(c pdsl.EvaluationContext, r pdsl.Resource, ops []*attributeOperation, redefine bool) {
+	c.Catalog().SetAttributes(r, overrideAttributes(r, ops, redefine))
+}
+
+// overrideAttributes returns the attributes of the given resource with the given attribute operations
+// applied. An undef value removes the attribute and the +> operator appends the value to the current value
+// of the attribute. Changing an attribute that has already been set is an error unless redefine is true.
+func overrideAttributes(r pdsl.Resource, ops []*attributeOperation, redefine bool) px.OrderedMap {
+	attributes := r.Attributes()
+	for _, op := range ops {
+		value := op.value
+		if current, found := attributes.Get4(op.name); found {
+			if !redefine {
+				panic(evalError(pdsl.AttributeAlreadySet, op.location,
+					issue.H{`attribute`: op.name, `resource`: newResourceType(r.Type(), r.Title())}))
+			}
+			if op.append {
+				value = appendAttributeValue(current, value)
+			}
+		}
+		if _, ok := value.(*types.UndefValue); ok {
+			name := op.name
+			attributes = attributes.RejectPairs(func(k, v px.Value) bool { return k.String() == name })
+		} else {
+			attributes = attributes.Merge(types.WrapHash([]*types.HashEntry{types.WrapHashEntry2(op.name, value)}))
+		}
+	}
+	return attributes
+}
+
+// appendAttributeValue returns an array containing the elements of the current value followed by the
+// elements of the given value. Values that are not arrays are treated as arrays with one element.
+func appendAttributeValue(current, value px.Value) px.Value {
+	ca, ok := current.(*types.Array)
+	if !ok {
+		ca = types.SingletonArray(current)
+	}
+	if va, ok := value.(*types.Array); ok {
+		return ca.AddAll(va)
+	}
+	return ca.Add(value)
+}
+
+// evalResourceReference evaluates an access expression such as File['/tmp/x'], Resource[File, '/tmp/x'],
+// or Class['x'] into a reference to a resource. An array of references is returned when more than one
+// title is given and the type itself is returned when no title is given.
+func evalResourceReference(typeName string, args []px.Value, expr *parser.AccessExpression) px.Value {
+	if typeName == `Resource` {
+		if len(args) == 0 {
+			panic(evalError(pdsl.IllegalResourceType, expr, issue.H{`actual`: types.DefaultUndefType()}))
+		}
+		typeName = referencedTypeName(expr, args[0])
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return newResourceType(typeName, ``)
+	}
+	titles := resourceTitles(expr, types.WrapValues(args))
+	if typeName == `Class` {
+		// Class titles are always capitalized
+		for i, title := range titles {
+			titles[i] = classTitle(title)
+		}
+	}
+	if len(titles) == 1 {
+		return newResourceType(typeName, titles[0])
+	}
+	refs := make([]px.Value, len(titles))
+	for i, title := range titles {
+		refs[i] = newResourceType(typeName, title)
+	}
+	return types.WrapValues(refs)
+}
+
+// resourceReferences appends all references found in the given value to the given slice. The value must
+// be a reference or a possibly nested array of references. Other values cause the given issue to be raised
+// with the type of the value as its 'actual' argument.
+func resourceReferences(location issue.Location, code issue.Code, value px.Value, refs []pdsl.Reference) []pdsl.Reference {
+	switch value := value.(type) {
+	case *resourceType:
+		if value.typeName != `` && value.title != `` {
+			return append(refs, value)
+		}
+	case px.List:
+		if _, ok := value.(px.StringValue); !ok {
+			value.Each(func(v px.Value) { refs = resourceReferences(location, code, v, refs) })
+			return refs
+		}
+	}
+	panic(evalError(code, location, issue.H{`actual`: value.PType()}))
+}
+
+// isResourceTypeName returns true if the given name is Resource, Class, or cannot be resolved into a
+// known type and therefore is assumed to be the name of a resource type
+func isResourceTypeName(e pdsl.Evaluator, name string) bool {
+	switch types.Resolve(e, name).(type) {
+	case *types.TypeReferenceType, *resourceType:
+		return true
+	default:
+		return false
+	}
+}
+
+// resourceTypeName returns the capitalized name of the resource type that the given expression
+// evaluates to
+func resourceTypeName(e pdsl.Evaluator, expr parser.Expression) string {
+	switch qn := expr.(type) {
+	case *parser.QualifiedName:
+		return utils.CapitalizeSegments(qn.Name())
+	case *parser.QualifiedReference:
+		return utils.CapitalizeSegments(qn.Name())
+	}
+	return referencedTypeName(expr, e.Eval(expr))
+}
+
+// resourceTitles converts the value of the given title expression into a slice of strings. The
+// value must be a String or a possibly nested Array of strings.
+func resourceTitles(expr parser.Expression, value px.Value) []string {
+	switch tv := value.(type) {
+	case px.StringValue:
+		return []string{tv.String()}
+	case *types.Array:
+		tl := tv.Flatten()
+		titles := make([]string, tl.Len())
+		tl.EachWithIndex(func(t px.Value, i int) {
+			if s, ok := t.(px.StringValue); ok {
+				titles[i] = s.String()
+			} else {
+				panic(evalError(pdsl.IllegalTitleType, expr, issue.H{`index`: i, `actual`: t.PType()}))
+			}
+		})
+		return titles
+	default:
+		panic(evalError(pdsl.IllegalTitleType, expr, issue.H{`index`: 0, `actual`: tv.PType()}))
+	}
+}
+
+// evalAttributeOperations evaluates the operations of a resource body into a hash of attributes. Attributes
+// that evaluate to undef are considered unset and are not included in the result.
+func evalAttributeOperations(e pdsl.Evaluator, ops []parser.Expression) px.OrderedMap {
+	aos := evalAttributeOperationList(e, ops)
+	entries := make([]*types.HashEntry, 0, len(aos))
+	for _, ao := range aos {
+		if _, ok := ao.value.(*types.UndefValue); !ok {
+			entries = append(entries, types.WrapHashEntry2(ao.name, ao.value))
+		}
+	}
+	return types.WrapHash(entries)
+}
+
+// evalAttributeOperationList evaluates the operations of a resource body. The attributes of a '* =>'
+// operation are expanded into separate operations.
+func evalAttributeOperationList(e pdsl.Evaluator, ops []parser.Expression) []*attributeOperation {
+	aos := make([]*attributeOperation, 0, len(ops))
+	seen := make(map[string]bool, len(ops))
+	add := func(name string, value px.Value, appendOp bool, location issue.Location) {
+		if seen[name] {
+			panic(evalError(pdsl.DuplicateAttribute, location, issue.H{`attribute`: name}))
+		}
+		seen[name] = true
+		aos = append(aos, &attributeOperation{name: name, value: value, append: appendOp, location: location})
+	}
+
+	for _, op := range ops {
+		switch op := op.(type) {
+		case *parser.AttributeOperation:
+			add(op.Name(), e.Eval(op.Value()), op.Operator() == `+>`, op)
+		case *parser.AttributesOperation:
+			switch av := e.Eval(op.Expr()).(type) {
+			case *types.UndefValue:
+			case px.OrderedMap:
+				if !av.AllKeysAreStrings() {
+					panic(evalError(pdsl.AttributesNotHash, op, issue.H{`actual`: av.PType()}))
+				}
+				av.EachPair(func(k, v px.Value) { add(k.String(), v, false, op) })
+			default:
+				panic(evalError(pdsl.AttributesNotHash, op, issue.H{`actual`: av.PType()}))
+			}
+		}
+	}
+	return aos
+}
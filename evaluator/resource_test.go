@@ -0,0 +1,91 @@
+package evaluator_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+	"github.com/lyraproj/puppet-evaluator/puppet"
+)
+
+// compile evaluates the given manifest and returns the resulting catalog
+func compile(c pdsl.EvaluationContext, manifest string) pdsl.Catalog {
+	evaluateProgram(c, manifest)
+	return c.Catalog()
+}
+
+// resourceList returns the references of the non virtual resources in the given catalog, separated by
+// commas
+func resourceList(cat pdsl.Catalog) string {
+	refs := make([]string, 0)
+	for _, r := range cat.Resources() {
+		if !r.Virtual() {
+			refs = append(refs, r.Type()+`[`+r.Title()+`]`)
+		}
+	}
+	return strings.Join(refs, `, `)
+}
+
+// expectAttributes asserts that the attributes of the given resource have the expected string form
+func expectAttributes(t *testing.T, cat pdsl.Catalog, typeName, title, expected string) {
+	t.Helper()
+	r, ok := cat.Resource(typeName, title)
+	if !ok {
+		t.Errorf(`%s[%s] is not in the catalog`, typeName, title)
+		return
+	}
+	if actual := r.Attributes().String(); actual != expected {
+		t.Errorf(`%s[%s]: expected attributes %s, got %s`, typeName, title, expected, actual)
+	}
+}
+
+func TestResourceExpressions(t *testing.T) {
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		cat := compile(c, `
+file { '/tmp/x': ensure => present, mode => '0644' }
+file { ['/tmp/a', ['/tmp/b']]: ensure => directory }
+notify { 'one': message => 1; 'two': * => { message => 2 } }
+$refs = notify { 'three': }
+notify { 'refs': message => $refs }`)
+
+		if actual := resourceList(cat); actual != `File[/tmp/x], File[/tmp/a], File[/tmp/b], Notify[one], Notify[two], Notify[three], Notify[refs]` {
+			t.Errorf(`unexpected resources %s`, actual)
+		}
+		expectAttributes(t, cat, `File`, `/tmp/x`, `{'ensure' => 'present', 'mode' => '0644'}`)
+		expectAttributes(t, cat, `File`, `/tmp/b`, `{'ensure' => 'directory'}`)
+		expectAttributes(t, cat, `Notify`, `two`, `{'message' => 2}`)
+		expectAttributes(t, cat, `Notify`, `refs`, `{'message' => [Notify['three']]}`)
+	})
+}
+
+func TestDuplicateResource(t *testing.T) {
+	for source, line := range map[string]int{
+		"file { '/x': }\n\nfile { '/x': }":              3,
+		"file { '/x': }\nFile { '/x': }":                2,
+		"file { ['/x', '/y']: }\nfile { ['/y']: }":      2,
+		"notify { 'a': }\n@notify { 'a': }":             2,
+		"file { '/x': }\nfile { '/x': ensure => file }": 2,
+	} {
+		puppet.Do(func(c pdsl.EvaluationContext) {
+			ri := expectIssue(t, pdsl.DuplicateResource, func() { compile(c, source) })
+			if ri == nil {
+				return
+			}
+			if ri.Location().Line() != line {
+				t.Errorf(`%s: expected %s on line %d, got line %d`, source, pdsl.DuplicateResource, line, ri.Location().Line())
+			}
+			if !strings.Contains(ri.Error(), `already declared at test.pp:1`) {
+				t.Errorf(`%s: expected the message to contain the location of the first declaration, got %s`, source, ri.Error())
+			}
+		})
+	}
+}
+
+func TestResourceErrors(t *testing.T) {
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		expectIssue(t, pdsl.IllegalTitleType, func() { compile(c, `notify { 3: }`) })
+		expectIssue(t, pdsl.IllegalTitleType, func() { compile(c, `notify { ['a', [b, 1]]: }`) })
+		expectIssue(t, pdsl.DuplicateAttribute, func() { compile(c, `notify { 'a': message => 1, message => 2 }`) })
+		expectIssue(t, pdsl.AttributesNotHash, func() { compile(c, `notify { 'b': * => [1] }`) })
+	})
+}
@@ -0,0 +1,51 @@
+package evaluator
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+)
+
+type localResourceStore struct {
+	lock      sync.RWMutex
+	resources map[string][]pdsl.Resource
+}
+
+// NewLocalResourceStore returns a ResourceStore that keeps exported resources in memory. The store can be
+// shared between the evaluations of the catalogs of several nodes.
+func NewLocalResourceStore() pdsl.ResourceStore {
+	return &localResourceStore{resources: make(map[string][]pdsl.Resource)}
+}
+
+func (s *localResourceStore) Export(certname string, resources []pdsl.Resource) {
+	rs := make([]pdsl.Resource, len(resources))
+	copy(rs, resources)
+	s.lock.Lock()
+	s.resources[certname] = rs
+	s.lock.Unlock()
+}
+
+func (s *localResourceStore) Exported(typeName, certname string) []pdsl.Resource {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	// Sort the names of the nodes to ensure a predictable order
+	names := make([]string, 0, len(s.resources))
+	for name := range s.resources {
+		if name != certname {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	result := make([]pdsl.Resource, 0)
+	for _, name := range names {
+		for _, r := range s.resources[name] {
+			if r.Type() == typeName {
+				result = append(result, r)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,202 @@
+package evaluator
+
+import (
+	"io"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/pcore/utils"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+)
+
+// resourceType is the type of a reference to a resource such as File['/tmp/x']. A resourceType
+// without title represents all resources of the given type. A resourceType with the type name
+// Class is the type of a reference to a class such as Class['Foo'].
+type resourceType struct {
+	typeName string
+	title    string
+}
+
+var resourceMetaType px.ObjectType
+
+var classMetaType px.ObjectType
+
+func init() {
+	resourceMetaType = px.NewObjectType(`Pcore::ResourceType`, `Pcore::CatalogEntryType {
+	attributes => {
+		type_name => { type => Optional[Variant[String,Type]], value => undef },
+		title => { type => Optional[String], value => undef }
+	}
+}`, func(ctx px.Context, args []px.Value) px.Value {
+		typeName := ``
+		title := ``
+		if len(args) > 0 {
+			typeName = referencedTypeName(nil, args[0])
+			if len(args) > 1 {
+				title = args[1].String()
+			}
+		}
+		return newResourceType(typeName, title)
+	})
+
+	classMetaType = px.NewObjectType(`Pcore::ClassType`, `Pcore::CatalogEntryType {
+	attributes => {
+		title => { type => Optional[String], value => undef }
+	}
+}`, func(ctx px.Context, args []px.Value) px.Value {
+		title := ``
+		if len(args) > 0 {
+			title = classTitle(args[0].String())
+		}
+		return newResourceType(`Class`, title)
+	})
+
+	px.RegisterResolvableType(newResourceType(``, ``))
+	px.RegisterResolvableType(newResourceType(`Class`, ``))
+}
+
+func newResourceType(typeName, title string) *resourceType {
+	return &resourceType{typeName: typeName, title: title}
+}
+
+func (t *resourceType) Accept(v px.Visitor, g px.Guard) {
+	v(t)
+}
+
+func (t *resourceType) Default() px.Type {
+	if t.typeName == `Class` {
+		return newResourceType(`Class`, ``)
+	}
+	return newResourceType(``, ``)
+}
+
+func (t *resourceType) Equals(o interface{}, g px.Guard) bool {
+	if ot, ok := o.(*resourceType); ok {
+		return t.typeName == ot.typeName && t.title == ot.title
+	}
+	return false
+}
+
+func (t *resourceType) Get(key string) (px.Value, bool) {
+	switch key {
+	case `type_name`:
+		if t.typeName == `` || t.typeName == `Class` {
+			return px.Undef, true
+		}
+		return types.WrapString(t.typeName), true
+	case `title`:
+		if t.title == `` {
+			return px.Undef, true
+		}
+		return types.WrapString(t.title), true
+	default:
+		return nil, false
+	}
+}
+
+func (t *resourceType) IsAssignable(o px.Type, g px.Guard) bool {
+	if ot, ok := o.(*resourceType); ok {
+		if t.typeName == `` {
+			// Classes are not resources
+			return ot.typeName != `Class`
+		}
+		return t.typeName == ot.typeName && (t.title == `` || t.title == ot.title)
+	}
+	return false
+}
+
+func (t *resourceType) IsInstance(o px.Value, g px.Guard) bool {
+	return false
+}
+
+func (t *resourceType) MetaType() px.ObjectType {
+	if t.typeName == `Class` {
+		return classMetaType
+	}
+	return resourceMetaType
+}
+
+func (t *resourceType) Name() string {
+	if t.typeName == `Class` {
+		return `Class`
+	}
+	return `Resource`
+}
+
+func (t *resourceType) Parameters() []px.Value {
+	if t.typeName == `` {
+		return px.EmptyValues
+	}
+	if t.typeName == `Class` {
+		if t.title == `` {
+			return px.EmptyValues
+		}
+		return []px.Value{types.WrapString(t.title)}
+	}
+	if t.title == `` {
+		return []px.Value{types.WrapString(t.typeName)}
+	}
+	return []px.Value{types.WrapString(t.typeName), types.WrapString(t.title)}
+}
+
+func (t *resourceType) PType() px.Type {
+	return types.NewTypeType(t)
+}
+
+// Resolve returns the receiver. It enables the Resource and Class types to be registered with the loader.
+func (t *resourceType) Resolve(c px.Context) px.Type {
+	return t
+}
+
+func (t *resourceType) String() string {
+	return px.ToString2(t, types.None)
+}
+
+// Title returns the title of the referenced resource
+func (t *resourceType) Title() string {
+	return t.title
+}
+
+func (t *resourceType) ToString(b io.Writer, s px.FormatContext, g px.RDetect) {
+	if t.typeName == `` {
+		utils.WriteString(b, `Resource`)
+		return
+	}
+	utils.WriteString(b, t.typeName)
+	if t.title != `` {
+		utils.WriteByte(b, '[')
+		utils.PuppetQuote(b, t.title)
+		utils.WriteByte(b, ']')
+	}
+}
+
+// Type returns the capitalized name of the referenced resource type
+func (t *resourceType) Type() string {
+	return t.typeName
+}
+
+// classTitle returns the title used when referencing the class with the given name, i.e. the capitalized
+// name without leading '::'
+func classTitle(name string) string {
+	return utils.CapitalizeSegments(strings.TrimPrefix(name, `::`))
+}
+
+// referencedTypeName returns the capitalized name of the resource type that the given value references. The
+// value must be a String or a Type.
+func referencedTypeName(location issue.Location, value px.Value) string {
+	switch tn := value.(type) {
+	case px.StringValue:
+		return utils.CapitalizeSegments(tn.String())
+	case *resourceType:
+		if tn.title == `` {
+			return tn.typeName
+		}
+	case *types.TypeReferenceType:
+		return utils.CapitalizeSegments(tn.TypeString())
+	case px.Type:
+		return tn.Name()
+	}
+	panic(evalError(pdsl.IllegalResourceType, location, issue.H{`actual`: value.PType()}))
+}
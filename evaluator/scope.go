@@ -1,8 +1,10 @@
 package evaluator
 
 import (
+	"fmt"
 	"strings"
 
+	"github.com/lyraproj/issue/issue"
 	"github.com/lyraproj/pcore/px"
 	"github.com/lyraproj/pcore/types"
 	"github.com/lyraproj/puppet-evaluator/pdsl"
@@ -18,6 +20,15 @@ type (
 		BasicScope
 		parent pdsl.Scope
 	}
+
+	// namedScope is the scope of a class or a defined type instance. Variables that are assigned at the
+	// top level of a named scope are also assigned to the global scope using the qualified name
+	// <scope name>::<variable name> so that they can be found from any other scope. This does not apply
+	// to a scope with an empty name.
+	namedScope struct {
+		parentedScope
+		name string
+	}
 )
 
 // NewScope creates a new Scope instance that in turn consists of a stack of ephemeral scopes. If
@@ -36,12 +47,45 @@ func NewParentedScope(parent pdsl.Scope, mutable bool) pdsl.Scope {
 	return &parentedScope{BasicScope{[]map[string]px.Value{make(map[string]px.Value, 8)}, mutable}, parent}
 }
 
+// NewNamedScope creates a scope with the given name. The parent must be a scope that only contains
+// global variables, or the named scope of a parent class. Variables in a scope with an empty name cannot
+// be found from other scopes.
+func NewNamedScope(name string, parent pdsl.Scope) pdsl.Scope {
+	return &namedScope{parentedScope{BasicScope{[]map[string]px.Value{make(map[string]px.Value, 8)}, false}, parent}, name}
+}
+
 func NewScope2(h *types.Hash, mutable bool) pdsl.Scope {
 	top := make(map[string]px.Value, h.Len())
 	h.EachPair(func(k, v px.Value) { top[k.String()] = v })
 	return &BasicScope{[]map[string]px.Value{top}, mutable}
 }
 
+// reservedVariables are the names of the variables that cannot be assigned by Puppet code, nor be used as
+// parameter names. They are assigned to the global scope by the evaluation context using setGlobal.
+var reservedVariables = map[string]bool{`facts`: true, `trusted`: true}
+
+// assertNotReserved panics with an IllegalReservedAssignment error if the given name is reserved. It guards
+// Set against callers that assign variables without going through the checks of the evaluator.
+func assertNotReserved(name string) {
+	if reservedVariables[name] {
+		panic(px.Error(pdsl.IllegalReservedAssignment, issue.H{`var`: name}))
+	}
+}
+
+// setGlobal assigns a variable in the global scope of the given scope, replacing any previous value
+func setGlobal(scope pdsl.Scope, name string, value px.Value) {
+	switch s := scope.(type) {
+	case *namedScope:
+		setGlobal(s.parent, name, value)
+	case *parentedScope:
+		s.scopes[0][name] = value
+	case *BasicScope:
+		s.scopes[0][name] = value
+	default:
+		panic(fmt.Sprintf(`unable to assign global variable '%s' in a %T`, name, scope))
+	}
+}
+
 // No key can ever start with '::' or a capital letter
 var groupKey = `::R`
 
@@ -123,6 +167,7 @@ func (e *BasicScope) Set(name string, value px.Value) bool {
 	} else {
 		scopeIdx = len(e.scopes) - 1
 	}
+	assertNotReserved(name)
 	current := e.scopes[scopeIdx]
 
 	if e.mutable && scopeIdx > 0 {
@@ -184,6 +229,7 @@ func (e *parentedScope) Set(name string, value px.Value) bool {
 	} else {
 		scopeIdx = len(e.scopes) - 1
 	}
+	assertNotReserved(name)
 	if scopeIdx == 0 && e.parent.State(name) == px.Global {
 		// Attempt to override global declared in parent. Only $pnr can do
 		// that and the override ends up here, not in the parent.
@@ -214,3 +260,57 @@ func (e *parentedScope) State(name string) px.VariableState {
 	}
 	return e.parent.State(name)
 }
+
+func (e *namedScope) Fork() pdsl.Scope {
+	clone := &namedScope{name: e.name}
+	clone.copyFrom(&e.BasicScope)
+	clone.parent = e.parent.Fork()
+	return clone
+}
+
+func (e *namedScope) Get(nv px.Value) (value px.Value, found bool) {
+	return e.Get2(nv.String())
+}
+
+func (e *namedScope) Get2(name string) (value px.Value, found bool) {
+	if strings.HasPrefix(name, `::`) {
+		return e.parent.Get2(name)
+	}
+	return e.parentedScope.Get2(name)
+}
+
+func (e *namedScope) Set(name string, value px.Value) bool {
+	if strings.HasPrefix(name, `::`) {
+		return e.parent.Set(name, value)
+	}
+	if !e.BasicScope.Set(name, value) {
+		return false
+	}
+	if len(e.scopes) == 1 && e.name != `` {
+		e.parent.Set(`::`+e.name+`::`+name, value)
+	}
+	return true
+}
+
+func (e *namedScope) State(name string) px.VariableState {
+	if strings.HasPrefix(name, `::`) {
+		return e.parent.State(name)
+	}
+	return e.parentedScope.State(name)
+}
+
+// globalScope returns a scope that shares the global variables of the given scope but none of
+// its local variables.
+func globalScope(s pdsl.Scope) pdsl.Scope {
+	switch s := s.(type) {
+	case *namedScope:
+		// The parent is the scope of a parent class when the class inherits another class
+		return globalScope(s.parent)
+	case *parentedScope:
+		return &parentedScope{BasicScope{s.scopes[0:1:1], s.mutable}, s.parent}
+	case *BasicScope:
+		return &BasicScope{s.scopes[0:1:1], s.mutable}
+	default:
+		return s
+	}
+}
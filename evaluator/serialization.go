@@ -0,0 +1,285 @@
+package evaluator
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/serialization"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+// The types of the objects that represent lambdas, functions, and iterators in serialized form
+var lambdaType, functionReferenceType, iteratorValueType px.ObjectType
+
+func init() {
+	lambdaType = px.NewObjectType(`Lambda`, `{
+	attributes => {
+	  # The source of the lambda expression
+	  source => String
+	}
+}`)
+
+	functionReferenceType = px.NewObjectType(`FunctionReference`, `{
+	attributes => {
+	  # The name of the function
+	  name => String
+	}
+}`)
+
+	iteratorValueType = px.NewObjectType(`IteratorValue`, `{
+	attributes => {
+	  # The values that remained in the iterator when it was serialized
+	  values => Array
+	}
+}`)
+}
+
+// SerializeJSON writes the given value in the Pcore rich data JSON format to the given writer. See
+// ToSerializable for how values that have no rich data representation are written.
+func SerializeJSON(c pdsl.EvaluationContext, value px.Value, out io.Writer) {
+	dc := types.NewCollector()
+	serialization.NewSerializer(c, px.EmptyMap).Convert(ToSerializable(c, value), dc)
+	WriteJSON(out, dc.Value())
+}
+
+// DeserializeJSON reads a value in the Pcore rich data JSON format from the given reader. Values that
+// were written by SerializeJSON are restored using FromSerializable.
+func DeserializeJSON(c pdsl.EvaluationContext, in io.Reader) px.Value {
+	ds := serialization.NewDeserializer(c, px.EmptyMap)
+	serialization.JsonToData(``, in, ds)
+	return FromSerializable(c, ds.Value())
+}
+
+// WriteJSON writes the given value as JSON to the given writer. Hash entries retain their order and hash keys
+// are written as strings. A Deferred value is written in the rich data format. Other values that have no JSON
+// representation are written as strings. The function panics if the writer returns an error.
+func WriteJSON(out io.Writer, value px.Value) {
+	w := bufio.NewWriter(out)
+	writeJSON(w, value)
+	assertWrite(w.Flush())
+}
+
+// writeJSON writes the given value as JSON. The JSON streamer of the serialization package is not used
+// since it fails to delimit values that follow a nested array or hash.
+func writeJSON(out *bufio.Writer, value px.Value) {
+	switch value := value.(type) {
+	case *types.Array:
+		assertWrite(out.WriteByte('['))
+		value.EachWithIndex(func(e px.Value, i int) {
+			if i > 0 {
+				assertWrite(out.WriteByte(','))
+			}
+			writeJSON(out, e)
+		})
+		assertWrite(out.WriteByte(']'))
+	case *types.Hash:
+		assertWrite(out.WriteByte('{'))
+		value.EachWithIndex(func(e px.Value, i int) {
+			if i > 0 {
+				assertWrite(out.WriteByte(','))
+			}
+			he := e.(px.MapEntry)
+			writeScalar(out, he.Key().String())
+			assertWrite(out.WriteByte(':'))
+			writeJSON(out, he.Value())
+		})
+		assertWrite(out.WriteByte('}'))
+	case types.Deferred:
+		_, err := out.WriteString(`{"__ptype":"Deferred","name":`)
+		assertWrite(err)
+		writeScalar(out, value.Name())
+		if args := value.Arguments(); args != nil && args.Len() > 0 {
+			_, err = out.WriteString(`,"arguments":`)
+			assertWrite(err)
+			writeJSON(out, args)
+		}
+		assertWrite(out.WriteByte('}'))
+	case nil, *types.UndefValue:
+		writeScalar(out, nil)
+	case px.StringValue:
+		writeScalar(out, value.String())
+	case px.Float:
+		writeScalar(out, value.Float())
+	case px.Integer:
+		writeScalar(out, value.Int())
+	case px.Boolean:
+		writeScalar(out, value.Bool())
+	default:
+		writeScalar(out, value.String())
+	}
+}
+
+func writeScalar(out *bufio.Writer, v interface{}) {
+	bs, err := json.Marshal(v)
+	if err == nil {
+		_, err = out.Write(bs)
+	}
+	assertWrite(err)
+}
+
+func assertWrite(err error) {
+	if err != nil {
+		panic(px.Error(px.Failure, issue.H{`message`: err.Error()}))
+	}
+}
+
+// ToSerializable returns the given value with all values that the Pcore serializer cannot represent
+// replaced by objects that it can represent. A Puppet lambda is replaced by a Lambda that holds its
+// source, a function by a FunctionReference that holds its name, and an iterator by an IteratorValue
+// that holds the values that remain in the iterator. The iterator is consumed in the process.
+//
+// Values in arrays, hashes, Sensitive values, the arguments of Deferred values, and the attributes of
+// objects are replaced. The given value is returned unchanged when nothing was replaced.
+func ToSerializable(c px.Context, value px.Value) px.Value {
+	return transform(c, value, func(v px.Value) px.Value {
+		switch v := v.(type) {
+		case *puppetLambda:
+			return px.New(c, lambdaType, types.WrapString(v.expression.String()))
+		case px.Function:
+			return px.New(c, functionReferenceType, types.WrapString(v.Name()))
+		case px.IteratorValue:
+			return px.New(c, iteratorValueType, ToSerializable(c, v.AsArray()))
+		}
+		return nil
+	})
+}
+
+// FromSerializable is the inverse of ToSerializable. It replaces the Lambda, FunctionReference, and
+// IteratorValue objects in the given value with the values that they represent. A function is loaded
+// using its name.
+func FromSerializable(c pdsl.EvaluationContext, value px.Value) px.Value {
+	return transform(c, value, func(v px.Value) px.Value {
+		po, ok := v.(px.PuppetObject)
+		if !ok {
+			return nil
+		}
+		switch po.PType() {
+		case lambdaType:
+			source, _ := po.Get(`source`)
+			call := parseSource(`lambda() ` + source.String()).(*parser.CallNamedFunctionExpression)
+			return NewPuppetLambda(call.Lambda().(*parser.LambdaExpression), c)
+		case functionReferenceType:
+			name, _ := po.Get(`name`)
+			f, ok := px.Load(c, px.NewTypedName(px.NsFunction, name.String()))
+			if !ok {
+				panic(px.Error(px.UnknownFunction, issue.H{`name`: name.String()}))
+			}
+			return f.(px.Value)
+		case iteratorValueType:
+			values, _ := po.Get(`values`)
+			return WrapIterator(WrapIterable(FromSerializable(c, values).(*types.Array)))
+		}
+		return nil
+	})
+}
+
+// transform returns the given value with each contained value for which the replacer returns a non
+// nil value replaced with that value. Containers are only copied when one of their values is replaced.
+func transform(c px.Context, v px.Value, replacer func(px.Value) px.Value) px.Value {
+	return (&transformer{ctx: c, replacer: replacer}).transform(v)
+}
+
+// A transformer walks a value and replaces each value for which its replacer returns a non nil value. The
+// keys and values of arrays and hashes, the value of Sensitive values, the arguments of Deferred values,
+// and the attributes of objects are walked recursively. It panics when a value contains itself.
+type transformer struct {
+	ctx      px.Context
+	replacer func(px.Value) px.Value
+
+	// path holds the index, key, or attribute name of each value from the root down to the value that
+	// is currently being transformed, and ancestors holds the containers that these keys were found in
+	path      []px.Value
+	ancestors []px.Value
+}
+
+func (t *transformer) transform(v px.Value) px.Value {
+	if r := t.replacer(v); r != nil {
+		return r
+	}
+	switch v := v.(type) {
+	case *types.Array:
+		t.enter(v)
+		defer t.leave()
+		var elements []px.Value
+		v.EachWithIndex(func(e px.Value, i int) {
+			t.path = append(t.path, types.WrapInteger(int64(i)))
+			te := t.transform(e)
+			t.path = t.path[:len(t.path)-1]
+			if te != e && elements == nil {
+				elements = v.AppendTo(make([]px.Value, 0, v.Len()))
+			}
+			if elements != nil {
+				elements[i] = te
+			}
+		})
+		if elements != nil {
+			return types.WrapValues(elements)
+		}
+	case *types.Hash:
+		t.enter(v)
+		defer t.leave()
+		if th, changed := t.transformEntries(v); changed {
+			return th
+		}
+	case *types.Sensitive:
+		if tu := t.transform(v.Unwrap()); tu != v.Unwrap() {
+			return types.WrapSensitive(tu)
+		}
+	case *deferredExpr:
+	case types.Deferred:
+		if args := v.Arguments(); args != nil {
+			if ta := t.transform(args); ta != args {
+				return types.NewDeferred(v.Name(), ta.(*types.Array).AppendTo(nil)...)
+			}
+		}
+	case px.Type:
+	case px.PuppetObject:
+		t.enter(v)
+		defer t.leave()
+		if ih, changed := t.transformEntries(v.InitHash().(*types.Hash)); changed {
+			return px.New(t.ctx, v.PType(), ih)
+		}
+	}
+	return v
+}
+
+func (t *transformer) transformEntries(h *types.Hash) (*types.Hash, bool) {
+	changed := false
+	entries := make([]*types.HashEntry, 0, h.Len())
+	h.EachPair(func(k, e px.Value) {
+		tk := t.transform(k)
+		t.path = append(t.path, k)
+		te := t.transform(e)
+		t.path = t.path[:len(t.path)-1]
+		changed = changed || tk != k || te != e
+		entries = append(entries, types.WrapHashEntry(tk, te))
+	})
+	if changed {
+		return types.WrapHash(entries), true
+	}
+	return h, false
+}
+
+// enter adds the given container to the ancestors of the values that are transformed next. It is an error
+// if the container already is an ancestor.
+func (t *transformer) enter(container px.Value) {
+	for _, a := range t.ancestors {
+		if sameObject(a, container) {
+			panic(px.Error(pdsl.CyclicValue, issue.H{`path`: t.pathString()}))
+		}
+	}
+	t.ancestors = append(t.ancestors, container)
+}
+
+func (t *transformer) leave() {
+	t.ancestors = t.ancestors[:len(t.ancestors)-1]
+}
+
+func (t *transformer) pathString() string {
+	return types.WrapValues(t.path).String()
+}
@@ -0,0 +1,128 @@
+package evaluator_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/puppet-evaluator/evaluator"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+	"github.com/lyraproj/puppet-evaluator/puppet"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+// roundTrip serializes the given value to JSON, asserts that the JSON contains the given text, and returns
+// the deserialized value
+func roundTrip(t *testing.T, c pdsl.EvaluationContext, value px.Value, contains string) px.Value {
+	t.Helper()
+	b := bytes.NewBufferString(``)
+	evaluator.SerializeJSON(c, value, b)
+	if !bytes.Contains(b.Bytes(), []byte(contains)) {
+		t.Fatalf(`expected %s in %s`, contains, b)
+	}
+	return evaluator.DeserializeJSON(c, b)
+}
+
+// evaluate returns the result of evaluating the given source
+func evaluate(c pdsl.EvaluationContext, source string) px.Value {
+	return pdsl.TopEvaluate(c, c.ParseAndValidate(`test.pp`, source, false))
+}
+
+func loadFunction(t *testing.T, c pdsl.EvaluationContext, name string) px.Function {
+	t.Helper()
+	f, ok := px.Load(c, px.NewTypedName(px.NsFunction, name))
+	if !ok {
+		t.Fatalf(`unable to load function %s`, name)
+	}
+	return f.(px.Function)
+}
+
+func TestSerializeLambda(t *testing.T) {
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		expr, err := parser.CreateParser().Parse(``, `lambda() |$x, $y = 2| { $x * $y + 1 }`, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lambda := evaluator.NewPuppetLambda(expr.(*parser.CallNamedFunctionExpression).Lambda().(*parser.LambdaExpression), c)
+
+		v := roundTrip(t, c, types.WrapValues([]px.Value{lambda}), `"__ptype":"Lambda"`)
+		l, ok := v.(*types.Array).At(0).(px.Lambda)
+		if !ok {
+			t.Fatalf(`expected a lambda, got %s`, v)
+		}
+		if r := l.Call(c, nil, types.WrapInteger(3)); !r.Equals(types.WrapInteger(7), nil) {
+			t.Errorf(`expected the lambda to return 7, got %s`, r)
+		}
+	})
+}
+
+func TestSerializeFunctionReference(t *testing.T) {
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		v := roundTrip(t, c, types.WrapStringToValueMap(map[string]px.Value{`f`: loadFunction(t, c, `split`)}), `"__ptype":"FunctionReference"`)
+		f, ok := v.(*types.Hash).Get5(`f`, px.Undef).(px.Function)
+		if !ok {
+			t.Fatalf(`expected a function, got %s`, v)
+		}
+		if r := f.Call(c, nil, types.WrapString(`a-b`), types.WrapString(`-`)); r.String() != `['a', 'b']` {
+			t.Errorf(`expected ['a', 'b'], got %s`, r)
+		}
+	})
+}
+
+func TestSerializeDeferred(t *testing.T) {
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		d := types.NewDeferred(`split`, types.WrapString(`a-b`), types.WrapString(`-`))
+		v := roundTrip(t, c, d, `"__ptype":"Deferred"`)
+		if !d.Equals(v, nil) {
+			t.Errorf(`expected %s, got %s`, d, v)
+		}
+		rv := evaluator.ResolveDeferred(c, v, evaluator.LoadFunctionRegistry(c, `split`))
+		if rv.String() != `['a', 'b']` {
+			t.Errorf(`expected ['a', 'b'], got %s`, rv)
+		}
+	})
+}
+
+func TestSerializeDeferredExpression(t *testing.T) {
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		dt, ok := px.Load(c, px.NewTypedName(px.NsType, `DeferredExpression`))
+		if !ok {
+			t.Fatal(`unable to load type DeferredExpression`)
+		}
+		d := px.New(c, dt.(px.Type), types.WrapString(`[1, 2].map |$x| { $x * 3 }`))
+		v := roundTrip(t, c, d, `"__ptype":"DeferredExpression"`)
+		if !d.Equals(v, nil) {
+			t.Errorf(`expected %s, got %s`, d, v)
+		}
+		if r := v.(types.Deferred).Resolve(c, nil); r.String() != `[3, 6]` {
+			t.Errorf(`expected [3, 6], got %s`, r)
+		}
+	})
+}
+
+func TestSerializeIterator(t *testing.T) {
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		iter := evaluate(c, `[1, 2, 3].reverse_each`)
+		v := roundTrip(t, c, iter, `"__ptype":"IteratorValue"`)
+		it, ok := v.(px.IteratorValue)
+		if !ok {
+			t.Fatalf(`expected an iterator, got %s`, v)
+		}
+		if a := it.AsArray(); a.String() != `[3, 2, 1]` {
+			t.Errorf(`expected [3, 2, 1], got %s`, a)
+		}
+	})
+}
+
+func TestSerializeUnchanged(t *testing.T) {
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		value := evaluate(c, `{ a => [1, 2.5, true, undef], 'b' => { c => 'text "quoted"' } }`)
+		if v := roundTrip(t, c, value, `"text \"quoted\""`); !value.Equals(v, nil) {
+			t.Errorf(`expected %s, got %s`, value, v)
+		}
+		if evaluator.ToSerializable(c, value) != value {
+			t.Error(`expected ToSerializable to return a value without replacements unchanged`)
+		}
+	})
+}
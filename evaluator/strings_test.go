@@ -0,0 +1,111 @@
+package evaluator_test
+
+import (
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+	"github.com/lyraproj/puppet-evaluator/puppet"
+)
+
+func TestRegsubst(t *testing.T) {
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		expectValues(t, c, map[string]string{
+			`regsubst('aaa', 'a', 'b')`:                     `baa`,
+			`regsubst('aaa', 'a', 'b', 'G')`:                `bbb`,
+			`regsubst('AaA', 'a', 'b', 'I')`:                `baA`,
+			`regsubst('AaA', 'a', 'b', 'GI')`:               `bbb`,
+			"regsubst(\"a\\nb\", 'a.b', 'x')":               "a\nb",
+			"regsubst(\"a\\nb\", 'a.b', 'x', 'M')":          `x`,
+			`regsubst('abc', 'a b  # comment', 'x', 'E')`:   `xc`,
+			`regsubst('abc', 'a b', 'x')`:                   `abc`,
+			`regsubst('no match', 'x', 'y', 'G')`:           `no match`,
+			`regsubst('abc', /(a)(b)/, '\2\1')`:             `bac`,
+			`regsubst('abc', 'b', '[\0\&]')`:                `a[bb]c`,
+			"regsubst('abc', 'b', '\\\\`|\\\\\\'')":         `aa|cc`,
+			`regsubst('ab', '(?<x>a)', '\k<x>\k<x>')`:       `aab`,
+			`regsubst('ab', 'a', '\\\\')`:                   `\b`,
+			`regsubst('a1b2', /\d/, { '1' => 'one' }, 'G')`: `aoneb`,
+			`regsubst('aXa', Regexp['a'], 'b', 'G')`:        `bXb`,
+			`regsubst('abc', Regexp, 'x')`:                  `xabc`,
+
+			// Each string of an array is substituted
+			`regsubst(['ab', 'cb', 'bb'], 'b', 'x')`:      `['ax', 'cx', 'xb']`,
+			`regsubst(['ab', 'cb', 'bb'], 'b', 'x', 'G')`: `['ax', 'cx', 'xx']`,
+			`regsubst([], 'b', 'x')`:                      `[]`,
+		})
+		for source, code := range map[string]issue.Code{
+			`regsubst('a', '(', 'b')`:      px.InvalidRegexp,
+			`regsubst('a', 'a', 'b', 'X')`: px.IllegalArguments,
+			`regsubst('a', /a/, 'b', 'I')`: px.IllegalArguments,
+			`regsubst(1, 'a', 'b')`:        px.IllegalArguments,
+		} {
+			expectIssue(t, code, func() { evaluate(c, source) })
+		}
+	})
+}
+
+func TestScanf(t *testing.T) {
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		expectValues(t, c, map[string]string{
+			`scanf('42', '%d')`:                                          `[42]`,
+			`scanf('-42 17', '%d %u')`:                                   `[-42, 17]`,
+			`scanf('0x1f 017 12', '%i %i %i')`:                           `[31, 15, 12]`,
+			`scanf('17', '%o')`:                                          `[15]`,
+			`scanf('ff 0xFF', '%x %X')`:                                  `[255, 255]`,
+			`scanf('3.25 1e3 -2.5', '%f %e %g') == [3.25, 1000.0, -2.5]`: `true`,
+			`scanf('hello world', '%s %s')`:                              `['hello', 'world']`,
+			`scanf('abcdef', '%3c%s')`:                                   `['abc', 'def']`,
+			`scanf('12345', '%2d%d')`:                                    `[12, 345]`,
+			`scanf('a=1', '%[a-z]=%d')`:                                  `['a', 1]`,
+			`scanf('abc]def', '%[]a-c]%s')`:                              `['abc]', 'def']`,
+			`scanf('key: value', '%[^:]: %s')`:                           `['key', 'value']`,
+			`scanf('skip 7', '%*s %d')`:                                  `[7]`,
+			`scanf('100%', '%d%%')`:                                      `[100]`,
+			`scanf('  12   13', '%d%d')`:                                 `[12, 13]`,
+			`scanf('x12', '%d')`:                                         `[]`,
+			`scanf('12 x', '%d %d')`:                                     `[12]`,
+			`scanf('12', '%d') |$r| { $r[0] + 1 }`:                       `13`,
+			`scanf('1,2', '%d,%d') |$r| { $r[1] }`:                       `2`,
+			`scanf('', '%s')`:                                            `[]`,
+		})
+		for _, format := range []string{`%`, `%q`, `%5`, `%[abc`} {
+			expectIssue(t, pdsl.InvalidScanfFormat, func() { evaluate(c, `scanf('x', '`+format+`')`) })
+		}
+	})
+}
+
+func TestVersioncmp(t *testing.T) {
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		// Each version is less than all versions that follow it. Digits with a leading zero are compared as strings.
+		ordered := []string{
+			`0.1`, `1`, `1.0`, `1.0-rc1`, `1.0.0`, `1.0.1`, `1.010`, `1.1`, `1.2`, `1.10`, `2`, `2.0a`, `2.0b`, `10`,
+		}
+		for i, a := range ordered {
+			for j, b := range ordered {
+				expected := `0`
+				switch {
+				case i < j:
+					expected = `-1`
+				case i > j:
+					expected = `1`
+				}
+				source := `versioncmp('` + a + `', '` + b + `')`
+				if actual := evaluate(c, source).String(); actual != expected {
+					t.Errorf(`%s: expected %s, got %s`, source, expected, actual)
+				}
+			}
+		}
+		expectValues(t, c, map[string]string{
+			`versioncmp('1.0a', '1.0A')`:             `0`,
+			`versioncmp('1.0a', '1.0B')`:             `-1`,
+			`versioncmp('1-2', '1.2')`:               `-1`,
+			`versioncmp('1.01', '1.1')`:              `-1`,
+			`versioncmp('1.0', '1')`:                 `1`,
+			`versioncmp('1.0', '1', true)`:           `0`,
+			`versioncmp('1.0.0-rc1', '1-rc1', true)`: `0`,
+			`versioncmp('1.2.0', '1.2', false)`:      `1`,
+		})
+	})
+}
@@ -0,0 +1,139 @@
+package evaluator
+
+import (
+	"math"
+	"time"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+// lhsTimestampArithmetic adds a Timespan, or a number of seconds, to a Timestamp or subtracts it from the
+// Timestamp. The difference between two Timestamps is a Timespan.
+func lhsTimestampArithmetic(expr *parser.ArithmeticExpression, at time.Time, b px.Value) px.Value {
+	op := expr.Operator()
+	switch op {
+	case `+`, `-`:
+		if bt, ok := b.(*types.Timestamp); ok {
+			if op == `-` {
+				// Sub saturates instead of overflowing so the result must be verified
+				d := at.Sub(bt.Time())
+				if !bt.Time().Add(d).Equal(at) {
+					panic(evalError(pdsl.TimespanOverflow, expr, issue.H{`operator`: op}))
+				}
+				return types.WrapTimespan(d)
+			}
+			break
+		}
+		if d, ok := toDuration(expr, b); ok {
+			if op == `-` {
+				if d == math.MinInt64 {
+					panic(evalError(pdsl.TimespanOverflow, expr, issue.H{`operator`: op}))
+				}
+				d = -d
+			}
+			return types.WrapTimestamp(at.Add(d))
+		}
+	default:
+		panic(evalError(pdsl.OperatorNotApplicable, expr, issue.H{`operator`: op, `left`: `Timestamp`}))
+	}
+	panic(evalError(pdsl.OperatorNotApplicableWhen, expr, issue.H{`operator`: op, `left`: `Timestamp`, `right`: b.PType()}))
+}
+
+// lhsTimespanArithmetic performs arithmetic on a Timespan. A Timespan can be added to a Timestamp, added to or
+// subtracted from another Timespan or a number of seconds, multiplied or divided by a number, and divided by
+// another Timespan, which yields a Float.
+func lhsTimespanArithmetic(expr *parser.ArithmeticExpression, ad time.Duration, b px.Value) px.Value {
+	op := expr.Operator()
+	var r int64
+	overflow := false
+	switch op {
+	case `+`, `-`:
+		if bt, ok := b.(*types.Timestamp); ok {
+			if op == `+` {
+				return types.WrapTimestamp(bt.Time().Add(ad))
+			}
+			break
+		}
+		if bd, ok := toDuration(expr, b); ok {
+			if op == `+` {
+				r, overflow = addInt(int64(ad), int64(bd))
+			} else {
+				r, overflow = subInt(int64(ad), int64(bd))
+			}
+			return types.WrapTimespan(checkedDuration(expr, r, overflow))
+		}
+	case `*`:
+		switch b := b.(type) {
+		case px.Integer:
+			r, overflow = mulInt(int64(ad), b.Int())
+			return types.WrapTimespan(checkedDuration(expr, r, overflow))
+		case px.Float:
+			return types.WrapTimespan(floatDuration(expr, float64(ad)*b.Float()))
+		}
+	case `/`:
+		switch b := b.(type) {
+		case types.Timespan:
+			if b == 0 {
+				panic(evalError(pdsl.DivisionByZero, expr.Rhs(), issue.NoArgs))
+			}
+			return types.WrapFloat(float64(ad) / float64(b))
+		case px.Integer:
+			if b.Int() == 0 {
+				panic(evalError(pdsl.DivisionByZero, expr.Rhs(), issue.NoArgs))
+			}
+			return types.WrapTimespan(checkedDuration(expr, int64(ad)/b.Int(), ad == math.MinInt64 && b.Int() == -1))
+		case px.Float:
+			if b.Float() == 0 {
+				panic(evalError(pdsl.DivisionByZero, expr.Rhs(), issue.NoArgs))
+			}
+			return types.WrapTimespan(floatDuration(expr, float64(ad)/b.Float()))
+		}
+	case `%`:
+		if bd, ok := toDuration(expr, b); ok {
+			if bd == 0 {
+				panic(evalError(pdsl.ModuloByZero, expr.Rhs(), issue.NoArgs))
+			}
+			return types.WrapTimespan(ad % bd)
+		}
+	default:
+		panic(evalError(pdsl.OperatorNotApplicable, expr, issue.H{`operator`: op, `left`: `Timespan`}))
+	}
+	panic(evalError(pdsl.OperatorNotApplicableWhen, expr, issue.H{`operator`: op, `left`: `Timespan`, `right`: b.PType()}))
+}
+
+// toDuration returns the duration denoted by the given Timespan, or by the given Integer or Float when it is
+// interpreted as a number of seconds. The returned boolean is false for all other values.
+func toDuration(expr *parser.ArithmeticExpression, v px.Value) (time.Duration, bool) {
+	switch v := v.(type) {
+	case types.Timespan:
+		return v.Duration(), true
+	case px.Integer:
+		r, overflow := mulInt(v.Int(), int64(time.Second))
+		return checkedDuration(expr, r, overflow), true
+	case px.Float:
+		return floatDuration(expr, v.Float()*float64(time.Second)), true
+	}
+	return 0, false
+}
+
+// checkedDuration returns a duration of the given number of nanoseconds unless the computation of that
+// number overflowed
+func checkedDuration(expr *parser.ArithmeticExpression, ns int64, overflow bool) time.Duration {
+	if overflow {
+		panic(evalError(pdsl.TimespanOverflow, expr, issue.H{`operator`: expr.Operator()}))
+	}
+	return time.Duration(ns)
+}
+
+// floatDuration returns a duration of the given number of nanoseconds, truncated towards zero
+func floatDuration(expr *parser.ArithmeticExpression, ns float64) time.Duration {
+	// float64(math.MaxInt64) is 2^63 and hence out of range
+	if math.IsNaN(ns) || ns >= math.MaxInt64 || ns < math.MinInt64 {
+		panic(evalError(pdsl.TimespanOverflow, expr, issue.H{`operator`: expr.Operator()}))
+	}
+	return time.Duration(ns)
+}
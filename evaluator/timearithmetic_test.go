@@ -0,0 +1,108 @@
+package evaluator_test
+
+import (
+	"testing"
+
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+	"github.com/lyraproj/puppet-evaluator/puppet"
+)
+
+// withTimes runs the given function with the variables $t0 and $t1 assigned two Timestamps that are one
+// minute apart, and $s assigned a Timespan of five seconds
+func withTimes(f func(c pdsl.EvaluationContext)) {
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		evaluate(c, `$t0 = Timestamp('2019-01-01T00:00:00Z') $t1 = Timestamp('2019-01-01T00:01:00Z') $s = Timespan(5)`)
+		f(c)
+	})
+}
+
+func TestTimeArithmetic(t *testing.T) {
+	withTimes(func(c pdsl.EvaluationContext) {
+		for source, expected := range map[string]string{
+			// Timestamp ± Timespan and Timestamp ± Numeric
+			`$t0 + Timespan(60) == $t1`: `true`,
+			`Timespan(60) + $t0 == $t1`: `true`,
+			`$t1 - Timespan(60) == $t0`: `true`,
+			`$t0 + 60 == $t1`:           `true`,
+			`$t0 + 60.0 == $t1`:         `true`,
+			`$t1 - 60 == $t0`:           `true`,
+			`$t0 + 0.5 > $t0`:           `true`,
+
+			// Timestamp − Timestamp
+			`$t1 - $t0 == Timespan(60)`:  `true`,
+			`$t0 - $t1 == Timespan(-60)`: `true`,
+			`($t1 - $t0) =~ Timespan`:    `true`,
+
+			// Timespan ± Timespan and Timespan ± Numeric
+			`$s + $s == Timespan(10)`:   `true`,
+			`$s - $s == Timespan(0)`:    `true`,
+			`$s + 1 == Timespan(6)`:     `true`,
+			`$s - 0.5 == Timespan(4.5)`: `true`,
+
+			// Timespan */ Numeric
+			`$s * 2 == Timespan(10)`:          `true`,
+			`2 * $s == Timespan(10)`:          `true`,
+			`$s * 0.5 == Timespan(2.5)`:       `true`,
+			`0.5 * $s == Timespan(2.5)`:       `true`,
+			`$s / 2 == Timespan(2.5)`:         `true`,
+			`$s / 0.5 == Timespan(10)`:        `true`,
+			`Timespan(10) / $s`:               `2`,
+			`(Timespan(10) / $s) =~ Float`:    `true`,
+			`Timespan(7) % $s == Timespan(2)`: `true`,
+			`Timespan(7) % 5 == Timespan(2)`:  `true`,
+
+			// Ordering
+			`$t0 < $t1`:                        `true`,
+			`$t1 <= $t1`:                       `true`,
+			`$t0 > $t1`:                        `false`,
+			`$t1 >= $t0`:                       `true`,
+			`$s < Timespan(6)`:                 `true`,
+			`$s >= Timespan(5)`:                `true`,
+			`$s > 4`:                           `true`,
+			`$s < 4.5`:                         `false`,
+			`$t0 - $t1 < $s`:                   `true`,
+			`$t1 - $t0 > Timespan('00:00:59')`: `true`,
+		} {
+			if actual := evaluate(c, source).String(); actual != expected {
+				t.Errorf(`%s: expected %s, got %s`, source, expected, actual)
+			}
+		}
+	})
+}
+
+func TestTimeArithmeticErrors(t *testing.T) {
+	withTimes(func(c pdsl.EvaluationContext) {
+		expectErrors(t, c, []arithmeticError{
+			// Illegal combinations
+			{`$t0 + $t1`, pdsl.OperatorNotApplicableWhen, 1},
+			{`$t0 * 2`, pdsl.OperatorNotApplicable, 1},
+			{`$t0 / 2`, pdsl.OperatorNotApplicable, 1},
+			{`$t0 % 2`, pdsl.OperatorNotApplicable, 1},
+			{`$t0 + 'x'`, pdsl.OperatorNotApplicableWhen, 1},
+			{`$s - $t0`, pdsl.OperatorNotApplicableWhen, 1},
+			{`$s * $s`, pdsl.OperatorNotApplicableWhen, 1},
+			{`$s / $t0`, pdsl.OperatorNotApplicableWhen, 1},
+			{`$s % $t0`, pdsl.OperatorNotApplicableWhen, 1},
+			{`$s << 1`, pdsl.OperatorNotApplicable, 1},
+			{`2 / $s`, pdsl.OperatorNotApplicableWhen, 1},
+			{`2 - $s`, pdsl.OperatorNotApplicableWhen, 1},
+			{`2.5 + $s`, pdsl.OperatorNotApplicableWhen, 1},
+			{`$t0 < $s`, pdsl.OperatorNotApplicableWhen, 1},
+			{`$s < $t0`, pdsl.OperatorNotApplicableWhen, 1},
+			{`$s < 'x'`, pdsl.OperatorNotApplicableWhen, 1},
+
+			// Division by zero is reported for the right operand
+			{`$s / 0`, pdsl.DivisionByZero, 6},
+			{`$s / 0.0`, pdsl.DivisionByZero, 6},
+			{`$s / Timespan(0)`, pdsl.DivisionByZero, 6},
+			{`$s % 0`, pdsl.ModuloByZero, 6},
+
+			// Overflow
+			{`Timespan(9223372036) * 2`, pdsl.TimespanOverflow, 1},
+			{`Timespan(9223372036) + 1`, pdsl.TimespanOverflow, 1},
+			{`$s * 1e10`, pdsl.TimespanOverflow, 1},
+			{`$s + 1e10`, pdsl.TimespanOverflow, 1},
+			{`Timestamp('2200-01-01T00:00:00Z') - Timestamp('1900-01-01T00:00:00Z')`, pdsl.TimespanOverflow, 1},
+		})
+	})
+}
@@ -0,0 +1,144 @@
+// Package eyaml decrypts the values of data files written by hiera-eyaml. An encrypted value is
+// a block on the form ENC[PKCS7,<base64>] that may appear anywhere in a string.
+package eyaml
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+var encryptedPattern = regexp.MustCompile(`ENC\[(\w+),([A-Za-z0-9+/=\s]*)\]`)
+
+// Keys is the key pair used when decrypting PKCS7 encrypted values
+type Keys struct {
+	privateKey  *rsa.PrivateKey
+	certificate *x509.Certificate
+}
+
+// LoadKeys reads the PEM encoded RSA private key and, unless its path is empty, the PEM encoded
+// certificate that holds the public key. The private key may be in PKCS#1 or PKCS#8 format.
+func LoadKeys(privateKeyPath, publicKeyPath string) *Keys {
+	keys := &Keys{}
+	block := readPEM(privateKeyPath, `private key`)
+	var err error
+	switch block.Type {
+	case `RSA PRIVATE KEY`:
+		keys.privateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case `PRIVATE KEY`:
+		var key interface{}
+		if key, err = x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+			var ok bool
+			if keys.privateKey, ok = key.(*rsa.PrivateKey); !ok {
+				panic(px.Error(InvalidKey, issue.H{`kind`: `private key`, `path`: privateKeyPath, `detail`: `not an RSA key`}))
+			}
+		}
+	default:
+		panic(px.Error(InvalidKey, issue.H{`kind`: `private key`, `path`: privateKeyPath, `detail`: `unexpected PEM block '` + block.Type + `'`}))
+	}
+	if err != nil {
+		panic(px.Error(InvalidKey, issue.H{`kind`: `private key`, `path`: privateKeyPath, `detail`: err.Error()}))
+	}
+
+	if publicKeyPath != `` {
+		block = readPEM(publicKeyPath, `public key`)
+		if block.Type != `CERTIFICATE` {
+			panic(px.Error(InvalidKey, issue.H{`kind`: `public key`, `path`: publicKeyPath, `detail`: `unexpected PEM block '` + block.Type + `'`}))
+		}
+		if keys.certificate, err = x509.ParseCertificate(block.Bytes); err != nil {
+			panic(px.Error(InvalidKey, issue.H{`kind`: `public key`, `path`: publicKeyPath, `detail`: err.Error()}))
+		}
+	}
+	return keys
+}
+
+func readPEM(path, kind string) *pem.Block {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		panic(px.Error(InvalidKey, issue.H{`kind`: kind, `path`: path, `detail`: err.Error()}))
+	}
+	block, _ := pem.Decode(content)
+	if block == nil {
+		panic(px.Error(InvalidKey, issue.H{`kind`: kind, `path`: path, `detail`: `no PEM data found`}))
+	}
+	return block
+}
+
+// Decrypt returns the given value with all encrypted blocks in its strings replaced by their decrypted
+// content. Hashes and arrays are traversed recursively. A string that contained encrypted blocks is
+// wrapped in a Sensitive. The file and its content are used when reporting the location of a block
+// that cannot be decrypted.
+func Decrypt(keys *Keys, file string, content []byte, value px.Value) px.Value {
+	d := &decrypter{keys: keys, file: file, content: content}
+	return d.decrypt(value)
+}
+
+type decrypter struct {
+	keys    *Keys
+	file    string
+	content []byte
+}
+
+func (d *decrypter) decrypt(value px.Value) px.Value {
+	switch value := value.(type) {
+	case px.StringValue:
+		s := value.String()
+		if !strings.Contains(s, `ENC[`) {
+			return value
+		}
+		decrypted := encryptedPattern.ReplaceAllStringFunc(s, func(block string) string {
+			m := encryptedPattern.FindStringSubmatch(block)
+			return d.decryptBlock(m[1], m[2])
+		})
+		if decrypted == s {
+			return value
+		}
+		return types.WrapSensitive(types.WrapString(decrypted))
+	case *types.Array:
+		return value.Map(d.decrypt)
+	case *types.Hash:
+		return value.MapValues(d.decrypt)
+	default:
+		return value
+	}
+}
+
+func (d *decrypter) decryptBlock(method, encoded string) string {
+	encoded = strings.Join(strings.Fields(encoded), ``)
+	if method != `PKCS7` {
+		panic(d.error(encoded, UnsupportedEncryption, issue.H{`method`: method}))
+	}
+	der, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		panic(d.error(encoded, DecryptionFailed, issue.H{`method`: method, `detail`: `invalid base64 encoding`}))
+	}
+	plain, err := decryptPKCS7(der, d.keys.privateKey, d.keys.certificate)
+	if err != nil {
+		panic(d.error(encoded, DecryptionFailed, issue.H{`method`: method, `detail`: err.Error()}))
+	}
+	return string(plain)
+}
+
+// error creates an error located at the line in the data file where the given encoded block starts
+func (d *decrypter) error(encoded string, code issue.Code, args issue.H) issue.Reported {
+	line := 0
+	prefix := encoded
+	if len(prefix) > 32 {
+		prefix = prefix[:32]
+	}
+	if i := bytes.Index(d.content, []byte(`ENC[`+args[`method`].(string)+`,`+prefix)); i >= 0 {
+		line = bytes.Count(d.content[:i], []byte{'\n'}) + 1
+	} else if i = bytes.Index(d.content, []byte(prefix)); i >= 0 {
+		line = bytes.Count(d.content[:i], []byte{'\n'}) + 1
+	}
+	return px.Error2(issue.NewLocation(d.file, line, 0), code, args)
+}
@@ -0,0 +1,271 @@
+package eyaml
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+// testKeys is a private key and a self-signed certificate that are written to a temporary directory
+type testKeys struct {
+	key         *rsa.PrivateKey
+	certificate *x509.Certificate
+	keyPath     string
+	certPath    string
+}
+
+func newTestKeys(t *testing.T, dir, name string, pkcs8 bool) *testKeys {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour)}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tk := &testKeys{key: key, certificate: certificate,
+		keyPath: filepath.Join(dir, name+`_private_key.pem`), certPath: filepath.Join(dir, name+`_public_key.pem`)}
+	keyBlock := &pem.Block{Type: `RSA PRIVATE KEY`, Bytes: x509.MarshalPKCS1PrivateKey(key)}
+	if pkcs8 {
+		bs, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keyBlock = &pem.Block{Type: `PRIVATE KEY`, Bytes: bs}
+	}
+	writeFile(t, tk.keyPath, pem.EncodeToMemory(keyBlock))
+	writeFile(t, tk.certPath, pem.EncodeToMemory(&pem.Block{Type: `CERTIFICATE`, Bytes: der}))
+	return tk
+}
+
+func writeFile(t *testing.T, path string, content []byte) {
+	t.Helper()
+	if err := ioutil.WriteFile(path, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func tempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir(``, `eyaml`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// encrypt returns the given text as an ENC[PKCS7,...] block in the same way as hiera-eyaml, i.e. as PKCS7
+// enveloped data where the content is encrypted using AES-256-CBC
+func (tk *testKeys) encrypt(t *testing.T, text string) string {
+	t.Helper()
+	contentKey := make([]byte, 32)
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(contentKey); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rand.Read(iv); err != nil {
+		t.Fatal(err)
+	}
+	block, err := aes.NewCipher(contentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pad := aes.BlockSize - len(text)%aes.BlockSize
+	content := []byte(text + strings.Repeat(string(rune(pad)), pad))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(content, content)
+
+	encryptedKey, err := rsa.EncryptPKCS1v15(rand.Reader, &tk.key.PublicKey, contentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ivDER, err := asn1.Marshal(iv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ed, err := asn1.Marshal(envelopedData{
+		RecipientInfos: []recipientInfo{{
+			IssuerAndSerialNumber: issuerAndSerialNumber{
+				Issuer:       asn1.RawValue{FullBytes: tk.certificate.RawIssuer},
+				SerialNumber: tk.certificate.SerialNumber},
+			KeyEncryptionAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidRSAEncryption, Parameters: asn1.NullRawValue},
+			EncryptedKey:           encryptedKey}},
+		EncryptedContentInfo: encryptedContentInfo{
+			ContentType:                asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1},
+			ContentEncryptionAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidAES256CBC, Parameters: asn1.RawValue{FullBytes: ivDER}},
+			EncryptedContent:           asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, Bytes: content}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The explicit tag of the content is not applied by asn1.Marshal when the field is a RawValue
+	der, err := asn1.Marshal(contentInfo{ContentType: oidEnvelopedData,
+		Content: asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: ed}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return `ENC[PKCS7,` + base64.StdEncoding.EncodeToString(der) + `]`
+}
+
+// expectIssue calls f and fails unless it panics with an issue.Reported with the given code
+func expectIssue(t *testing.T, code issue.Code, f func()) issue.Reported {
+	t.Helper()
+	var reported issue.Reported
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				var ok bool
+				if reported, ok = r.(issue.Reported); !ok {
+					panic(r)
+				}
+			}
+		}()
+		f()
+	}()
+	if reported == nil {
+		t.Fatalf(`expected %s but nothing was raised`, code)
+	}
+	if reported.Code() != code {
+		t.Fatalf(`expected %s, got %s`, code, reported)
+	}
+	return reported
+}
+
+func expectSensitive(t *testing.T, v px.Value, expected string) {
+	t.Helper()
+	s, ok := v.(*types.Sensitive)
+	if !ok {
+		t.Fatalf(`expected a Sensitive, got %s`, v)
+	}
+	if s.Unwrap().String() != expected {
+		t.Fatalf(`expected Sensitive to contain %q, got %q`, expected, s.Unwrap().String())
+	}
+}
+
+func TestDecrypt(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	tk := newTestKeys(t, dir, `test`, false)
+	keys := LoadKeys(tk.keyPath, tk.certPath)
+	value := types.WrapStringToValueMap(map[string]px.Value{
+		`a`: types.WrapString(tk.encrypt(t, `secret`)),
+		`b`: types.WrapValues([]px.Value{
+			types.WrapString(`plain`),
+			types.WrapString(`user: ` + tk.encrypt(t, `alice`) + `, password: ` + tk.encrypt(t, `s3cr3t`))}),
+		`c`: types.WrapInteger(3)})
+
+	result := Decrypt(keys, `common.eyaml`, nil, value).(*types.Hash)
+	expectSensitive(t, result.Get5(`a`, px.Undef), `secret`)
+	b := result.Get5(`b`, px.Undef).(*types.Array)
+	if s, ok := b.At(0).(px.StringValue); !ok || s.String() != `plain` {
+		t.Errorf(`expected a string without encrypted blocks to be unchanged, got %s`, b.At(0))
+	}
+	expectSensitive(t, b.At(1), `user: alice, password: s3cr3t`)
+	if !result.Get5(`c`, px.Undef).Equals(types.WrapInteger(3), nil) {
+		t.Errorf(`expected 3, got %s`, result.Get5(`c`, px.Undef))
+	}
+}
+
+func TestDecryptMultiLineBlock(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	tk := newTestKeys(t, dir, `test`, true)
+	block := tk.encrypt(t, `secret`)
+
+	// hiera-eyaml folds the base64 content of long blocks into several lines
+	encoded := strings.TrimSuffix(strings.TrimPrefix(block, `ENC[PKCS7,`), `]`)
+	lines := make([]string, 0)
+	for len(encoded) > 60 {
+		lines = append(lines, encoded[:60])
+		encoded = encoded[60:]
+	}
+	lines = append(lines, encoded)
+	folded := "ENC[PKCS7," + strings.Join(lines, "\n    ") + "]"
+	expectSensitive(t, Decrypt(LoadKeys(tk.keyPath, ``), `common.eyaml`, nil, types.WrapString(folded)), `secret`)
+}
+
+func TestDecryptWithOtherKey(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	tk := newTestKeys(t, dir, `test`, false)
+	other := newTestKeys(t, dir, `other`, false)
+	block := tk.encrypt(t, `secret`)
+
+	content := []byte("---\nkey: " + block + "\n")
+	ri := expectIssue(t, DecryptionFailed, func() {
+		Decrypt(LoadKeys(other.keyPath, other.certPath), `common.eyaml`, content, types.WrapString(block))
+	})
+	if ri.Location().Line() != 2 {
+		t.Errorf(`expected the error to be reported on line 2, got %d`, ri.Location().Line())
+	}
+	if strings.Contains(ri.Error(), `secret`) {
+		t.Errorf(`the error must not contain the decrypted content: %s`, ri)
+	}
+
+	// Without a certificate, the private key is tried on all recipients
+	expectIssue(t, DecryptionFailed, func() {
+		Decrypt(LoadKeys(other.keyPath, ``), `common.eyaml`, content, types.WrapString(block))
+	})
+}
+
+func TestDecryptMalformed(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	keys := LoadKeys(newTestKeys(t, dir, `test`, false).keyPath, ``)
+	expectIssue(t, DecryptionFailed, func() {
+		Decrypt(keys, `common.eyaml`, nil, types.WrapString(`ENC[PKCS7,not=base64]`))
+	})
+	expectIssue(t, DecryptionFailed, func() {
+		Decrypt(keys, `common.eyaml`, nil, types.WrapString(`ENC[PKCS7,`+base64.StdEncoding.EncodeToString([]byte(`garbage`))+`]`))
+	})
+	expectIssue(t, UnsupportedEncryption, func() {
+		Decrypt(keys, `common.eyaml`, nil, types.WrapString(`ENC[GPG,aGVsbG8=]`))
+	})
+}
+
+func TestLoadKeysErrors(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	tk := newTestKeys(t, dir, `test`, false)
+	expectIssue(t, InvalidKey, func() { LoadKeys(filepath.Join(dir, `missing.pem`), ``) })
+	expectIssue(t, InvalidKey, func() { LoadKeys(tk.keyPath, filepath.Join(dir, `missing.pem`)) })
+
+	// A certificate is not a private key and vice versa
+	expectIssue(t, InvalidKey, func() { LoadKeys(tk.certPath, ``) })
+	expectIssue(t, InvalidKey, func() { LoadKeys(tk.keyPath, tk.keyPath) })
+
+	notPEM := filepath.Join(dir, `not.pem`)
+	writeFile(t, notPEM, []byte(`not a key`))
+	expectIssue(t, InvalidKey, func() { LoadKeys(notPEM, ``) })
+}
@@ -0,0 +1,17 @@
+package eyaml
+
+import "github.com/lyraproj/issue/issue"
+
+const (
+	DecryptionFailed      = `EYAML_DECRYPTION_FAILED`
+	InvalidKey            = `EYAML_INVALID_KEY`
+	UnsupportedEncryption = `EYAML_UNSUPPORTED_ENCRYPTION`
+)
+
+func init() {
+	issue.Hard(DecryptionFailed, `Unable to decrypt the %{method} encrypted value: %{detail}`)
+
+	issue.Hard(InvalidKey, `Unable to read the %{kind} in '%{path}': %{detail}`)
+
+	issue.Hard(UnsupportedEncryption, `Unsupported encryption method '%{method}'. Only PKCS7 is supported`)
+}
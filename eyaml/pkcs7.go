@@ -0,0 +1,180 @@
+package eyaml
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+)
+
+var (
+	oidEnvelopedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 3}
+	oidRSAEncryption = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidAES128CBC     = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC     = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC     = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	oidDESEDE3CBC    = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}
+)
+
+// The errors never contain any part of the decrypted content
+var (
+	errNotEnvelopedData   = errors.New(`the value is not PKCS7 enveloped data`)
+	errMalformed          = errors.New(`the PKCS7 structure is malformed`)
+	errNoRecipient        = errors.New(`the value was not encrypted for the configured key`)
+	errUnsupportedCipher  = errors.New(`the content encryption algorithm is not supported`)
+	errUnsupportedKeyAlgo = errors.New(`the key encryption algorithm is not supported`)
+	errBadContent         = errors.New(`the encrypted content is corrupt or the key is wrong`)
+)
+
+type (
+	contentInfo struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue `asn1:"explicit,tag:0"`
+	}
+
+	envelopedData struct {
+		Version              int
+		RecipientInfos       []recipientInfo `asn1:"set"`
+		EncryptedContentInfo encryptedContentInfo
+	}
+
+	recipientInfo struct {
+		Version                int
+		IssuerAndSerialNumber  issuerAndSerialNumber
+		KeyEncryptionAlgorithm pkix.AlgorithmIdentifier
+		EncryptedKey           []byte
+	}
+
+	issuerAndSerialNumber struct {
+		Issuer       asn1.RawValue
+		SerialNumber *big.Int
+	}
+
+	encryptedContentInfo struct {
+		ContentType                asn1.ObjectIdentifier
+		ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+		EncryptedContent           asn1.RawValue `asn1:"tag:0,optional"`
+	}
+)
+
+// decryptPKCS7 decrypts the given DER encoded PKCS7 enveloped data using the given private key. The
+// certificate, when not nil, is used to select the recipient that the content was encrypted for.
+func decryptPKCS7(der []byte, key *rsa.PrivateKey, certificate *x509.Certificate) ([]byte, error) {
+	var ci contentInfo
+	if rest, err := asn1.Unmarshal(der, &ci); err != nil || len(rest) > 0 {
+		return nil, errMalformed
+	}
+	if !ci.ContentType.Equal(oidEnvelopedData) {
+		return nil, errNotEnvelopedData
+	}
+	var ed envelopedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &ed); err != nil {
+		return nil, errMalformed
+	}
+
+	eci := ed.EncryptedContentInfo
+	block, keySize, err := contentCipher(eci.ContentEncryptionAlgorithm.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	var iv []byte
+	if _, err = asn1.Unmarshal(eci.ContentEncryptionAlgorithm.Parameters.FullBytes, &iv); err != nil {
+		return nil, errMalformed
+	}
+	content, err := encryptedContent(eci.EncryptedContent)
+	if err != nil {
+		return nil, err
+	}
+
+	found := false
+	for _, ri := range ed.RecipientInfos {
+		if certificate != nil && !ri.matches(certificate) {
+			continue
+		}
+		found = true
+		if !ri.KeyEncryptionAlgorithm.Algorithm.Equal(oidRSAEncryption) {
+			return nil, errUnsupportedKeyAlgo
+		}
+		contentKey, err := rsa.DecryptPKCS1v15(nil, key, ri.EncryptedKey)
+		if err != nil || len(contentKey) != keySize {
+			continue
+		}
+		if plain, err := decryptCBC(block, contentKey, iv, content); err == nil {
+			return plain, nil
+		}
+	}
+	if found {
+		return nil, errBadContent
+	}
+	return nil, errNoRecipient
+}
+
+func (ri *recipientInfo) matches(certificate *x509.Certificate) bool {
+	isn := ri.IssuerAndSerialNumber
+	return isn.SerialNumber != nil && isn.SerialNumber.Cmp(certificate.SerialNumber) == 0 &&
+		bytes.Equal(isn.Issuer.FullBytes, certificate.RawIssuer)
+}
+
+// contentCipher returns the block cipher constructor and key size of the given content encryption algorithm
+func contentCipher(algorithm asn1.ObjectIdentifier) (func([]byte) (cipher.Block, error), int, error) {
+	switch {
+	case algorithm.Equal(oidAES128CBC):
+		return aes.NewCipher, 16, nil
+	case algorithm.Equal(oidAES192CBC):
+		return aes.NewCipher, 24, nil
+	case algorithm.Equal(oidAES256CBC):
+		return aes.NewCipher, 32, nil
+	case algorithm.Equal(oidDESEDE3CBC):
+		return des.NewTripleDESCipher, 24, nil
+	}
+	return nil, 0, errUnsupportedCipher
+}
+
+// encryptedContent returns the content of the given octet string, which is either primitive or constructed
+// from a sequence of primitive octet strings
+func encryptedContent(rv asn1.RawValue) ([]byte, error) {
+	if !rv.IsCompound {
+		return rv.Bytes, nil
+	}
+	var content []byte
+	for rest := rv.Bytes; len(rest) > 0; {
+		var chunk []byte
+		var err error
+		if rest, err = asn1.Unmarshal(rest, &chunk); err != nil {
+			return nil, errMalformed
+		}
+		content = append(content, chunk...)
+	}
+	return content, nil
+}
+
+func decryptCBC(newCipher func([]byte) (cipher.Block, error), key, iv, content []byte) ([]byte, error) {
+	block, err := newCipher(key)
+	if err != nil {
+		return nil, errBadContent
+	}
+	bs := block.BlockSize()
+	if len(iv) != bs || len(content) == 0 || len(content)%bs != 0 {
+		return nil, errBadContent
+	}
+	plain := make([]byte, len(content))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, content)
+
+	// Remove the PKCS#7 padding
+	pad := int(plain[len(plain)-1])
+	if pad == 0 || pad > bs {
+		return nil, errBadContent
+	}
+	for _, b := range plain[len(plain)-pad:] {
+		if int(b) != pad {
+			return nil, errBadContent
+		}
+	}
+	return plain[:len(plain)-pad], nil
+}
@@ -0,0 +1,230 @@
+// Package facts collects the core facts of a Linux host without the use of Facter. The facts are read
+// from /proc, /sys, /etc, and the network interfaces of the host and have the structured layout of
+// Facter 4, so that the result can be assigned to the global scope using EvaluationContext.SetFacts.
+//
+// All files are read relative to the root of a System, which makes it possible to collect facts from
+// a directory that contains fixtures instead of the real files of the host.
+package facts
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+// A Provider returns the value of the fact that it is registered for, or nil when the value cannot be
+// determined. The given facts are the facts that have been collected before the provider is called.
+type Provider func(s *System, facts px.OrderedMap) px.Value
+
+// Interface describes a network interface of a System
+type Interface struct {
+	Name      string
+	MTU       int
+	MAC       string
+	Addresses []*net.IPNet
+}
+
+// System is the host that facts are collected from
+type System struct {
+	// Root is the directory that the paths of all files that are read are relative to
+	Root string
+
+	// UID and GID are the ids of the user and group that collect the facts, or -1 when unknown
+	UID int
+	GID int
+
+	// Machine is the machine hardware name that is used when it cannot be read from proc/sys/kernel/arch
+	Machine string
+
+	// Interfaces returns the network interfaces of the host, or is nil when they are unknown
+	Interfaces func() ([]*Interface, error)
+
+	// Now returns the current time of the host, or is nil when it is unknown
+	Now func() time.Time
+
+	// DiskUsage returns the total, free, and available number of bytes of the filesystem that is mounted
+	// on the given path, or is nil when the usage is unknown
+	DiskUsage func(path string) (total, free, available uint64, ok bool)
+}
+
+// NewSystem returns a System that reads its files relative to the given root. When the root is "/", all
+// other information is obtained from the running process and the host it runs on. Any other root is a
+// directory of fixtures and nothing is then obtained from the host. The fields of the returned System can
+// be assigned to provide that information.
+func NewSystem(root string) *System {
+	if root != `/` {
+		return &System{Root: root, UID: -1, GID: -1}
+	}
+	return &System{Root: root, UID: os.Getuid(), GID: os.Getgid(), Machine: hostMachine(), Interfaces: hostInterfaces,
+		Now: time.Now, DiskUsage: diskUsage}
+}
+
+// ReadFile returns the content of the file at the given path relative to the root of the system
+func (s *System) ReadFile(path string) ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join(s.Root, path))
+}
+
+// ReadString returns the trimmed content of the file at the given path relative to the root of the
+// system, or an empty string if the file cannot be read
+func (s *System) ReadString(path string) string {
+	content, err := s.ReadFile(path)
+	if err != nil {
+		return ``
+	}
+	return string(bytes.TrimSpace(content))
+}
+
+// ReadLines returns the lines of the file at the given path relative to the root of the system, or nil
+// if the file cannot be read
+func (s *System) ReadLines(path string) []string {
+	content, err := s.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
+
+type registration struct {
+	name     string
+	provider Provider
+}
+
+var registry struct {
+	sync.Mutex
+	providers []registration
+}
+
+// Register registers a provider for the fact with the given name. Providers are called in the order
+// that they were registered, starting with the providers of the core facts. A provider that is
+// registered for the name of a registered fact replaces the provider of that fact.
+func Register(name string, provider Provider) {
+	registry.Lock()
+	defer registry.Unlock()
+	for i, r := range registry.providers {
+		if r.name == name {
+			registry.providers[i].provider = provider
+			return
+		}
+	}
+	registry.providers = append(registry.providers, registration{name, provider})
+}
+
+// Collect calls all registered providers and returns the facts of the given system. Facts that cannot
+// be determined are omitted.
+func Collect(s *System) *types.Hash {
+	registry.Lock()
+	providers := make([]registration, len(registry.providers))
+	copy(providers, registry.providers)
+	registry.Unlock()
+
+	facts := &hashBuilder{}
+	for _, r := range providers {
+		facts.add(r.name, r.provider(s, facts.hash()))
+	}
+	return facts.hash()
+}
+
+// hashBuilder builds a hash where the order of the entries is the order in which they were added
+type hashBuilder struct {
+	entries []*types.HashEntry
+}
+
+// add adds the given entry unless the value is nil or an empty string. An entry with the same key as a
+// previously added entry replaces that entry.
+func (b *hashBuilder) add(key string, value px.Value) {
+	if value == nil {
+		return
+	}
+	if s, ok := value.(px.StringValue); ok && s.String() == `` {
+		return
+	}
+	entry := types.WrapHashEntry2(key, value)
+	for i, e := range b.entries {
+		if e.Key().String() == key {
+			b.entries[i] = entry
+			return
+		}
+	}
+	b.entries = append(b.entries, entry)
+}
+
+func (b *hashBuilder) addString(key, value string) {
+	b.add(key, types.WrapString(value))
+}
+
+// addBytes adds an entry with the given key suffixed with "_bytes" for the number of bytes, and an entry
+// with the key itself for a human readable form of that number
+func (b *hashBuilder) addBytes(key string, bytes uint64) {
+	b.add(key, types.WrapString(humanBytes(bytes)))
+	b.add(key+`_bytes`, types.WrapInteger(int64(bytes)))
+}
+
+func (b *hashBuilder) hash() *types.Hash {
+	return types.WrapHash(b.entries)
+}
+
+// value returns the built hash, or nil if the hash is empty
+func (b *hashBuilder) value() px.Value {
+	if len(b.entries) == 0 {
+		return nil
+	}
+	return b.hash()
+}
+
+// humanBytes formats a number of bytes the way Facter does, e.g. "512 bytes" or "1.50 GiB"
+func humanBytes(bytes uint64) string {
+	if bytes < 1024 {
+		return fmt.Sprintf(`%d bytes`, bytes)
+	}
+	v := float64(bytes)
+	unit := 0
+	for v >= 1024 && unit < 6 {
+		v /= 1024
+		unit++
+	}
+	return fmt.Sprintf(`%.2f %s`, v, []string{``, `KiB`, `MiB`, `GiB`, `TiB`, `PiB`, `EiB`}[unit])
+}
+
+// capacity formats the used part of a total as a percentage
+func capacity(used, total uint64) string {
+	if total == 0 {
+		return `0%`
+	}
+	return fmt.Sprintf(`%.2f%%`, float64(used)*100/float64(total))
+}
+
+// keyValues parses lines of the form <key><separator><value> into a map
+func keyValues(lines []string, separator string) map[string]string {
+	m := make(map[string]string, len(lines))
+	for _, line := range lines {
+		if k, v, ok := keyValue(line, separator); ok {
+			m[k] = v
+		}
+	}
+	return m
+}
+
+// keyValue splits a line of the form <key><separator><value>. The key and the value are trimmed and
+// surrounding quotes are removed from the value.
+func keyValue(line, separator string) (string, string, bool) {
+	i := strings.Index(line, separator)
+	if i <= 0 {
+		return ``, ``, false
+	}
+	return strings.TrimSpace(line[:i]), strings.Trim(strings.TrimSpace(line[i+len(separator):]), `"'`), true
+}
@@ -0,0 +1,213 @@
+package facts
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+// fact returns the value at the given dot separated path in the given facts, or nil if there is no such value
+func fact(facts px.OrderedMap, path string) px.Value {
+	var v px.Value = facts
+	for _, key := range strings.Split(path, `.`) {
+		h, ok := v.(px.OrderedMap)
+		if !ok {
+			return nil
+		}
+		if v, ok = h.Get4(key); !ok {
+			return nil
+		}
+	}
+	return v
+}
+
+// expectFacts asserts that the given facts contain the expected values. A nil expected value asserts
+// that the fact is absent.
+func expectFacts(t *testing.T, facts px.OrderedMap, expected map[string]interface{}) {
+	t.Helper()
+	for path, e := range expected {
+		v := fact(facts, path)
+		if e == nil {
+			if v != nil {
+				t.Errorf(`expected %s to be absent, got %s`, path, v)
+			}
+			continue
+		}
+		if ev := px.Wrap(nil, e); v == nil || !ev.Equals(v, nil) {
+			t.Errorf(`expected %s to be %s, got %v`, path, ev, v)
+		}
+	}
+}
+
+func TestUbuntu(t *testing.T) {
+	s := NewSystem(`testdata/ubuntu`)
+	s.UID, s.GID = 1000, 1000
+	expectFacts(t, Collect(s), map[string]interface{}{
+		`kernel`:                          `Linux`,
+		`kernelrelease`:                   `5.15.0-86-generic`,
+		`kernelversion`:                   `5.15.0`,
+		`kernelmajversion`:                `5.15`,
+		`identity.uid`:                    1000,
+		`identity.user`:                   `alice`,
+		`identity.gid`:                    1000,
+		`identity.group`:                  `alice`,
+		`identity.privileged`:             false,
+		`memory.system.total_bytes`:       4143427584,
+		`memory.system.available_bytes`:   2935832576,
+		`memory.system.used`:              `1.12 GiB`,
+		`memory.system.capacity`:          `29.14%`,
+		`memory.swap.total`:               `2.00 GiB`,
+		`memory.swap.used`:                `0 bytes`,
+		`mountpoints./.device`:            `/dev/sda1`,
+		`mountpoints./.filesystem`:        `ext4`,
+		`mountpoints./.options`:           []string{`rw`, `relatime`, `errors=remount-ro`},
+		`mountpoints./mnt/my data.device`: `/dev/sdb1`,
+		`mountpoints./proc`:               nil,
+		`networking.hostname`:             `web01`,
+		`networking.domain`:               `example.com`,
+		`networking.fqdn`:                 `web01.example.com`,
+		`os.name`:                         `Ubuntu`,
+		`os.family`:                       `Debian`,
+		`os.hardware`:                     `x86_64`,
+		`os.architecture`:                 `amd64`,
+		`os.release.full`:                 `22.04`,
+		`os.release.major`:                `22.04`,
+		`os.release.minor`:                nil,
+		`os.distro.codename`:              `jammy`,
+		`os.distro.description`:           `Ubuntu 22.04.3 LTS`,
+		`os.selinux.enabled`:              false,
+		`processors.count`:                2,
+		`processors.physicalcount`:        1,
+		`processors.isa`:                  `x86_64`,
+		`processors.speed`:                `2.40 GHz`,
+		`processors.models`:               []string{`Intel(R) Xeon(R) CPU E5-2676 v3 @ 2.40GHz`, `Intel(R) Xeon(R) CPU E5-2676 v3 @ 2.40GHz`},
+	})
+}
+
+func TestCentOS(t *testing.T) {
+	s := NewSystem(`testdata/centos`)
+	s.UID, s.GID = 0, 0
+	expectFacts(t, Collect(s), map[string]interface{}{
+		`kernelrelease`:             `4.18.0-477.10.1.el8_8.x86_64`,
+		`kernelmajversion`:          `4.18`,
+		`identity.user`:             `root`,
+		`identity.privileged`:       true,
+		`memory.system.available`:   `512.00 MiB`,
+		`memory.system.capacity`:    `50.00%`,
+		`memory.swap`:               nil,
+		`networking.hostname`:       `db01`,
+		`networking.domain`:         `example.org`,
+		`networking.fqdn`:           `db01.example.org`,
+		`os.name`:                   `CentOS`,
+		`os.family`:                 `RedHat`,
+		`os.release.major`:          `8`,
+		`os.selinux.enabled`:        true,
+		`os.selinux.enforced`:       true,
+		`os.selinux.current_mode`:   `enforcing`,
+		`os.selinux.policy_version`: `33`,
+		`processors.count`:          1,
+		`processors.speed`:          `800.00 MHz`,
+		`mountpoints./.filesystem`:  `xfs`,
+		`mountpoints./.available`:   nil,
+		`os.hardware`:               nil,
+		`processors.isa`:            nil,
+		`networking.interfaces`:     nil,
+		`networking.primary`:        nil,
+		`timezone`:                  nil,
+	})
+}
+
+func TestFixtureSystemUsesNothingFromTheHost(t *testing.T) {
+	facts := Collect(NewSystem(`testdata/centos`))
+	for _, path := range []string{`identity`, `os.hardware`, `os.architecture`, `processors.isa`, `networking.interfaces`, `timezone`, `mountpoints./.size`} {
+		if v := fact(facts, path); v != nil {
+			t.Errorf(`expected %s to be absent, got %s`, path, v)
+		}
+	}
+}
+
+func TestInjectedHostInformation(t *testing.T) {
+	_, eth0, _ := net.ParseCIDR(`192.168.1.10/24`)
+	eth0.IP = net.ParseIP(`192.168.1.10`)
+	_, eth0v6, _ := net.ParseCIDR(`fe80::42:acff:fe11:2/64`)
+	eth0v6.IP = net.ParseIP(`fe80::42:acff:fe11:2`)
+	_, lo, _ := net.ParseCIDR(`127.0.0.1/8`)
+	lo.IP = net.ParseIP(`127.0.0.1`)
+
+	s := NewSystem(`testdata/ubuntu`)
+	s.Interfaces = func() ([]*Interface, error) {
+		return []*Interface{
+			{Name: `lo`, MTU: 65536, Addresses: []*net.IPNet{lo}},
+			{Name: `eth0`, MTU: 1500, MAC: `02:42:ac:11:00:02`, Addresses: []*net.IPNet{eth0, eth0v6}}}, nil
+	}
+	s.Now = func() time.Time { return time.Date(2024, 1, 1, 0, 0, 0, 0, time.FixedZone(`CET`, 3600)) }
+	s.DiskUsage = func(path string) (uint64, uint64, uint64, bool) {
+		if path == `/` {
+			return 1000 * 4096, 600 * 4096, 500 * 4096, true
+		}
+		return 0, 0, 0, false
+	}
+
+	expectFacts(t, Collect(s), map[string]interface{}{
+		`networking.primary`:                  `eth0`,
+		`networking.ip`:                       `192.168.1.10`,
+		`networking.netmask`:                  `255.255.255.0`,
+		`networking.network`:                  `192.168.1.0`,
+		`networking.ip6`:                      `fe80::42:acff:fe11:2`,
+		`networking.mac`:                      `02:42:ac:11:00:02`,
+		`networking.mtu`:                      1500,
+		`networking.interfaces.lo.ip`:         `127.0.0.1`,
+		`networking.interfaces.lo.mac`:        nil,
+		`networking.interfaces.eth0.network6`: `fe80::`,
+		`timezone`:                            `CET`,
+		`mountpoints./.size_bytes`:            4096000,
+		`mountpoints./.used`:                  `1.56 MiB`,
+		`mountpoints./.available`:             `1.95 MiB`,
+		`mountpoints./.capacity`:              `44.44%`,
+		`mountpoints./mnt/my data.size`:       nil,
+	})
+}
+
+func TestMachine(t *testing.T) {
+	s := NewSystem(`testdata/centos`)
+	s.Machine = `aarch64`
+	expectFacts(t, Collect(s), map[string]interface{}{
+		`os.hardware`:     `aarch64`,
+		`os.architecture`: `aarch64`,
+		`processors.isa`:  `aarch64`,
+	})
+
+	// The kernel arch file takes precedence
+	s = NewSystem(`testdata/ubuntu`)
+	s.Machine = `aarch64`
+	expectFacts(t, Collect(s), map[string]interface{}{`os.hardware`: `x86_64`})
+}
+
+func TestRegister(t *testing.T) {
+	Register(`test_fact`, func(s *System, facts px.OrderedMap) px.Value {
+		return types.WrapString(fact(facts, `os.name`).String() + `-` + fact(facts, `kernel`).String())
+	})
+	defer func() {
+		registry.Lock()
+		registry.providers = registry.providers[:len(registry.providers)-1]
+		registry.Unlock()
+	}()
+	expectFacts(t, Collect(NewSystem(`testdata/ubuntu`)), map[string]interface{}{`test_fact`: `Ubuntu-Linux`})
+}
+
+func TestHumanBytes(t *testing.T) {
+	for bytes, expected := range map[uint64]string{
+		0:                  `0 bytes`,
+		1023:               `1023 bytes`,
+		1024:               `1.00 KiB`,
+		1536 * 1024 * 1024: `1.50 GiB`,
+	} {
+		if actual := humanBytes(bytes); actual != expected {
+			t.Errorf(`expected %d bytes to be %s, got %s`, bytes, expected, actual)
+		}
+	}
+}
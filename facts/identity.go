@@ -0,0 +1,38 @@
+package facts
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+func init() {
+	Register(`identity`, func(s *System, _ px.OrderedMap) px.Value {
+		id := &hashBuilder{}
+		if s.GID >= 0 {
+			id.add(`gid`, types.WrapInteger(int64(s.GID)))
+			id.addString(`group`, nameOfID(s, `etc/group`, s.GID))
+		}
+		if s.UID >= 0 {
+			id.add(`privileged`, types.WrapBoolean(s.UID == 0))
+			id.add(`uid`, types.WrapInteger(int64(s.UID)))
+			id.addString(`user`, nameOfID(s, `etc/passwd`, s.UID))
+		}
+		return id.value()
+	})
+}
+
+// nameOfID returns the name of the entry with the given id in a file with the format of /etc/passwd or
+// /etc/group, or an empty string if no such entry exists
+func nameOfID(s *System, path string, id int) string {
+	ids := strconv.Itoa(id)
+	for _, line := range s.ReadLines(path) {
+		fields := strings.Split(line, `:`)
+		if len(fields) > 2 && fields[2] == ids {
+			return fields[0]
+		}
+	}
+	return ``
+}
@@ -0,0 +1,46 @@
+package facts
+
+import (
+	"strings"
+
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+func init() {
+	Register(`kernel`, func(s *System, _ px.OrderedMap) px.Value {
+		if kernel := s.ReadString(`proc/sys/kernel/ostype`); kernel != `` {
+			return types.WrapString(kernel)
+		}
+		return types.WrapString(`Linux`)
+	})
+
+	Register(`kernelrelease`, func(s *System, _ px.OrderedMap) px.Value {
+		return stringOrNil(s.ReadString(`proc/sys/kernel/osrelease`))
+	})
+
+	Register(`kernelversion`, func(s *System, _ px.OrderedMap) px.Value {
+		return stringOrNil(kernelVersion(s))
+	})
+
+	Register(`kernelmajversion`, func(s *System, _ px.OrderedMap) px.Value {
+		parts := strings.SplitN(kernelVersion(s), `.`, 3)
+		if len(parts) < 2 {
+			return stringOrNil(parts[0])
+		}
+		return types.WrapString(parts[0] + `.` + parts[1])
+	})
+}
+
+// kernelVersion returns the part of the kernel release that precedes the first '-', e.g. 5.15.0 for
+// the release 5.15.0-86-generic
+func kernelVersion(s *System) string {
+	return strings.SplitN(s.ReadString(`proc/sys/kernel/osrelease`), `-`, 2)[0]
+}
+
+func stringOrNil(s string) px.Value {
+	if s == `` {
+		return nil
+	}
+	return types.WrapString(s)
+}
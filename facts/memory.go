@@ -0,0 +1,55 @@
+package facts
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/lyraproj/pcore/px"
+)
+
+func init() {
+	Register(`memory`, func(s *System, _ px.OrderedMap) px.Value {
+		info := keyValues(s.ReadLines(`proc/meminfo`), `:`)
+		if len(info) == 0 {
+			return nil
+		}
+		kib := func(key string) (uint64, bool) {
+			v, ok := info[key]
+			if !ok {
+				return 0, false
+			}
+			n, err := strconv.ParseUint(strings.TrimSuffix(v, ` kB`), 10, 64)
+			return n * 1024, err == nil
+		}
+
+		total, _ := kib(`MemTotal`)
+		available, ok := kib(`MemAvailable`)
+		if !ok {
+			// Kernels older than 3.14 don't report the available memory
+			free, _ := kib(`MemFree`)
+			buffers, _ := kib(`Buffers`)
+			cached, _ := kib(`Cached`)
+			available = free + buffers + cached
+		}
+		m := &hashBuilder{}
+		m.add(`system`, memoryUsage(total, available))
+		swapTotal, _ := kib(`SwapTotal`)
+		swapFree, _ := kib(`SwapFree`)
+		if swapTotal > 0 {
+			m.add(`swap`, memoryUsage(swapTotal, swapFree))
+		}
+		return m.value()
+	})
+}
+
+func memoryUsage(total, available uint64) px.Value {
+	if available > total {
+		available = total
+	}
+	u := &hashBuilder{}
+	u.addBytes(`available`, available)
+	u.addString(`capacity`, capacity(total-available, total))
+	u.addBytes(`total`, total)
+	u.addBytes(`used`, total-available)
+	return u.value()
+}
@@ -0,0 +1,86 @@
+package facts
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+// pseudoFilesystems are the types of the filesystems that are not included in the mountpoints fact
+var pseudoFilesystems = map[string]bool{
+	`autofs`:      true,
+	`binfmt_misc`: true,
+	`bpf`:         true,
+	`cgroup`:      true,
+	`cgroup2`:     true,
+	`configfs`:    true,
+	`debugfs`:     true,
+	`devpts`:      true,
+	`efivarfs`:    true,
+	`fusectl`:     true,
+	`hugetlbfs`:   true,
+	`mqueue`:      true,
+	`nsfs`:        true,
+	`proc`:        true,
+	`pstore`:      true,
+	`rpc_pipefs`:  true,
+	`securityfs`:  true,
+	`selinuxfs`:   true,
+	`sysfs`:       true,
+	`tracefs`:     true,
+}
+
+func init() {
+	Register(`mountpoints`, func(s *System, _ px.OrderedMap) px.Value {
+		mps := &hashBuilder{}
+		for _, line := range s.ReadLines(`proc/mounts`) {
+			fields := strings.Fields(line)
+			if len(fields) < 4 || pseudoFilesystems[fields[2]] {
+				continue
+			}
+			path := unescapeMountField(fields[1])
+			options := strings.Split(fields[3], `,`)
+			ov := make([]px.Value, len(options))
+			for i, o := range options {
+				ov[i] = types.WrapString(o)
+			}
+			mp := &hashBuilder{}
+			mp.addString(`device`, unescapeMountField(fields[0]))
+			mp.addString(`filesystem`, fields[2])
+			mp.add(`options`, types.WrapValues(ov))
+			if s.DiskUsage != nil {
+				if total, free, available, ok := s.DiskUsage(path); ok {
+					used := total - free
+					mp.addBytes(`available`, available)
+					mp.addString(`capacity`, capacity(used, used+available))
+					mp.addBytes(`size`, total)
+					mp.addBytes(`used`, used)
+				}
+			}
+			mps.add(path, mp.value())
+		}
+		return mps.value()
+	})
+}
+
+// unescapeMountField replaces the octal escapes that /proc/mounts uses for spaces, tabs, newlines,
+// and backslashes with the characters they represent
+func unescapeMountField(field string) string {
+	if !strings.Contains(field, `\`) {
+		return field
+	}
+	b := strings.Builder{}
+	for i := 0; i < len(field); i++ {
+		if field[i] == '\\' && i+3 < len(field) {
+			if c, err := strconv.ParseUint(field[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(field[i])
+	}
+	return b.String()
+}
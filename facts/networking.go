@@ -0,0 +1,159 @@
+package facts
+
+import (
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+func init() {
+	Register(`networking`, func(s *System, _ px.OrderedMap) px.Value {
+		hostname := s.ReadString(`proc/sys/kernel/hostname`)
+		domain := ``
+		if i := strings.IndexByte(hostname, '.'); i >= 0 {
+			hostname, domain = hostname[:i], hostname[i+1:]
+		} else {
+			domain = resolvDomain(s)
+		}
+		fqdn := hostname
+		if domain != `` {
+			fqdn += `.` + domain
+		}
+
+		n := &hashBuilder{}
+		n.addString(`domain`, domain)
+		n.addString(`fqdn`, fqdn)
+		n.addString(`hostname`, hostname)
+
+		var ifcs []*Interface
+		if s.Interfaces != nil {
+			ifcs, _ = s.Interfaces()
+		}
+		sort.Slice(ifcs, func(i, j int) bool { return ifcs[i].Name < ifcs[j].Name })
+		interfaces := &hashBuilder{}
+		var primary px.OrderedMap
+		primaryName := defaultRouteInterface(s, ifcs)
+		for _, ifc := range ifcs {
+			iv := interfaceFacts(ifc)
+			interfaces.add(ifc.Name, iv)
+			if ifc.Name == primaryName {
+				primary = iv.(px.OrderedMap)
+			}
+		}
+		n.add(`interfaces`, interfaces.value())
+		if primary != nil {
+			// The addresses of the primary interface are also the addresses of the host
+			for _, key := range []string{`ip`, `ip6`, `mac`, `mtu`, `netmask`, `netmask6`, `network`, `network6`} {
+				n.add(key, primary.Get5(key, nil))
+			}
+			n.addString(`primary`, primaryName)
+		}
+		return n.value()
+	})
+}
+
+// resolvDomain returns the domain or the first search domain of /etc/resolv.conf
+func resolvDomain(s *System) string {
+	search := ``
+	for _, line := range s.ReadLines(`etc/resolv.conf`) {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case `domain`:
+			return fields[1]
+		case `search`:
+			if search == `` {
+				search = fields[1]
+			}
+		}
+	}
+	return search
+}
+
+// defaultRouteInterface returns the name of the interface of the default route in /proc/net/route. If
+// there is no default route, the first interface that isn't a loopback interface and that has an IPv4
+// address is returned.
+func defaultRouteInterface(s *System, ifcs []*Interface) string {
+	for _, line := range s.ReadLines(`proc/net/route`) {
+		fields := strings.Fields(line)
+		if len(fields) > 1 && fields[1] == `00000000` {
+			return fields[0]
+		}
+	}
+	for _, ifc := range ifcs {
+		for _, a := range ifc.Addresses {
+			if a.IP.To4() != nil && !a.IP.IsLoopback() {
+				return ifc.Name
+			}
+		}
+	}
+	return ``
+}
+
+func interfaceFacts(ifc *Interface) px.Value {
+	var bindings, bindings6 []px.Value
+	f := &hashBuilder{}
+	for _, a := range ifc.Addresses {
+		b := &hashBuilder{}
+		b.addString(`address`, a.IP.String())
+		b.addString(`netmask`, net.IP(a.Mask).String())
+		b.addString(`network`, a.IP.Mask(a.Mask).String())
+		if a.IP.To4() != nil {
+			bindings = append(bindings, b.value())
+		} else {
+			bindings6 = append(bindings6, b.value())
+		}
+	}
+	if len(bindings) > 0 {
+		f.add(`bindings`, types.WrapValues(bindings))
+	}
+	if len(bindings6) > 0 {
+		f.add(`bindings6`, types.WrapValues(bindings6))
+	}
+	// The first binding of each kind provides the addresses of the interface
+	for _, bs := range []struct {
+		bindings []px.Value
+		suffix   string
+	}{{bindings, ``}, {bindings6, `6`}} {
+		if len(bs.bindings) > 0 {
+			b := bs.bindings[0].(px.OrderedMap)
+			f.add(`ip`+bs.suffix, b.Get5(`address`, nil))
+			f.add(`netmask`+bs.suffix, b.Get5(`netmask`, nil))
+			f.add(`network`+bs.suffix, b.Get5(`network`, nil))
+		}
+	}
+	f.addString(`mac`, ifc.MAC)
+	if ifc.MTU > 0 {
+		f.add(`mtu`, types.WrapInteger(int64(ifc.MTU)))
+	}
+	if v := f.value(); v != nil {
+		return v
+	}
+	return px.EmptyMap
+}
+
+// hostInterfaces returns the network interfaces of the host that the process runs on
+func hostInterfaces() ([]*Interface, error) {
+	nis, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	ifcs := make([]*Interface, len(nis))
+	for i, ni := range nis {
+		ifc := &Interface{Name: ni.Name, MTU: ni.MTU, MAC: ni.HardwareAddr.String()}
+		if addrs, err := ni.Addrs(); err == nil {
+			for _, a := range addrs {
+				if ipn, ok := a.(*net.IPNet); ok {
+					ifc.Addresses = append(ifc.Addresses, ipn)
+				}
+			}
+		}
+		ifcs[i] = ifc
+	}
+	return ifcs, nil
+}
@@ -0,0 +1,170 @@
+package facts
+
+import (
+	"runtime"
+	"strings"
+
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+// osNames are the names that Facter uses for the ID of /etc/os-release
+var osNames = map[string]string{
+	`almalinux`: `AlmaLinux`,
+	`alpine`:    `Alpine`,
+	`amzn`:      `Amazon`,
+	`arch`:      `Archlinux`,
+	`centos`:    `CentOS`,
+	`debian`:    `Debian`,
+	`fedora`:    `Fedora`,
+	`gentoo`:    `Gentoo`,
+	`linuxmint`: `LinuxMint`,
+	`ol`:        `OracleLinux`,
+	`opensuse`:  `OpenSuSE`,
+	`rhel`:      `RedHat`,
+	`rocky`:     `Rocky`,
+	`sles`:      `SLES`,
+	`ubuntu`:    `Ubuntu`,
+}
+
+// osFamilies are the families of the operating systems that Facter knows about
+var osFamilies = map[string]string{
+	`almalinux`: `RedHat`,
+	`amzn`:      `RedHat`,
+	`centos`:    `RedHat`,
+	`fedora`:    `RedHat`,
+	`ol`:        `RedHat`,
+	`rhel`:      `RedHat`,
+	`rocky`:     `RedHat`,
+	`debian`:    `Debian`,
+	`linuxmint`: `Debian`,
+	`ubuntu`:    `Debian`,
+	`opensuse`:  `Suse`,
+	`sles`:      `Suse`,
+	`arch`:      `Archlinux`,
+	`gentoo`:    `Gentoo`,
+	`alpine`:    `Alpine`,
+}
+
+// goArchitectures maps the Go architectures to the machine names of the Linux kernel
+var goArchitectures = map[string]string{
+	`386`:     `i686`,
+	`amd64`:   `x86_64`,
+	`arm`:     `armv7l`,
+	`arm64`:   `aarch64`,
+	`ppc64le`: `ppc64le`,
+	`s390x`:   `s390x`,
+}
+
+func init() {
+	Register(`os`, func(s *System, _ px.OrderedMap) px.Value {
+		rel := keyValues(s.ReadLines(`etc/os-release`), `=`)
+		if len(rel) == 0 {
+			rel = keyValues(s.ReadLines(`usr/lib/os-release`), `=`)
+		}
+		id := rel[`ID`]
+		if strings.HasPrefix(id, `opensuse`) {
+			id = `opensuse`
+		}
+		name, ok := osNames[id]
+		if !ok {
+			if name = rel[`NAME`]; name == `` {
+				name = `Linux`
+			}
+		}
+		family, ok := osFamilies[id]
+		if !ok {
+			// Use the first known system that this system is like
+			for _, like := range strings.Fields(rel[`ID_LIKE`]) {
+				if family, ok = osFamilies[like]; ok {
+					break
+				}
+			}
+			if !ok {
+				family = name
+			}
+		}
+
+		hardware := machine(s)
+		architecture := hardware
+		if family == `Debian` && hardware == `x86_64` {
+			architecture = `amd64`
+		}
+
+		os := &hashBuilder{}
+		os.addString(`architecture`, architecture)
+		os.add(`distro`, distro(rel, id))
+		os.addString(`family`, family)
+		os.addString(`hardware`, hardware)
+		os.addString(`name`, name)
+		os.add(`release`, release(rel[`VERSION_ID`], id == `ubuntu`))
+		os.add(`selinux`, selinux(s))
+		return os.value()
+	})
+}
+
+// machine returns the machine hardware name of the system
+func machine(s *System) string {
+	if arch := s.ReadString(`proc/sys/kernel/arch`); arch != `` {
+		return arch
+	}
+	return s.Machine
+}
+
+// hostMachine returns the machine hardware name of the host that the process runs on, based on the
+// architecture that the process was compiled for
+func hostMachine() string {
+	if arch, ok := goArchitectures[runtime.GOARCH]; ok {
+		return arch
+	}
+	return runtime.GOARCH
+}
+
+func distro(rel map[string]string, id string) px.Value {
+	d := &hashBuilder{}
+	d.addString(`codename`, rel[`VERSION_CODENAME`])
+	d.addString(`description`, rel[`PRETTY_NAME`])
+	if name, ok := osNames[id]; ok {
+		d.addString(`id`, name)
+	} else {
+		d.addString(`id`, rel[`NAME`])
+	}
+	d.add(`release`, release(rel[`VERSION_ID`], id == `ubuntu`))
+	return d.value()
+}
+
+// release returns the full, major, and minor parts of the given version. The major release of Ubuntu
+// consists of the year and the month, e.g. 22.04.
+func release(version string, ubuntu bool) px.Value {
+	if version == `` {
+		return nil
+	}
+	r := &hashBuilder{}
+	r.addString(`full`, version)
+	parts := strings.Split(version, `.`)
+	if ubuntu && len(parts) > 1 {
+		parts = append([]string{parts[0] + `.` + parts[1]}, parts[2:]...)
+	}
+	r.addString(`major`, parts[0])
+	if len(parts) > 1 {
+		r.addString(`minor`, parts[1])
+	}
+	return r.value()
+}
+
+func selinux(s *System) px.Value {
+	enforce := s.ReadString(`sys/fs/selinux/enforce`)
+	if enforce == `` {
+		return types.WrapHash([]*types.HashEntry{types.WrapHashEntry2(`enabled`, types.BooleanFalse)})
+	}
+	mode := `permissive`
+	if enforce == `1` {
+		mode = `enforcing`
+	}
+	sl := &hashBuilder{}
+	sl.add(`enabled`, types.BooleanTrue)
+	sl.add(`enforced`, types.WrapBoolean(enforce == `1`))
+	sl.addString(`current_mode`, mode)
+	sl.addString(`policy_version`, s.ReadString(`sys/fs/selinux/policyvers`))
+	return sl.value()
+}
@@ -0,0 +1,59 @@
+package facts
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+func init() {
+	Register(`processors`, func(s *System, _ px.OrderedMap) px.Value {
+		lines := s.ReadLines(`proc/cpuinfo`)
+		if lines == nil {
+			return nil
+		}
+		count := 0
+		models := make([]px.Value, 0)
+		physical := make(map[string]bool)
+		mhz := 0.0
+		for _, line := range lines {
+			k, v, _ := keyValue(line, `:`)
+			switch k {
+			case `processor`:
+				count++
+			case `model name`, `cpu model`:
+				models = append(models, types.WrapString(v))
+			case `physical id`:
+				physical[v] = true
+			case `cpu MHz`:
+				if mhz == 0 {
+					mhz, _ = strconv.ParseFloat(v, 64)
+				}
+			}
+		}
+		physicalCount := len(physical)
+		if physicalCount == 0 && count > 0 {
+			physicalCount = 1
+		}
+
+		p := &hashBuilder{}
+		p.add(`count`, types.WrapInteger(int64(count)))
+		p.addString(`isa`, machine(s))
+		p.add(`models`, types.WrapValues(models))
+		p.add(`physicalcount`, types.WrapInteger(int64(physicalCount)))
+		if mhz > 0 {
+			p.addString(`speed`, frequency(mhz))
+		}
+		return p.value()
+	})
+}
+
+// frequency formats a frequency given in MHz the way Facter does, e.g. "2.40 GHz"
+func frequency(mhz float64) string {
+	if mhz >= 1000 {
+		return fmt.Sprintf(`%.2f GHz`, mhz/1000)
+	}
+	return fmt.Sprintf(`%.2f MHz`, mhz)
+}
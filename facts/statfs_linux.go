@@ -0,0 +1,15 @@
+//go:build linux
+
+package facts
+
+import "syscall"
+
+// diskUsage returns the total, free, and available number of bytes of the filesystem at the given path
+func diskUsage(path string) (total, free, available uint64, ok bool) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, 0, 0, false
+	}
+	bs := uint64(st.Bsize)
+	return st.Blocks * bs, st.Bfree * bs, st.Bavail * bs, true
+}
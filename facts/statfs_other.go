@@ -0,0 +1,8 @@
+//go:build !linux
+
+package facts
+
+// diskUsage is not supported on this platform
+func diskUsage(path string) (total, free, available uint64, ok bool) {
+	return 0, 0, 0, false
+}
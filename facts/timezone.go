@@ -0,0 +1,15 @@
+package facts
+
+import (
+	"github.com/lyraproj/pcore/px"
+)
+
+func init() {
+	Register(`timezone`, func(s *System, _ px.OrderedMap) px.Value {
+		if s.Now == nil {
+			return nil
+		}
+		zone, _ := s.Now().Zone()
+		return stringOrNil(zone)
+	})
+}
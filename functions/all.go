@@ -4,22 +4,23 @@ import (
 	"github.com/lyraproj/pcore/px"
 	"github.com/lyraproj/pcore/types"
 	"github.com/lyraproj/puppet-evaluator/evaluator"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
 )
 
-func allIterator(c px.Context, arg px.Indexed, block px.Lambda) px.Value {
-	return types.WrapBoolean(evaluator.WrapIterable(arg).All(func(v px.Value) bool { return px.IsTruthy(block.Call(c, nil, v)) }))
+func allIterator(c px.Context, iter pdsl.Iterator, block px.Lambda) px.Value {
+	return types.WrapBoolean(iter.All(func(v px.Value) bool { return px.IsTruthy(block.Call(c, nil, v)) }))
 }
 
-func allIndexIterator(c px.Context, iter px.Indexed, block px.Lambda) px.Value {
+func allIndexIterator(c px.Context, iter pdsl.Iterator, block px.Lambda) px.Value {
 	index := int64(-1)
-	return types.WrapBoolean(evaluator.WrapIterable(iter).All(func(v px.Value) bool {
+	return types.WrapBoolean(iter.All(func(v px.Value) bool {
 		index++
 		return px.IsTruthy(block.Call(c, nil, types.WrapInteger(index), v))
 	}))
 }
 
-func allHashIterator(c px.Context, iter px.Indexed, block px.Lambda) px.Value {
-	return types.WrapBoolean(evaluator.WrapIterable(iter).All(func(v px.Value) bool {
+func allHashIterator(c px.Context, iter pdsl.Iterator, block px.Lambda) px.Value {
+	return types.WrapBoolean(iter.All(func(v px.Value) bool {
 		vi := v.(px.List)
 		return px.IsTruthy(block.Call(c, nil, vi.At(0), vi.At(1)))
 	}))
@@ -31,7 +32,7 @@ func init() {
 			d.Param(`Hash`)
 			d.Block(`Callable[1,1]`)
 			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
-				return allIterator(c, args[0].(*types.Hash), block)
+				return allIterator(c, evaluator.Iterate(args[0]), block)
 			})
 		},
 
@@ -39,7 +40,7 @@ func init() {
 			d.Param(`Hash`)
 			d.Block(`Callable[2,2]`)
 			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
-				return allHashIterator(c, args[0].(*types.Hash), block)
+				return allHashIterator(c, evaluator.Iterate(args[0]), block)
 			})
 		},
 
@@ -47,7 +48,7 @@ func init() {
 			d.Param(`Iterable`)
 			d.Block(`Callable[1,1]`)
 			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
-				return allIterator(c, args[0].(px.Indexed), block)
+				return allIterator(c, evaluator.Iterate(args[0]), block)
 			})
 		},
 
@@ -57,9 +58,25 @@ func init() {
 			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
 				iter := args[0].(px.Indexed)
 				if iter.IsHashStyle() {
-					return allHashIterator(c, iter, block)
+					return allHashIterator(c, evaluator.WrapIterable(iter), block)
 				}
-				return allIndexIterator(c, iter, block)
+				return allIndexIterator(c, evaluator.WrapIterable(iter), block)
+			})
+		},
+
+		func(d px.Dispatch) {
+			d.Param(`Iterator`)
+			d.Block(`Callable[1,1]`)
+			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
+				return allIterator(c, evaluator.Iterate(args[0]), block)
+			})
+		},
+
+		func(d px.Dispatch) {
+			d.Param(`Iterator`)
+			d.Block(`Callable[2,2]`)
+			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
+				return allIndexIterator(c, evaluator.Iterate(args[0]), block)
 			})
 		},
 	)
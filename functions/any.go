@@ -4,22 +4,23 @@ import (
 	"github.com/lyraproj/pcore/px"
 	"github.com/lyraproj/pcore/types"
 	"github.com/lyraproj/puppet-evaluator/evaluator"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
 )
 
-func anyIterator(c px.Context, arg px.Indexed, block px.Lambda) px.Value {
-	return types.WrapBoolean(evaluator.WrapIterable(arg).Any(func(v px.Value) bool { return px.IsTruthy(block.Call(c, nil, v)) }))
+func anyIterator(c px.Context, iter pdsl.Iterator, block px.Lambda) px.Value {
+	return types.WrapBoolean(iter.Any(func(v px.Value) bool { return px.IsTruthy(block.Call(c, nil, v)) }))
 }
 
-func anyIndexIterator(c px.Context, iter px.Indexed, block px.Lambda) px.Value {
+func anyIndexIterator(c px.Context, iter pdsl.Iterator, block px.Lambda) px.Value {
 	index := int64(-1)
-	return types.WrapBoolean(evaluator.WrapIterable(iter).Any(func(v px.Value) bool {
+	return types.WrapBoolean(iter.Any(func(v px.Value) bool {
 		index++
 		return px.IsTruthy(block.Call(c, nil, types.WrapInteger(index), v))
 	}))
 }
 
-func anyHashIterator(c px.Context, iter px.Indexed, block px.Lambda) px.Value {
-	return types.WrapBoolean(evaluator.WrapIterable(iter).Any(func(v px.Value) bool {
+func anyHashIterator(c px.Context, iter pdsl.Iterator, block px.Lambda) px.Value {
+	return types.WrapBoolean(iter.Any(func(v px.Value) bool {
 		vi := v.(px.List)
 		return px.IsTruthy(block.Call(c, nil, vi.At(0), vi.At(1)))
 	}))
@@ -31,7 +32,7 @@ func init() {
 			d.Param(`Hash`)
 			d.Block(`Callable[1,1]`)
 			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
-				return anyIterator(c, args[0].(*types.Hash), block)
+				return anyIterator(c, evaluator.Iterate(args[0]), block)
 			})
 		},
 
@@ -39,7 +40,7 @@ func init() {
 			d.Param(`Hash`)
 			d.Block(`Callable[2,2]`)
 			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
-				return anyHashIterator(c, args[0].(*types.Hash), block)
+				return anyHashIterator(c, evaluator.Iterate(args[0]), block)
 			})
 		},
 
@@ -47,7 +48,7 @@ func init() {
 			d.Param(`Iterable`)
 			d.Block(`Callable[1,1]`)
 			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
-				return anyIterator(c, args[0].(px.Indexed), block)
+				return anyIterator(c, evaluator.Iterate(args[0]), block)
 			})
 		},
 
@@ -57,9 +58,25 @@ func init() {
 			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
 				iter := args[0].(px.Indexed)
 				if iter.IsHashStyle() {
-					return anyHashIterator(c, iter, block)
+					return anyHashIterator(c, evaluator.WrapIterable(iter), block)
 				}
-				return anyIndexIterator(c, iter, block)
+				return anyIndexIterator(c, evaluator.WrapIterable(iter), block)
+			})
+		},
+
+		func(d px.Dispatch) {
+			d.Param(`Iterator`)
+			d.Block(`Callable[1,1]`)
+			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
+				return anyIterator(c, evaluator.Iterate(args[0]), block)
+			})
+		},
+
+		func(d px.Dispatch) {
+			d.Param(`Iterator`)
+			d.Block(`Callable[2,2]`)
+			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
+				return anyIndexIterator(c, evaluator.Iterate(args[0]), block)
 			})
 		},
 	)
@@ -2,7 +2,6 @@ package functions
 
 import (
 	"github.com/lyraproj/pcore/px"
-	"github.com/lyraproj/pcore/types"
 )
 
 func init() {
@@ -10,7 +9,7 @@ func init() {
 		func(d px.Dispatch) {
 			d.Param(`String`)
 			d.Function(func(c px.Context, args []px.Value) px.Value {
-				return types.BinaryFromFile(args[0].String())
+				return readFile(c, fileNames(args))
 			})
 		})
 }
@@ -0,0 +1,21 @@
+package functions
+
+import (
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+)
+
+func init() {
+	px.NewGoFunction(`contain`,
+		func(d px.Dispatch) {
+			d.RepeatedParam(`Variant[String[1],Type,Array[Variant[String[1],Type]]]`)
+			d.Function(func(c px.Context, args []px.Value) px.Value {
+				return declareClasses(c, args, func(ec pdsl.EvaluationContext, ref pdsl.Reference) {
+					if container := ec.Container(); container != nil {
+						ec.Catalog().Contain(container, ref)
+					}
+				})
+			})
+		},
+	)
+}
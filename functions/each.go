@@ -2,22 +2,22 @@ package functions
 
 import (
 	"github.com/lyraproj/pcore/px"
-	"github.com/lyraproj/pcore/types"
 	"github.com/lyraproj/puppet-evaluator/evaluator"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
 )
 
-func eachIterator(c px.Context, arg px.Indexed, block px.Lambda) {
-	evaluator.WrapIterable(arg).Each(func(v px.Value) { block.Call(c, nil, v) })
+func eachIterator(c px.Context, iter pdsl.Iterator, block px.Lambda) {
+	iter.Each(func(v px.Value) { block.Call(c, nil, v) })
 }
 
-func eachIndexIterator(c px.Context, iter px.Indexed, block px.Lambda) {
-	evaluator.WrapIterable(iter).EachWithIndex(func(idx px.Value, v px.Value) {
+func eachIndexIterator(c px.Context, iter pdsl.Iterator, block px.Lambda) {
+	iter.EachWithIndex(func(idx px.Value, v px.Value) {
 		block.Call(c, nil, idx, v)
 	})
 }
 
-func eachHashIterator(c px.Context, iter px.Indexed, block px.Lambda) {
-	evaluator.WrapIterable(iter).Each(func(v px.Value) {
+func eachHashIterator(c px.Context, iter pdsl.Iterator, block px.Lambda) {
+	iter.Each(func(v px.Value) {
 		vi := v.(px.List)
 		block.Call(c, nil, vi.At(0), vi.At(1))
 	})
@@ -29,9 +29,8 @@ func init() {
 			d.Param(`Hash`)
 			d.Block(`Callable[1,1]`)
 			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
-				hash := args[0].(*types.Hash)
-				eachIterator(c, hash, block)
-				return hash
+				eachIterator(c, evaluator.Iterate(args[0]), block)
+				return args[0]
 			})
 		},
 
@@ -39,9 +38,8 @@ func init() {
 			d.Param(`Hash`)
 			d.Block(`Callable[2,2]`)
 			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
-				hash := args[0].(*types.Hash)
-				eachHashIterator(c, hash, block)
-				return hash
+				eachHashIterator(c, evaluator.Iterate(args[0]), block)
+				return args[0]
 			})
 		},
 
@@ -49,9 +47,8 @@ func init() {
 			d.Param(`Iterable`)
 			d.Block(`Callable[1,1]`)
 			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
-				iter := args[0].(px.Indexed)
-				eachIterator(c, iter, block)
-				return iter.(px.Value)
+				eachIterator(c, evaluator.Iterate(args[0]), block)
+				return args[0]
 			})
 		},
 
@@ -61,11 +58,29 @@ func init() {
 			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
 				iter := args[0].(px.Indexed)
 				if iter.IsHashStyle() {
-					eachHashIterator(c, iter, block)
+					eachHashIterator(c, evaluator.WrapIterable(iter), block)
 				} else {
-					eachIndexIterator(c, iter, block)
+					eachIndexIterator(c, evaluator.WrapIterable(iter), block)
 				}
-				return iter.(px.Value)
+				return args[0]
+			})
+		},
+
+		func(d px.Dispatch) {
+			d.Param(`Iterator`)
+			d.Block(`Callable[1,1]`)
+			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
+				eachIterator(c, evaluator.Iterate(args[0]), block)
+				return args[0]
+			})
+		},
+
+		func(d px.Dispatch) {
+			d.Param(`Iterator`)
+			d.Block(`Callable[2,2]`)
+			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
+				eachIndexIterator(c, evaluator.Iterate(args[0]), block)
+				return args[0]
 			})
 		},
 	)
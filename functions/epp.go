@@ -0,0 +1,42 @@
+package functions
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/puppet-evaluator/evaluator"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+)
+
+// readTemplate returns the path and the content of the template with the given name. A name that isn't an
+// absolute path is on the form <module name>/<file> and denotes a file in the templates directory of the module.
+func readTemplate(c px.Context, name string) (string, string) {
+	if path := pdsl.ModuleFile(c, `templates`, name); path != `` {
+		content, err := ioutil.ReadFile(path)
+		if err == nil {
+			return path, string(content)
+		}
+		if os.IsPermission(err) {
+			panic(px.Error(px.FileReadDenied, issue.H{`path`: path}))
+		}
+	}
+	panic(px.Error(pdsl.UnknownTemplate, issue.H{`name`: name}))
+}
+
+func init() {
+	px.NewGoFunction(`epp`,
+		func(d px.Dispatch) {
+			d.Param(`String`)
+			d.OptionalParam(`Hash[Pattern[/\A\w+\z/], Any]`)
+			d.Function(func(c px.Context, args []px.Value) px.Value {
+				params := px.EmptyMap
+				if len(args) > 1 {
+					params = args[1].(px.OrderedMap)
+				}
+				path, source := readTemplate(c, args[0].String())
+				return evaluator.RenderTemplate(c.(pdsl.EvaluationContext), path, source, params, false)
+			})
+		})
+}
@@ -0,0 +1,32 @@
+package functions
+
+import (
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/yaml"
+	"github.com/lyraproj/puppet-evaluator/eyaml"
+	"github.com/lyraproj/puppet-evaluator/hiera"
+)
+
+func init() {
+	px.NewGoFunction(`eyaml_data`,
+		func(d px.Dispatch) {
+			d.Param(`Struct[{path=>String, pkcs7_private_key=>String, Optional[pkcs7_public_key]=>String}]`)
+			d.Param(`LookupContext`)
+			d.Function(func(c px.Context, args []px.Value) px.Value {
+				options := args[0].(px.OrderedMap)
+				path := options.Get5(`path`, px.EmptyString).String()
+				publicKey := ``
+				if pk, ok := options.Get4(`pkcs7_public_key`); ok {
+					publicKey = pk.String()
+				}
+				keys := eyaml.LoadKeys(options.Get5(`pkcs7_private_key`, px.EmptyString).String(), publicKey)
+
+				var data []byte
+				value := hiera.ReadData(path, `YAML`, func(content []byte) px.Value {
+					data = content
+					return yaml.Unmarshal(c, content)
+				})
+				return eyaml.Decrypt(keys, path, data, value)
+			})
+		})
+}
@@ -0,0 +1,16 @@
+package functions
+
+import (
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+func init() {
+	px.NewGoFunction(`file`,
+		func(d px.Dispatch) {
+			d.RequiredRepeatedParam(`Variant[String, Array[String]]`)
+			d.Function(func(c px.Context, args []px.Value) px.Value {
+				return types.WrapString(string(readFile(c, fileNames(args)).Bytes()))
+			})
+		})
+}
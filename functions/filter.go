@@ -4,22 +4,23 @@ import (
 	"github.com/lyraproj/pcore/px"
 	"github.com/lyraproj/pcore/types"
 	"github.com/lyraproj/puppet-evaluator/evaluator"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
 )
 
-func selectIterator(c px.Context, arg px.Indexed, block px.Lambda) px.List {
-	return evaluator.WrapIterable(arg).Select(func(v px.Value) bool { return px.IsTruthy(block.Call(c, nil, v)) }).AsArray()
+func selectIterator(c px.Context, iter pdsl.Iterator, block px.Lambda) px.List {
+	return iter.Select(func(v px.Value) bool { return px.IsTruthy(block.Call(c, nil, v)) }).AsArray()
 }
 
-func selectIndexIterator(c px.Context, iter px.Indexed, block px.Lambda) px.List {
+func selectIndexIterator(c px.Context, iter pdsl.Iterator, block px.Lambda) px.List {
 	index := int64(-1)
-	return evaluator.WrapIterable(iter).Select(func(v px.Value) bool {
+	return iter.Select(func(v px.Value) bool {
 		index++
 		return px.IsTruthy(block.Call(c, nil, types.WrapInteger(index), v))
 	}).AsArray()
 }
 
-func selectHashIterator(c px.Context, iter px.Indexed, block px.Lambda) px.List {
-	return evaluator.WrapIterable(iter).Select(func(v px.Value) bool {
+func selectHashIterator(c px.Context, iter pdsl.Iterator, block px.Lambda) px.List {
+	return iter.Select(func(v px.Value) bool {
 		vi := v.(px.List)
 		return px.IsTruthy(block.Call(c, nil, vi.At(0), vi.At(1)))
 	}).AsArray()
@@ -31,7 +32,7 @@ func init() {
 			d.Param(`Hash`)
 			d.Block(`Callable[1,1]`)
 			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
-				return selectIterator(c, args[0].(*types.Hash), block)
+				return selectIterator(c, evaluator.Iterate(args[0]), block)
 			})
 		},
 
@@ -39,7 +40,7 @@ func init() {
 			d.Param(`Hash`)
 			d.Block(`Callable[2,2]`)
 			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
-				return selectHashIterator(c, args[0].(*types.Hash), block)
+				return selectHashIterator(c, evaluator.Iterate(args[0]), block)
 			})
 		},
 
@@ -47,7 +48,7 @@ func init() {
 			d.Param(`Iterable`)
 			d.Block(`Callable[1,1]`)
 			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
-				return selectIterator(c, args[0].(px.Indexed), block)
+				return selectIterator(c, evaluator.Iterate(args[0]), block)
 			})
 		},
 
@@ -57,9 +58,25 @@ func init() {
 			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
 				iter := args[0].(px.Indexed)
 				if iter.IsHashStyle() {
-					return selectHashIterator(c, iter, block)
+					return selectHashIterator(c, evaluator.WrapIterable(iter), block)
 				}
-				return selectIndexIterator(c, iter, block)
+				return selectIndexIterator(c, evaluator.WrapIterable(iter), block)
+			})
+		},
+
+		func(d px.Dispatch) {
+			d.Param(`Iterator`)
+			d.Block(`Callable[1,1]`)
+			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
+				return selectIterator(c, evaluator.Iterate(args[0]), block)
+			})
+		},
+
+		func(d px.Dispatch) {
+			d.Param(`Iterator`)
+			d.Block(`Callable[2,2]`)
+			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
+				return selectIndexIterator(c, evaluator.Iterate(args[0]), block)
 			})
 		},
 	)
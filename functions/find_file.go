@@ -0,0 +1,39 @@
+package functions
+
+import (
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+)
+
+// fileNames returns the names given as arguments to the file functions. Arrays are flattened.
+func fileNames(args []px.Value) []string {
+	names := make([]string, 0, len(args))
+	types.WrapValues(args).Flatten().Each(func(arg px.Value) {
+		names = append(names, arg.String())
+	})
+	return names
+}
+
+// readFile returns the content of the first of the given names that denotes an existing file
+func readFile(c px.Context, names []string) *types.Binary {
+	path := pdsl.FindFile(c, names...)
+	if path == `` {
+		panic(px.Error(pdsl.UnknownFile, issue.H{`names`: types.WrapStrings(names)}))
+	}
+	return types.BinaryFromFile(path)
+}
+
+func init() {
+	px.NewGoFunction(`find_file`,
+		func(d px.Dispatch) {
+			d.RequiredRepeatedParam(`Variant[String, Array[String]]`)
+			d.Function(func(c px.Context, args []px.Value) px.Value {
+				if path := pdsl.FindFile(c, fileNames(args)...); path != `` {
+					return types.WrapString(path)
+				}
+				return px.Undef
+			})
+		})
+}
@@ -0,0 +1,21 @@
+package functions
+
+import (
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/puppet-evaluator/hiera"
+	"github.com/lyraproj/puppet-evaluator/hocon"
+)
+
+func init() {
+	px.NewGoFunction(`hocon_data`,
+		func(d px.Dispatch) {
+			d.Param(`Struct[{path=>String}]`)
+			d.Param(`LookupContext`)
+			d.Function(func(c px.Context, args []px.Value) px.Value {
+				path := args[0].(px.OrderedMap).Get5(`path`, px.EmptyString).String()
+				return hiera.ReadData(path, `HOCON`, func(content []byte) px.Value {
+					return hocon.Parse(path, content)
+				})
+			})
+		})
+}
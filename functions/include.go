@@ -0,0 +1,38 @@
+package functions
+
+import (
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/puppet-evaluator/evaluator"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+)
+
+// declareClasses declares all classes that are named by the given arguments and calls the
+// given function with a reference to each declared class. An array of the references is returned.
+func declareClasses(c px.Context, args []px.Value, declared func(ec pdsl.EvaluationContext, ref pdsl.Reference)) px.Value {
+	ec := c.(pdsl.EvaluationContext)
+	refs := make([]px.Value, 0, len(args))
+	types.WrapValues(args).Flatten().Each(func(arg px.Value) {
+		name := arg.String()
+		if r, ok := arg.(pdsl.Reference); ok && r.Type() == `Class` {
+			name = r.Title()
+		}
+		ref := evaluator.DeclareClass(ec, name, px.EmptyMap, false, c.StackTop())
+		if declared != nil {
+			declared(ec, ref)
+		}
+		refs = append(refs, ref.(px.Value))
+	})
+	return types.WrapValues(refs)
+}
+
+func init() {
+	px.NewGoFunction(`include`,
+		func(d px.Dispatch) {
+			d.RepeatedParam(`Variant[String[1],Type,Array[Variant[String[1],Type]]]`)
+			d.Function(func(c px.Context, args []px.Value) px.Value {
+				return declareClasses(c, args, nil)
+			})
+		},
+	)
+}
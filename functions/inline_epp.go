@@ -0,0 +1,22 @@
+package functions
+
+import (
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/puppet-evaluator/evaluator"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+)
+
+func init() {
+	px.NewGoFunction(`inline_epp`,
+		func(d px.Dispatch) {
+			d.Param(`String`)
+			d.OptionalParam(`Hash[Pattern[/\A\w+\z/], Any]`)
+			d.Function(func(c px.Context, args []px.Value) px.Value {
+				params := px.EmptyMap
+				if len(args) > 1 {
+					params = args[1].(px.OrderedMap)
+				}
+				return evaluator.RenderTemplate(c.(pdsl.EvaluationContext), `inline template`, args[0].String(), params, true)
+			})
+		})
+}
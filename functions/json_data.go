@@ -0,0 +1,25 @@
+package functions
+
+import (
+	"bytes"
+
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/serialization"
+	"github.com/lyraproj/puppet-evaluator/hiera"
+)
+
+func init() {
+	px.NewGoFunction(`json_data`,
+		func(d px.Dispatch) {
+			d.Param(`Struct[{path=>String}]`)
+			d.Param(`LookupContext`)
+			d.Function(func(c px.Context, args []px.Value) px.Value {
+				path := args[0].(px.OrderedMap).Get5(`path`, px.EmptyString).String()
+				return hiera.ReadData(path, `JSON`, func(content []byte) px.Value {
+					collector := px.NewCollector()
+					serialization.JsonToData(path, bytes.NewReader(content), collector)
+					return collector.Value()
+				})
+			})
+		})
+}
@@ -0,0 +1,132 @@
+package functions
+
+import (
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/puppet-evaluator/hiera"
+)
+
+const lookupOptionsType = `Struct[{
+	Optional[value_type] => Type,
+	Optional[merge] => Variant[String[1], Hash[String, Any]],
+	Optional[default_value] => Any,
+	Optional[default_values_hash] => Hash[String, Any],
+	Optional[override] => Hash[String, Any]
+}]`
+
+const lookupOptionsWithNameType = `Struct[{
+	name => Variant[String, Array[String]],
+	Optional[value_type] => Type,
+	Optional[merge] => Variant[String[1], Hash[String, Any]],
+	Optional[default_value] => Any,
+	Optional[default_values_hash] => Hash[String, Any],
+	Optional[override] => Hash[String, Any]
+}]`
+
+// lookup looks up the given names in order and returns the first found value. The override hash of the
+// given options is consulted before the lookup and the default_values_hash after it. When nothing is found,
+// the default_value of the options is returned, or the result of calling the given block with the first
+// name. It is an error if no value can be produced.
+func lookup(c px.Context, names px.Value, options px.OrderedMap, block px.Lambda) px.Value {
+	var nameList []string
+	if a, ok := names.(*types.Array); ok {
+		a.Each(func(n px.Value) { nameList = append(nameList, n.String()) })
+	} else {
+		nameList = []string{names.String()}
+	}
+	valueType := options.Get5(`value_type`, types.DefaultAnyType()).(px.Type)
+	assertValueType := func(name string, v px.Value) px.Value {
+		return px.AssertInstance(func() string { return `lookup() value for '` + name + `'` }, valueType, v)
+	}
+
+	override := options.Get5(`override`, px.EmptyMap).(px.OrderedMap)
+	for _, name := range nameList {
+		if v, ok := override.Get4(name); ok {
+			return assertValueType(name, v)
+		}
+	}
+
+	merge := options.Get5(`merge`, px.Undef)
+	for _, name := range nameList {
+		if v, ok := hiera.Lookup(c, name, merge); ok {
+			return assertValueType(name, v)
+		}
+	}
+
+	defaults := options.Get5(`default_values_hash`, px.EmptyMap).(px.OrderedMap)
+	for _, name := range nameList {
+		if v, ok := defaults.Get4(name); ok {
+			return assertValueType(name, v)
+		}
+	}
+
+	if v, ok := options.Get4(`default_value`); ok {
+		return assertValueType(nameList[0], v)
+	}
+	if block != nil {
+		return assertValueType(nameList[0], block.Call(c, nil, types.WrapString(nameList[0])))
+	}
+	panic(px.Error(hiera.NameNotFound, issue.H{`name`: nameList[0]}))
+}
+
+func init() {
+	positionalOptions := func(args []px.Value) px.OrderedMap {
+		entries := make([]*types.HashEntry, 0, 3)
+		for i, key := range []string{`value_type`, `merge`, `default_value`} {
+			if i+1 < len(args) && (args[i+1] != px.Undef || key == `default_value`) {
+				entries = append(entries, types.WrapHashEntry2(key, args[i+1]))
+			}
+		}
+		return types.WrapHash(entries)
+	}
+
+	px.NewGoFunction(`lookup`,
+		func(d px.Dispatch) {
+			d.Param(`Variant[String, Array[String]]`)
+			d.OptionalParam(`Optional[Type]`)
+			d.OptionalParam(`Optional[Variant[String[1], Hash[String, Any]]]`)
+			d.Function(func(c px.Context, args []px.Value) px.Value {
+				return lookup(c, args[0], positionalOptions(args), nil)
+			})
+		},
+
+		func(d px.Dispatch) {
+			d.Param(`Variant[String, Array[String]]`)
+			d.Param(`Optional[Type]`)
+			d.Param(`Optional[Variant[String[1], Hash[String, Any]]]`)
+			d.Param(`Any`)
+			d.Function(func(c px.Context, args []px.Value) px.Value {
+				return lookup(c, args[0], positionalOptions(args), nil)
+			})
+		},
+
+		func(d px.Dispatch) {
+			d.Param(`Variant[String, Array[String]]`)
+			d.OptionalParam(`Optional[Type]`)
+			d.OptionalParam(`Optional[Variant[String[1], Hash[String, Any]]]`)
+			d.Block(`Callable[1,1]`)
+			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
+				return lookup(c, args[0], positionalOptions(args), block)
+			})
+		},
+
+		func(d px.Dispatch) {
+			d.Param(lookupOptionsWithNameType)
+			d.OptionalBlock(`Callable[1,1]`)
+			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
+				options := args[0].(px.OrderedMap)
+				return lookup(c, options.Get5(`name`, px.Undef), options, block)
+			})
+		},
+
+		func(d px.Dispatch) {
+			d.Param(`Variant[String, Array[String]]`)
+			d.Param(lookupOptionsType)
+			d.OptionalBlock(`Callable[1,1]`)
+			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
+				return lookup(c, args[0], args[1].(px.OrderedMap), block)
+			})
+		},
+	)
+}
@@ -4,22 +4,23 @@ import (
 	"github.com/lyraproj/pcore/px"
 	"github.com/lyraproj/pcore/types"
 	"github.com/lyraproj/puppet-evaluator/evaluator"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
 )
 
-func mapIterator(c px.Context, arg px.Indexed, block px.Lambda) px.List {
-	return evaluator.WrapIterable(arg).Map(block.Signature().ReturnType(), func(v px.Value) px.Value { return block.Call(c, nil, v) }).AsArray()
+func mapIterator(c px.Context, iter pdsl.Iterator, block px.Lambda) px.List {
+	return iter.Map(block.Signature().ReturnType(), func(v px.Value) px.Value { return block.Call(c, nil, v) }).AsArray()
 }
 
-func mapIndexIterator(c px.Context, iter px.Indexed, block px.Lambda) px.List {
+func mapIndexIterator(c px.Context, iter pdsl.Iterator, block px.Lambda) px.List {
 	index := int64(-1)
-	return evaluator.WrapIterable(iter).Map(block.Signature().ReturnType(), func(v px.Value) px.Value {
+	return iter.Map(block.Signature().ReturnType(), func(v px.Value) px.Value {
 		index++
 		return block.Call(c, nil, types.WrapInteger(index), v)
 	}).AsArray()
 }
 
-func mapHashIterator(c px.Context, iter px.Indexed, block px.Lambda) px.List {
-	return evaluator.WrapIterable(iter).Map(block.Signature().ReturnType(), func(v px.Value) px.Value {
+func mapHashIterator(c px.Context, iter pdsl.Iterator, block px.Lambda) px.List {
+	return iter.Map(block.Signature().ReturnType(), func(v px.Value) px.Value {
 		vi := v.(px.List)
 		return block.Call(c, nil, vi.At(0), vi.At(1))
 	}).AsArray()
@@ -31,7 +32,7 @@ func init() {
 			d.Param(`Hash`)
 			d.Block(`Callable[1,1]`)
 			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
-				return mapIterator(c, args[0].(*types.Hash), block)
+				return mapIterator(c, evaluator.Iterate(args[0]), block)
 			})
 		},
 
@@ -39,7 +40,7 @@ func init() {
 			d.Param(`Hash`)
 			d.Block(`Callable[2,2]`)
 			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
-				return mapHashIterator(c, args[0].(*types.Hash), block)
+				return mapHashIterator(c, evaluator.Iterate(args[0]), block)
 			})
 		},
 
@@ -47,7 +48,7 @@ func init() {
 			d.Param(`Iterable`)
 			d.Block(`Callable[1,1]`)
 			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
-				return mapIterator(c, args[0].(px.Indexed), block)
+				return mapIterator(c, evaluator.Iterate(args[0]), block)
 			})
 		},
 
@@ -57,9 +58,25 @@ func init() {
 			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
 				iter := args[0].(px.Indexed)
 				if iter.IsHashStyle() {
-					return mapHashIterator(c, iter, block)
+					return mapHashIterator(c, evaluator.WrapIterable(iter), block)
 				}
-				return mapIndexIterator(c, iter, block)
+				return mapIndexIterator(c, evaluator.WrapIterable(iter), block)
+			})
+		},
+
+		func(d px.Dispatch) {
+			d.Param(`Iterator`)
+			d.Block(`Callable[1,1]`)
+			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
+				return mapIterator(c, evaluator.Iterate(args[0]), block)
+			})
+		},
+
+		func(d px.Dispatch) {
+			d.Param(`Iterator`)
+			d.Block(`Callable[2,2]`)
+			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
+				return mapIndexIterator(c, evaluator.Iterate(args[0]), block)
 			})
 		},
 	)
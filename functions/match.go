@@ -41,11 +41,11 @@ func matchPatterns(s string, v px.Value) px.Value {
 	case *types.PatternType:
 		return matchArray(s, v.Patterns())
 	case *types.RegexpType:
-		return matchRegexp(s, types.WrapRegexp(v.PatternString()))
+		return matchRegexp(s, pdsl.WrapRegexp(v.PatternString()))
 	case *types.Array:
 		return matchArray(s, v)
 	default:
-		return matchRegexp(s, types.WrapRegexp(v.String()))
+		return matchRegexp(s, pdsl.WrapRegexp(v.String()))
 	}
 }
 
@@ -54,13 +54,18 @@ func matchRegexp(s string, rx *types.Regexp) px.Value {
 		panic(px.Error(pdsl.MissingRegexpInType, issue.NoArgs))
 	}
 
-	g := rx.Match(s)
+	g := pdsl.RegexpOf(rx).FindStringSubmatchIndex(s)
 	if g == nil {
 		return px.Undef
 	}
-	rs := make([]px.Value, len(g))
-	for i, s := range g {
-		rs[i] = types.WrapString(s)
+	// A group that didn't participate in the match is undef
+	rs := make([]px.Value, len(g)/2)
+	for i := range rs {
+		if g[2*i] < 0 {
+			rs[i] = px.Undef
+		} else {
+			rs[i] = types.WrapString(s[g[2*i]:g[2*i+1]])
+		}
 	}
 	return types.WrapValues(rs)
 }
@@ -8,20 +8,20 @@ import (
 func init() {
 	px.NewGoFunction(`reduce`,
 		func(d px.Dispatch) {
-			d.Param(`Iterable`)
+			d.Param(`Variant[Iterable, Iterator]`)
 			d.Block(`Callable[2,2]`)
 			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
-				return evaluator.WrapIterable(args[0].(px.Indexed)).Reduce(
+				return evaluator.Iterate(args[0]).Reduce(
 					func(v1 px.Value, v2 px.Value) px.Value { return block.Call(c, nil, v1, v2) })
 			})
 		},
 
 		func(d px.Dispatch) {
-			d.Param(`Iterable`)
+			d.Param(`Variant[Iterable, Iterator]`)
 			d.Param(`Any`)
 			d.Block(`Callable[2,2]`)
 			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
-				return evaluator.WrapIterable(args[0].(px.Indexed)).Reduce2(
+				return evaluator.Iterate(args[0]).Reduce2(
 					args[1], func(v1 px.Value, v2 px.Value) px.Value { return block.Call(c, nil, v1, v2) })
 			})
 		},
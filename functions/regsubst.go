@@ -0,0 +1,134 @@
+package functions
+
+import (
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+)
+
+func init() {
+	px.NewGoFunction(`regsubst`,
+		func(d px.Dispatch) {
+			d.Param(`Variant[Array[String], String]`)
+			d.Param(`String`)
+			d.Param(`Variant[String, Hash[String, String]]`)
+			d.OptionalParam(`Optional[Pattern[/^[GEIM]*$/]]`)
+			// The encoding is accepted for compatibility. Go strings are always UTF-8.
+			d.OptionalParam(`Enum['N', 'E', 'S', 'U']`)
+			d.Function(func(c px.Context, args []px.Value) px.Value {
+				flags := ``
+				if len(args) > 3 && args[3] != px.Undef {
+					flags = args[3].String()
+				}
+				options := ``
+				for _, f := range [][2]string{{`I`, `i`}, {`M`, `m`}, {`E`, `x`}} {
+					if strings.Contains(flags, f[0]) {
+						options += f[1]
+					}
+				}
+				pattern := args[1].String()
+				rx, err := pdsl.CompileRegexpOptions(pattern, options)
+				if err != nil {
+					panic(px.Error(px.InvalidRegexp, issue.H{`pattern`: pattern, `detail`: err.Error()}))
+				}
+				return regsubst(args[0], rx, args[2], strings.Contains(flags, `G`))
+			})
+		},
+
+		func(d px.Dispatch) {
+			d.Param(`Variant[Array[String], String]`)
+			d.Param(`Variant[Regexp, Type[Regexp]]`)
+			d.Param(`Variant[String, Hash[String, String]]`)
+			d.OptionalParam(`Pattern[/^G?$/]`)
+			d.Function(func(c px.Context, args []px.Value) px.Value {
+				var rx *types.Regexp
+				if t, ok := args[1].(*types.RegexpType); ok {
+					rx = types.WrapRegexp2(t.Regexp())
+				} else {
+					rx = args[1].(*types.Regexp)
+				}
+				return regsubst(args[0], pdsl.RegexpOf(rx), args[2], len(args) > 3 && args[3].String() == `G`)
+			})
+		},
+	)
+}
+
+// regsubst performs the substitution on the given string, or on each string in the given array
+func regsubst(target px.Value, rx pdsl.Regexp, replacement px.Value, global bool) px.Value {
+	if a, ok := target.(*types.Array); ok {
+		return a.Map(func(e px.Value) px.Value { return regsubst(e, rx, replacement, global) })
+	}
+	s := target.String()
+	n := 1
+	if global {
+		n = -1
+	}
+	matches := rx.FindAllStringSubmatchIndex(s, n)
+	if len(matches) == 0 {
+		return target
+	}
+	b := strings.Builder{}
+	beg := 0
+	for _, loc := range matches {
+		b.WriteString(s[beg:loc[0]])
+		if h, ok := replacement.(*types.Hash); ok {
+			// A match that is not found in the hash is replaced with an empty string
+			if v, ok := h.Get4(s[loc[0]:loc[1]]); ok {
+				b.WriteString(v.String())
+			}
+		} else {
+			expandReplacement(&b, replacement.String(), s, loc, rx.SubexpNames())
+		}
+		beg = loc[1]
+	}
+	b.WriteString(s[beg:])
+	return types.WrapString(b.String())
+}
+
+// expandReplacement writes the given replacement to the builder with the back references of Ruby
+// expanded. The references are \0 and \& for the whole match, \1 through \9 and \k<name> for the
+// capture groups, \` and \' for the text before and after the match, and \\ for a backslash.
+func expandReplacement(b *strings.Builder, repl, s string, loc []int, names []string) {
+	group := func(i int) {
+		if 2*i+1 < len(loc) && loc[2*i] >= 0 {
+			b.WriteString(s[loc[2*i]:loc[2*i+1]])
+		}
+	}
+	for i := 0; i < len(repl); i++ {
+		c := repl[i]
+		if c != '\\' || i+1 == len(repl) {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		switch c = repl[i]; {
+		case c >= '0' && c <= '9':
+			group(int(c - '0'))
+		case c == '&':
+			group(0)
+		case c == '`':
+			b.WriteString(s[:loc[0]])
+		case c == '\'':
+			b.WriteString(s[loc[1]:])
+		case c == '\\':
+			b.WriteByte('\\')
+		case c == 'k' && i+1 < len(repl) && repl[i+1] == '<' && strings.IndexByte(repl[i+2:], '>') >= 0:
+			end := i + 2 + strings.IndexByte(repl[i+2:], '>')
+			name := repl[i+2 : end]
+			// When several groups have the same name, the last one that participated in the match is used
+			for gi := len(names) - 1; gi > 0; gi-- {
+				if names[gi] == name && 2*gi < len(loc) && loc[2*gi] >= 0 {
+					group(gi)
+					break
+				}
+			}
+			i = end
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package functions
+
+import (
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/puppet-evaluator/evaluator"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+)
+
+func init() {
+	px.NewGoFunction(`require`,
+		func(d px.Dispatch) {
+			d.RepeatedParam(`Variant[String[1],Type,Array[Variant[String[1],Type]]]`)
+			d.Function(func(c px.Context, args []px.Value) px.Value {
+				return declareClasses(c, args, func(ec pdsl.EvaluationContext, ref pdsl.Reference) {
+					if container := ec.Container(); container != nil {
+						ec.Catalog().AddEdge(evaluator.NewEdge(ref, container, false, c.StackTop()))
+					}
+				})
+			})
+		},
+	)
+}
@@ -0,0 +1,20 @@
+package functions
+
+import (
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/puppet-evaluator/evaluator"
+)
+
+func init() {
+	px.NewGoFunction(`resolve_deferred`,
+		func(d px.Dispatch) {
+			d.Param(`Any`)
+			d.Param(`Array[String[1]]`)
+			d.Function(func(c px.Context, args []px.Value) px.Value {
+				names := make([]string, 0, args[1].(px.List).Len())
+				args[1].(px.List).Each(func(n px.Value) { names = append(names, n.String()) })
+				return evaluator.ResolveDeferred(c, args[0], evaluator.LoadFunctionRegistry(c, names...))
+			})
+		},
+	)
+}
@@ -0,0 +1,26 @@
+package functions
+
+import (
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/puppet-evaluator/evaluator"
+)
+
+func init() {
+	px.NewGoFunction(`reverse_each`,
+		func(d px.Dispatch) {
+			d.Param(`Variant[Iterable, Iterator, Integer, Type[Integer]]`)
+			d.Function(func(c px.Context, args []px.Value) px.Value {
+				return evaluator.ReverseIterator(args[0])
+			})
+		},
+
+		func(d px.Dispatch) {
+			d.Param(`Variant[Iterable, Iterator, Integer, Type[Integer]]`)
+			d.Block(`Callable[1,1]`)
+			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
+				evaluator.Iterate(evaluator.ReverseIterator(args[0])).Each(func(v px.Value) { block.Call(c, nil, v) })
+				return px.Undef
+			})
+		},
+	)
+}
@@ -0,0 +1,328 @@
+package functions
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+)
+
+// scanDirective is one element of a scanf format. The verb is the conversion character of a directive,
+// a space for whitespace, or zero for literal text.
+type scanDirective struct {
+	verb     byte
+	literal  string
+	width    int
+	suppress bool
+	negate   bool
+	set      []rune
+}
+
+// parseScanFormat parses a format with the directives of Ruby's String#scanf. A directive is a '%'
+// followed by an optional '*' that suppresses the result, an optional maximum width, and one of the
+// conversions d, u, i, o, x, X, a, e, f, g, A, E, F, G, s, c, or a character set in brackets. A '%%' is
+// a literal '%' and a run of whitespace matches any amount of whitespace.
+func parseScanFormat(format string) []*scanDirective {
+	invalid := func(detail string) {
+		panic(px.Error(pdsl.InvalidScanfFormat, issue.H{`format`: format, `detail`: detail}))
+	}
+	var directives []*scanDirective
+	literal := func(s string) {
+		if n := len(directives); n > 0 && directives[n-1].verb == 0 {
+			directives[n-1].literal += s
+		} else {
+			directives = append(directives, &scanDirective{literal: s})
+		}
+	}
+	for i := 0; i < len(format); {
+		c := format[i]
+		if isScanSpace(c) {
+			for i < len(format) && isScanSpace(format[i]) {
+				i++
+			}
+			directives = append(directives, &scanDirective{verb: ' '})
+			continue
+		}
+		if c != '%' {
+			_, size := utf8.DecodeRuneInString(format[i:])
+			literal(format[i : i+size])
+			i += size
+			continue
+		}
+		i++
+		if i < len(format) && format[i] == '%' {
+			literal(`%`)
+			i++
+			continue
+		}
+		d := &scanDirective{}
+		if i < len(format) && format[i] == '*' {
+			d.suppress = true
+			i++
+		}
+		for i < len(format) && isDigit(format[i]) {
+			d.width = d.width*10 + int(format[i]-'0')
+			i++
+		}
+		if i == len(format) {
+			invalid(`the format ends with an incomplete directive`)
+		}
+		d.verb = format[i]
+		i++
+		switch d.verb {
+		case 'd', 'u', 'i', 'o', 'x', 'X', 'a', 'e', 'f', 'g', 'A', 'E', 'F', 'G', 's', 'c':
+		case '[':
+			i = d.parseSet(format, i)
+			if i < 0 {
+				invalid(`unterminated character set`)
+			}
+		default:
+			invalid(`unknown conversion '%` + string(d.verb) + `'`)
+		}
+		directives = append(directives, d)
+	}
+	return directives
+}
+
+// parseSet parses the character set that starts at the given position of the format and returns the
+// position after the closing bracket, or -1 if there is no closing bracket. A ']' that is first in the
+// set is a member and so is a '-' that is first or last. Other '-' characters denote ranges.
+func (d *scanDirective) parseSet(format string, i int) int {
+	if i < len(format) && format[i] == '^' {
+		d.negate = true
+		i++
+	}
+	start := i
+	for i < len(format) {
+		r, size := utf8.DecodeRuneInString(format[i:])
+		if r == ']' && i > start {
+			return i + 1
+		}
+		i += size
+		if i+1 < len(format) && format[i] == '-' && format[i+1] != ']' {
+			hi, hs := utf8.DecodeRuneInString(format[i+1:])
+			d.set = append(d.set, r, hi)
+			i += 1 + hs
+		} else {
+			d.set = append(d.set, r, r)
+		}
+	}
+	return -1
+}
+
+func (d *scanDirective) inSet(r rune) bool {
+	for i := 0; i < len(d.set); i += 2 {
+		if r >= d.set[i] && r <= d.set[i+1] {
+			return !d.negate
+		}
+	}
+	return d.negate
+}
+
+func isScanSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+// scan matches the directives against the given string and returns the converted values. The scan
+// stops at the first directive that doesn't match.
+func scan(s string, directives []*scanDirective) []px.Value {
+	result := make([]px.Value, 0)
+	pos := 0
+	for _, d := range directives {
+		if d.verb == ' ' {
+			pos = skipSpace(s, pos)
+			continue
+		}
+		if d.verb == 0 {
+			if !strings.HasPrefix(s[pos:], d.literal) {
+				break
+			}
+			pos += len(d.literal)
+			continue
+		}
+		if d.verb != 'c' && d.verb != '[' {
+			pos = skipSpace(s, pos)
+		}
+		token := d.token(s[pos:])
+		if token == `` {
+			break
+		}
+		v := d.convert(token)
+		if v == nil {
+			break
+		}
+		pos += len(token)
+		if !d.suppress {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+func skipSpace(s string, pos int) int {
+	for pos < len(s) && isScanSpace(s[pos]) {
+		pos++
+	}
+	return pos
+}
+
+// token returns the longest prefix of the given string that the directive matches, limited to the
+// width of the directive, or an empty string if there is no match
+func (d *scanDirective) token(s string) string {
+	width := d.width
+	switch d.verb {
+	case 'c', 's', '[':
+		if width == 0 {
+			if d.verb == 'c' {
+				width = 1
+			} else {
+				width = -1
+			}
+		}
+		end := 0
+		for n := 0; end < len(s) && n != width; n++ {
+			r, size := utf8.DecodeRuneInString(s[end:])
+			if d.verb == 's' && unicode.IsSpace(r) || d.verb == '[' && !d.inSet(r) {
+				break
+			}
+			end += size
+		}
+		return s[:end]
+	}
+
+	if width > 0 && width < len(s) {
+		s = s[:width]
+	}
+	end := 0
+	if end < len(s) && (s[end] == '-' || s[end] == '+') {
+		end++
+	}
+	digits := func(valid func(byte) bool) int {
+		start := end
+		for end < len(s) && valid(s[end]) {
+			end++
+		}
+		return end - start
+	}
+	switch d.verb {
+	case 'd', 'u':
+		if digits(isDigit) == 0 {
+			return ``
+		}
+	case 'o':
+		if digits(isOctal) == 0 {
+			return ``
+		}
+	case 'x', 'X':
+		if hasHexPrefix(s[end:]) {
+			end += 2
+		}
+		if digits(isHex) == 0 {
+			return ``
+		}
+	case 'i':
+		switch {
+		case hasHexPrefix(s[end:]):
+			end += 2
+			if digits(isHex) == 0 {
+				return ``
+			}
+		case end < len(s) && s[end] == '0':
+			digits(isOctal)
+		case digits(isDigit) == 0:
+			return ``
+		}
+	default:
+		n := digits(isDigit)
+		if end < len(s) && s[end] == '.' {
+			end++
+			n += digits(isDigit)
+		}
+		if n == 0 {
+			return ``
+		}
+		if end < len(s) && (s[end] == 'e' || s[end] == 'E') {
+			mark := end
+			end++
+			if end < len(s) && (s[end] == '-' || s[end] == '+') {
+				end++
+			}
+			if digits(isDigit) == 0 {
+				end = mark
+			}
+		}
+	}
+	return s[:end]
+}
+
+// convert converts the given token to the value of the directive, or returns nil if the token cannot
+// be converted
+func (d *scanDirective) convert(token string) px.Value {
+	base := 0
+	switch d.verb {
+	case 'c', 's', '[':
+		return types.WrapString(token)
+	case 'a', 'e', 'f', 'g', 'A', 'E', 'F', 'G':
+		f, err := strconv.ParseFloat(token, 64)
+		if err != nil {
+			return nil
+		}
+		return types.WrapFloat(f)
+	case 'd', 'u':
+		base = 10
+	case 'o':
+		base = 8
+	case 'x', 'X':
+		base = 16
+	}
+	sign := ``
+	if token[0] == '-' || token[0] == '+' {
+		sign, token = token[:1], token[1:]
+	}
+	switch {
+	case base == 16 && hasHexPrefix(token):
+		token = token[2:]
+	case base == 0 && len(token) > 1 && token[0] == '0' && !hasHexPrefix(token):
+		// A leading zero means octal for %i
+		base = 8
+	}
+	i, err := strconv.ParseInt(sign+token, base, 64)
+	if err != nil {
+		return nil
+	}
+	return types.WrapInteger(i)
+}
+
+func hasHexPrefix(s string) bool {
+	return len(s) > 2 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X') && isHex(s[2])
+}
+
+func isOctal(c byte) bool {
+	return c >= '0' && c <= '7'
+}
+
+func isHex(c byte) bool {
+	return isDigit(c) || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+func init() {
+	px.NewGoFunction(`scanf`,
+		func(d px.Dispatch) {
+			d.Param(`String`)
+			d.Param(`String`)
+			d.OptionalBlock(`Callable[1,1]`)
+			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
+				result := types.WrapValues(scan(args[0].String(), parseScanFormat(args[1].String())))
+				if block == nil {
+					return result
+				}
+				return block.Call(c, nil, result)
+			})
+		},
+	)
+}
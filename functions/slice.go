@@ -0,0 +1,48 @@
+package functions
+
+import (
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/puppet-evaluator/evaluator"
+)
+
+func init() {
+	px.NewGoFunction(`slice`,
+		func(d px.Dispatch) {
+			d.Param(`Variant[Iterable, Iterator, Integer, Type[Integer]]`)
+			d.Param(`Integer[1]`)
+			d.Function(func(c px.Context, args []px.Value) px.Value {
+				return evaluator.SliceIterator(args[0], args[1].(px.Integer).Int())
+			})
+		},
+
+		func(d px.Dispatch) {
+			d.Param(`Variant[Iterable, Iterator, Integer, Type[Integer]]`)
+			d.Param(`Integer[1]`)
+			d.Block(`Callable[1]`)
+			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
+				iter := evaluator.SliceIterator(args[0], args[1].(px.Integer).Int())
+
+				// A block with more than one parameter receives the elements of each slice as separate
+				// arguments. Missing elements are undef.
+				serving := len(block.Parameters())
+				evaluator.Iterate(iter).Each(func(v px.Value) {
+					if serving == 1 {
+						block.Call(c, nil, v)
+						return
+					}
+					slice := v.(px.List)
+					sargs := make([]px.Value, serving)
+					for i := range sargs {
+						if i < slice.Len() {
+							sargs[i] = slice.At(i)
+						} else {
+							sargs[i] = px.Undef
+						}
+					}
+					block.Call(c, nil, sargs...)
+				})
+				return args[0]
+			})
+		},
+	)
+}
@@ -3,6 +3,7 @@ package functions
 import (
 	"github.com/lyraproj/pcore/px"
 	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
 )
 
 func init() {
@@ -11,8 +12,7 @@ func init() {
 			d.Param(`String`)
 			d.Param(`String`)
 			d.Function(func(c px.Context, args []px.Value) px.Value {
-				types.WrapRegexp(args[1].String()).Regexp()
-				return args[0].(px.StringValue).Split(types.WrapRegexp(args[1].String()).Regexp())
+				return pdsl.Split(args[0].(px.StringValue), pdsl.RegexpOf(pdsl.WrapRegexp(args[1].String())))
 			})
 		},
 
@@ -20,7 +20,7 @@ func init() {
 			d.Param(`String`)
 			d.Param(`Regexp`)
 			d.Function(func(c px.Context, args []px.Value) px.Value {
-				return args[0].(px.StringValue).Split(args[1].(*types.Regexp).Regexp())
+				return pdsl.Split(args[0].(px.StringValue), pdsl.RegexpOf(args[1].(*types.Regexp)))
 			})
 		},
 
@@ -28,7 +28,7 @@ func init() {
 			d.Param(`String`)
 			d.Param(`Type[Regexp]`)
 			d.Function(func(c px.Context, args []px.Value) px.Value {
-				return args[0].(px.StringValue).Split(args[1].(*types.RegexpType).Regexp())
+				return pdsl.Split(args[0].(px.StringValue), pdsl.RegexpOf(types.WrapRegexp2(args[1].(*types.RegexpType).Regexp())))
 			})
 		},
 	)
@@ -0,0 +1,29 @@
+package functions
+
+import (
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/puppet-evaluator/evaluator"
+)
+
+func init() {
+	px.NewGoFunction(`step`,
+		func(d px.Dispatch) {
+			d.Param(`Variant[Iterable, Iterator, Integer, Type[Integer]]`)
+			d.Param(`Integer[1]`)
+			d.Function(func(c px.Context, args []px.Value) px.Value {
+				return evaluator.StepIterator(args[0], args[1].(px.Integer).Int())
+			})
+		},
+
+		func(d px.Dispatch) {
+			d.Param(`Variant[Iterable, Iterator, Integer, Type[Integer]]`)
+			d.Param(`Integer[1]`)
+			d.Block(`Callable[1,1]`)
+			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
+				iter := evaluator.StepIterator(args[0], args[1].(px.Integer).Int())
+				evaluator.Iterate(iter).Each(func(v px.Value) { block.Call(c, nil, v) })
+				return px.Undef
+			})
+		},
+	)
+}
@@ -0,0 +1,82 @@
+package functions
+
+import (
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/puppet-evaluator/evaluator"
+)
+
+const treeOptionsType = `Struct[{
+  Optional[order] => Enum[depth_first, breadth_first],
+  Optional[include_containers] => Boolean,
+  Optional[include_values] => Boolean,
+  Optional[include_root] => Boolean,
+  Optional[container_type] => Type[Variant[Array, Hash, Object]],
+  Optional[containers] => Type[Variant[Array, Hash, Object]]
+}]`
+
+// treeOptions creates the options of a tree iterator from the given options hash. The containers key is
+// an alias for container_type.
+func treeOptions(args []px.Value) *evaluator.TreeOptions {
+	options := &evaluator.TreeOptions{IncludeContainers: true, IncludeValues: true, IncludeRoot: true}
+	if len(args) < 2 {
+		return options
+	}
+	oh := args[1].(px.OrderedMap)
+	options.BreadthFirst = oh.Get5(`order`, px.Undef).String() == `breadth_first`
+	if b, ok := oh.Get5(`include_containers`, px.Undef).(px.Boolean); ok {
+		options.IncludeContainers = b.Bool()
+	}
+	if b, ok := oh.Get5(`include_values`, px.Undef).(px.Boolean); ok {
+		options.IncludeValues = b.Bool()
+	}
+	if b, ok := oh.Get5(`include_root`, px.Undef).(px.Boolean); ok {
+		options.IncludeRoot = b.Bool()
+	}
+	for _, key := range []string{`container_type`, `containers`} {
+		if t, ok := oh.Get5(key, px.Undef).(px.Type); ok {
+			options.Containers = t
+		}
+	}
+	if !(options.IncludeContainers || options.IncludeValues) {
+		panic(px.Error(px.IllegalArgument, issue.H{`function`: `tree_each`, `index`: 1,
+			`arg`: `include_containers and include_values cannot both be false`}))
+	}
+	return options
+}
+
+func init() {
+	px.NewGoFunction(`tree_each`,
+		func(d px.Dispatch) {
+			d.Param(`Variant[Iterator, Array, Hash, Object]`)
+			d.OptionalParam(treeOptionsType)
+			d.Function(func(c px.Context, args []px.Value) px.Value {
+				return evaluator.TreeIterator(args[0], treeOptions(args))
+			})
+		},
+
+		func(d px.Dispatch) {
+			d.Param(`Variant[Iterator, Array, Hash, Object]`)
+			d.OptionalParam(treeOptionsType)
+			d.Block(`Callable[1,1]`)
+			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
+				evaluator.Iterate(evaluator.TreeIterator(args[0], treeOptions(args))).Each(func(v px.Value) { block.Call(c, nil, v) })
+				return args[0]
+			})
+		},
+
+		func(d px.Dispatch) {
+			d.Param(`Variant[Iterator, Array, Hash, Object]`)
+			d.OptionalParam(treeOptionsType)
+			d.Block(`Callable[2,2]`)
+			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
+				evaluator.Iterate(evaluator.TreeIterator(args[0], treeOptions(args))).Each(func(v px.Value) {
+					pair := v.(*types.Array)
+					block.Call(c, nil, pair.At(0), pair.At(1))
+				})
+				return args[0]
+			})
+		},
+	)
+}
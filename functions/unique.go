@@ -0,0 +1,85 @@
+package functions
+
+import (
+	"strings"
+
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/puppet-evaluator/evaluator"
+)
+
+// keyFunction returns a function that calls the given block, or nil if the block is nil
+func keyFunction(c px.Context, block px.Lambda) px.Mapper {
+	if block == nil {
+		return nil
+	}
+	return func(v px.Value) px.Value { return block.Call(c, nil, v) }
+}
+
+// uniqueHash returns a hash where each unique value of the given hash is associated with an array
+// of the keys that had that value
+func uniqueHash(c px.Context, hash *types.Hash, block px.Lambda) px.Value {
+	keyFunc := keyFunction(c, block)
+	order := make([]px.HashKey, 0, hash.Len())
+	keys := make(map[px.HashKey][]px.Value, hash.Len())
+	values := make(map[px.HashKey]px.Value, hash.Len())
+	hash.EachPair(func(k, v px.Value) {
+		key := v
+		if keyFunc != nil {
+			key = keyFunc(v)
+		}
+		hk := px.ToKey(key)
+		if _, ok := keys[hk]; !ok {
+			order = append(order, hk)
+			values[hk] = v
+		}
+		keys[hk] = append(keys[hk], k)
+	})
+	entries := make([]*types.HashEntry, len(order))
+	for i, hk := range order {
+		entries[i] = types.WrapHashEntry(types.WrapValues(keys[hk]), values[hk])
+	}
+	return types.WrapHash(entries)
+}
+
+func init() {
+	px.NewGoFunction(`unique`,
+		func(d px.Dispatch) {
+			d.Param(`String`)
+			d.OptionalBlock(`Callable[1,1]`)
+			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
+				chars := evaluator.UniqueIterator(args[0], keyFunction(c, block)).AsArray()
+				b := strings.Builder{}
+				chars.Each(func(v px.Value) { b.WriteString(v.String()) })
+				return types.WrapString(b.String())
+			})
+		},
+
+		func(d px.Dispatch) {
+			d.Param(`Hash`)
+			d.OptionalBlock(`Callable[1,1]`)
+			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
+				return uniqueHash(c, args[0].(*types.Hash), block)
+			})
+		},
+
+		func(d px.Dispatch) {
+			d.Param(`Array`)
+			d.OptionalBlock(`Callable[1,1]`)
+			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
+				if block == nil {
+					return args[0].(*types.Array).Unique()
+				}
+				return evaluator.UniqueIterator(args[0], keyFunction(c, block)).AsArray()
+			})
+		},
+
+		func(d px.Dispatch) {
+			d.Param(`Variant[Iterator, Integer, Type[Integer]]`)
+			d.OptionalBlock(`Callable[1,1]`)
+			d.Function2(func(c px.Context, args []px.Value, block px.Lambda) px.Value {
+				return evaluator.UniqueIterator(args[0], keyFunction(c, block))
+			})
+		},
+	)
+}
@@ -0,0 +1,102 @@
+package functions
+
+import (
+	"strings"
+
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+func init() {
+	px.NewGoFunction(`versioncmp`,
+		func(d px.Dispatch) {
+			d.Param(`String`)
+			d.Param(`String`)
+			d.OptionalParam(`Boolean`)
+			d.Function(func(c px.Context, args []px.Value) px.Value {
+				a, b := args[0].String(), args[1].String()
+				if len(args) > 2 && args[2].(px.Boolean).Bool() {
+					a, b = trimTrailingZeroes(a), trimTrailingZeroes(b)
+				}
+				return types.WrapInteger(int64(versionCompare(a, b)))
+			})
+		},
+	)
+}
+
+// versionCompare compares two version strings the way Puppet compares package versions. The versions
+// are split into segments of digits, separators ('-' and '.'), and other characters. Segments are
+// compared pairwise until they differ. A separator sorts before other segments and a '-' before a '.'.
+// Digit segments are compared as numbers unless one of them has a leading zero. Other segments are
+// compared case insensitively. When all pairs are equal, the whole strings are compared.
+func versionCompare(a, b string) int {
+	as, bs := versionSegments(a), versionSegments(b)
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		sa, sb := as[i], bs[i]
+		switch {
+		case sa == sb:
+			continue
+		case sa == `-`:
+			return -1
+		case sb == `-`:
+			return 1
+		case sa == `.`:
+			return -1
+		case sb == `.`:
+			return 1
+		case isDigits(sa) && isDigits(sb) && sa[0] != '0' && sb[0] != '0':
+			if len(sa) != len(sb) {
+				if len(sa) < len(sb) {
+					return -1
+				}
+				return 1
+			}
+			return strings.Compare(sa, sb)
+		default:
+			return strings.Compare(strings.ToUpper(sa), strings.ToUpper(sb))
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+func versionSegments(v string) []string {
+	var segments []string
+	for i := 0; i < len(v); {
+		j := i + 1
+		switch {
+		case v[i] == '-' || v[i] == '.':
+		case isDigit(v[i]):
+			for j < len(v) && isDigit(v[j]) {
+				j++
+			}
+		default:
+			for j < len(v) && !(v[j] == '-' || v[j] == '.' || isDigit(v[j])) {
+				j++
+			}
+		}
+		segments = append(segments, v[i:j])
+		i = j
+	}
+	return segments
+}
+
+// trimTrailingZeroes removes trailing zeroes and dots from the part of the version that precedes the
+// first '-', so that e.g. 1.0 and 1 are equal
+func trimTrailingZeroes(v string) string {
+	parts := strings.SplitN(v, `-`, 2)
+	parts[0] = strings.TrimRight(parts[0], `.0`)
+	return strings.Join(parts, `-`)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isDigit(s[i]) {
+			return false
+		}
+	}
+	return s != ``
+}
@@ -0,0 +1,27 @@
+package functions
+
+import (
+	"bytes"
+
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/yaml"
+	"github.com/lyraproj/puppet-evaluator/hiera"
+)
+
+func init() {
+	px.NewGoFunction(`yaml_data`,
+		func(d px.Dispatch) {
+			d.Param(`Struct[{path=>String}]`)
+			d.Param(`LookupContext`)
+			d.Function(func(c px.Context, args []px.Value) px.Value {
+				path := args[0].(px.OrderedMap).Get5(`path`, px.EmptyString).String()
+				return hiera.ReadData(path, `YAML`, func(content []byte) px.Value {
+					if len(bytes.TrimSpace(content)) == 0 {
+						// An empty document is not accepted by the YAML unmarshaller
+						return px.Undef
+					}
+					return yaml.Unmarshal(c, content)
+				})
+			})
+		})
+}
@@ -1,7 +1,22 @@
 module github.com/lyraproj/puppet-evaluator
 
+go 1.27.1
+
 require (
 	github.com/lyraproj/issue v0.0.0-20190606092846-e082d6813d15
 	github.com/lyraproj/pcore v0.0.0-20190716055636-c093587ebc58
 	github.com/lyraproj/puppet-parser v0.0.0-20190606112603-21687f912799
 )
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/golang/protobuf v1.2.0 // indirect
+	github.com/lyraproj/data-protobuf v0.0.0-20190329160005-a909d9e1f93b // indirect
+	github.com/lyraproj/semver v0.0.0-20181213164306-02ecea2cd6a2 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/stretchr/objx v0.1.0 // indirect
+	github.com/stretchr/testify v1.3.0 // indirect
+	golang.org/x/sync v0.0.0-20190423024810-112230192c58 // indirect
+	gopkg.in/check.v1 v0.0.0-20161208181325-20d25e280405 // indirect
+	gopkg.in/yaml.v3 v3.0.0-20190502103701-55513cacd4ae // indirect
+)
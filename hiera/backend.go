@@ -0,0 +1,80 @@
+package hiera
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+// data returns the data hash of the location. The hash is produced by the data_hash function of the location,
+// which is called with the options of the hierarchy level, the path of the location, and a lookup context.
+func (l *location) data(ic *invocation) px.OrderedMap {
+	return cached(l.backend+`:`+l.path, l.path, func() interface{} {
+		return l.callFunction(ic)
+	}).(px.OrderedMap)
+}
+
+func (l *location) callFunction(ic *invocation) (data px.OrderedMap) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(notFound); !ok {
+				panic(r)
+			}
+			data = px.EmptyMap
+		}
+	}()
+	f, _ := loadFunction(ic.c, l.backend)
+	options := l.options.Merge(types.WrapHash([]*types.HashEntry{types.WrapHashEntry2(`path`, types.WrapString(l.path))}))
+	return dataHash(l.path, f.Call(ic.c, nil, options, newLookupContext(ic, l.module, l.backend)))
+}
+
+func loadFunction(c px.Context, name string) (px.Function, bool) {
+	if f, ok := px.Load(c, px.NewTypedName(px.NsFunction, name)); ok {
+		return f.(px.Function), true
+	}
+	return nil, false
+}
+
+// ReadData reads the file at the given path and parses its content using the given function. The format
+// names the kind of content in errors. The parsed value must be a hash. An empty file yields an empty hash.
+func ReadData(path, format string, parse func(content []byte) px.Value) px.OrderedMap {
+	return dataHash(path, parseData(path, format, readFile(path), parse))
+}
+
+func parseData(path, format string, content []byte, parse func(content []byte) px.Value) (value px.Value) {
+	defer func() {
+		if r := recover(); r != nil {
+			err, ok := r.(error)
+			if !ok {
+				panic(r)
+			}
+			panic(px.Error(DataFileMalformed, issue.H{`format`: format, `path`: path, `detail`: err.Error()}))
+		}
+	}()
+	return parse(content)
+}
+
+func readFile(path string) []byte {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsPermission(err) {
+			panic(px.Error(px.FileReadDenied, issue.H{`path`: path}))
+		}
+		panic(px.Error(DataFileNotFound, issue.H{`path`: path}))
+	}
+	return content
+}
+
+// dataHash asserts that the given value is a hash. An empty file yields an empty hash.
+func dataHash(path string, value px.Value) px.OrderedMap {
+	if hash, ok := value.(px.OrderedMap); ok {
+		return hash
+	}
+	if value == px.Undef {
+		return px.EmptyMap
+	}
+	panic(px.Error(NotHash, issue.H{`path`: path}))
+}
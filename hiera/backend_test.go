@@ -0,0 +1,88 @@
+package hiera_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/puppet-evaluator/hiera"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+	"github.com/lyraproj/puppet-evaluator/puppet"
+)
+
+// readData calls the given data function with the given path
+func readData(c px.Context, function, path string) px.Value {
+	f, ok := px.Load(c, px.NewTypedName(px.NsFunction, function))
+	if !ok {
+		panic(px.Error(px.UnknownFunction, issue.H{`name`: function}))
+	}
+	options := types.WrapStringToInterfaceMap(c, map[string]interface{}{`path`: path})
+	return f.(px.Function).Call(c, nil, options, px.New(c, hiera.LookupContextType))
+}
+
+func TestDataFunctions(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		`data.yaml`:  "a: 1\nb: [x]\n",
+		`data.json`:  `{"a": 1, "b": ["x"]}`,
+		`data.conf`:  "a = 1\nb = [x]",
+		`empty.yaml`: "\n",
+	})
+	defer os.RemoveAll(dir)
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		for _, tt := range []struct {
+			function, file, expected string
+		}{
+			{`yaml_data`, `data.yaml`, `{'a' => 1, 'b' => ['x']}`},
+			{`json_data`, `data.json`, `{'a' => 1, 'b' => ['x']}`},
+			{`hocon_data`, `data.conf`, `{'a' => 1, 'b' => ['x']}`},
+			{`yaml_data`, `empty.yaml`, `{}`},
+		} {
+			if v := readData(c, tt.function, filepath.Join(dir, tt.file)); v.String() != tt.expected {
+				t.Errorf(`%s(%s): expected %s, got %s`, tt.function, tt.file, tt.expected, v)
+			}
+		}
+	})
+}
+
+func TestDataFunctionErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		`malformed.yaml`: "a: [1\n",
+		`malformed.json`: `{"a": `,
+		`malformed.conf`: `a = "x`,
+		`array.yaml`:     "- 1\n",
+		`array.json`:     `[1]`,
+		`scalar.yaml`:    "just a string\n",
+	})
+	defer os.RemoveAll(dir)
+	puppet.Do(func(c pdsl.EvaluationContext) {
+		for _, tt := range []struct {
+			function, file string
+			code           issue.Code
+		}{
+			// A missing file and malformed content are reported with different issues
+			{`yaml_data`, `missing.yaml`, hiera.DataFileNotFound},
+			{`json_data`, `missing.json`, hiera.DataFileNotFound},
+			{`hocon_data`, `missing.conf`, hiera.DataFileNotFound},
+			{`yaml_data`, `malformed.yaml`, hiera.DataFileMalformed},
+			{`json_data`, `malformed.json`, hiera.DataFileMalformed},
+			{`hocon_data`, `malformed.conf`, hiera.DataFileMalformed},
+
+			// Content that parses to something other than a hash
+			{`yaml_data`, `array.yaml`, hiera.NotHash},
+			{`json_data`, `array.json`, hiera.NotHash},
+			{`yaml_data`, `scalar.yaml`, hiera.NotHash},
+		} {
+			func() {
+				defer func() {
+					if r, ok := recover().(issue.Reported); !ok || r.Code() != tt.code {
+						t.Errorf(`%s(%s): expected %s, got %v`, tt.function, tt.file, tt.code, r)
+					}
+				}()
+				readData(c, tt.function, filepath.Join(dir, tt.file))
+			}()
+		}
+	})
+}
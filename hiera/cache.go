@@ -0,0 +1,42 @@
+package hiera
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+type cacheEntry struct {
+	modTime time.Time
+	size    int64
+	value   interface{}
+}
+
+// fileCache contains values that have been produced from files. An entry is reused for as long as its
+// file is unchanged.
+var fileCache = struct {
+	sync.Mutex
+	entries map[string]*cacheEntry
+}{entries: make(map[string]*cacheEntry)}
+
+// cached returns the value that is cached under the given key if the file at the given path hasn't changed
+// since the value was produced. Otherwise the value is produced by calling the given function and then
+// cached. Values are not cached when the file cannot be found.
+func cached(key, path string, produce func() interface{}) interface{} {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return produce()
+	}
+	fileCache.Lock()
+	ce, ok := fileCache.entries[key]
+	fileCache.Unlock()
+	if ok && ce.modTime.Equal(fi.ModTime()) && ce.size == fi.Size() {
+		return ce.value
+	}
+
+	value := produce()
+	fileCache.Lock()
+	fileCache.entries[key] = &cacheEntry{modTime: fi.ModTime(), size: fi.Size(), value: value}
+	fileCache.Unlock()
+	return value
+}
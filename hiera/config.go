@@ -0,0 +1,254 @@
+package hiera
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/pcore/yaml"
+)
+
+// ConfigFileName is the name of the hiera configuration file of an environment or a module
+const ConfigFileName = `hiera.yaml`
+
+const levelType = `Struct[{
+	name => String[1],
+	Optional[data_hash] => String[1],
+	Optional[lookup_key] => String[1],
+	Optional[data_dig] => String[1],
+	Optional[hiera3_backend] => String[1],
+	Optional[datadir] => String[1],
+	Optional[path] => String[1],
+	Optional[paths] => Array[String[1], 1],
+	Optional[glob] => String[1],
+	Optional[globs] => Array[String[1], 1],
+	Optional[mapped_paths] => Array[String[1], 3, 3],
+	Optional[uri] => String[1],
+	Optional[uris] => Array[String[1], 1],
+	Optional[options] => Hash[String[1], Any]
+}]`
+
+const configType = `Struct[{
+	version => Integer[5, 5],
+	Optional[defaults] => Struct[{
+		Optional[data_hash] => String[1],
+		Optional[lookup_key] => String[1],
+		Optional[data_dig] => String[1],
+		Optional[datadir] => String[1],
+		Optional[options] => Hash[String[1], Any]
+	}],
+	Optional[hierarchy] => Array[` + levelType + `],
+	Optional[default_hierarchy] => Array[` + levelType + `]
+}]`
+
+var dataProviderKeys = []string{`data_hash`, `lookup_key`, `data_dig`, `hiera3_backend`}
+
+var locationKeys = []string{`path`, `paths`, `glob`, `globs`, `mapped_paths`, `uri`, `uris`}
+
+type (
+	// config is a parsed hiera.yaml version 5
+	config struct {
+		path             string
+		hierarchy        []*level
+		defaultHierarchy []*level
+	}
+
+	// level is an entry in the hierarchy of a config
+	level struct {
+		name     string
+		module   string
+		backend  string
+		datadir  string
+		options  px.OrderedMap
+		location string
+		patterns []string
+	}
+
+	// location is a data file found by a hierarchy level
+	location struct {
+		module  string
+		backend string
+		path    string
+		options px.OrderedMap
+	}
+)
+
+// defaultEnvironmentConfig is used for environments that have no hiera.yaml
+var defaultEnvironmentConfig = types.WrapHash([]*types.HashEntry{
+	types.WrapHashEntry2(`version`, types.WrapInteger(5)),
+	types.WrapHashEntry2(`hierarchy`, types.WrapValues([]px.Value{
+		types.WrapHash([]*types.HashEntry{
+			types.WrapHashEntry2(`name`, types.WrapString(`Common`)),
+			types.WrapHashEntry2(`path`, types.WrapString(`common.yaml`))})}))})
+
+// readConfig reads the hiera.yaml at the given path. The module is the name of the module that the config
+// belongs to or an empty string. A nil config is returned when no such file exists.
+func readConfig(c px.Context, path, module string) *config {
+	var data px.Value
+	content, err := ioutil.ReadFile(path)
+	switch {
+	case err == nil:
+		data = yaml.Unmarshal(c, content)
+	case os.IsNotExist(err):
+		return nil
+	case os.IsPermission(err):
+		panic(px.Error(px.FileReadDenied, issue.H{`path`: path}))
+	default:
+		panic(err)
+	}
+	return parseConfig(c, path, module, data)
+}
+
+// parseConfig validates the given data and creates a config from it
+func parseConfig(c px.Context, path, module string, data px.Value) *config {
+	px.AssertInstance(func() string { return path }, c.ParseType(configType), data)
+	hash := data.(px.OrderedMap)
+
+	dir := filepath.Dir(path)
+	defaults := hash.Get5(`defaults`, px.EmptyMap).(px.OrderedMap)
+	defaultBackend := dataProvider(c, path, `defaults`, defaults, `yaml_data`)
+	defaultDatadir := defaults.Get5(`datadir`, types.WrapString(`data`)).String()
+	defaultOptions := defaults.Get5(`options`, px.EmptyMap).(px.OrderedMap)
+
+	parseLevels := func(key string) []*level {
+		levels := make([]*level, 0)
+		hash.Get5(key, px.EmptyArray).(px.List).Each(func(lv px.Value) {
+			lh := lv.(px.OrderedMap)
+			name := lh.Get5(`name`, px.EmptyString).String()
+			l := &level{
+				name:    name,
+				module:  module,
+				backend: dataProvider(c, path, name, lh, defaultBackend),
+				datadir: lh.Get5(`datadir`, types.WrapString(defaultDatadir)).String(),
+				options: lh.Get5(`options`, defaultOptions).(px.OrderedMap)}
+			if !filepath.IsAbs(l.datadir) {
+				l.datadir = filepath.Join(dir, l.datadir)
+			}
+
+			for _, key := range locationKeys {
+				v, ok := lh.Get4(key)
+				if !ok {
+					continue
+				}
+				if l.location != `` {
+					panic(px.Error(MultipleLocations, issue.H{`name`: name, `path`: path}))
+				}
+				l.location = key
+				if a, ok := v.(*types.Array); ok {
+					l.patterns = make([]string, a.Len())
+					a.EachWithIndex(func(e px.Value, i int) { l.patterns[i] = e.String() })
+				} else {
+					l.patterns = []string{v.String()}
+				}
+			}
+			if l.location == `uri` || l.location == `uris` {
+				panic(px.Error(UnsupportedDataProvider,
+					issue.H{`name`: name, `path`: path, `provider`: `data_hash`, `function`: l.backend}))
+			}
+			levels = append(levels, l)
+		})
+		return levels
+	}
+	return &config{path: path, hierarchy: parseLevels(`hierarchy`), defaultHierarchy: parseLevels(`default_hierarchy`)}
+}
+
+// dataProvider returns the name of the data_hash function that is used by the given hierarchy level or
+// defaults. The given default is returned when the level doesn't declare a data provider.
+func dataProvider(c px.Context, path, name string, lh px.OrderedMap, dflt string) string {
+	provider := ``
+	function := dflt
+	for _, key := range dataProviderKeys {
+		if v, ok := lh.Get4(key); ok {
+			if provider != `` {
+				panic(px.Error(MultipleDataProviders, issue.H{`name`: name, `path`: path}))
+			}
+			provider = key
+			function = v.String()
+		}
+	}
+	if provider == `` {
+		return function
+	}
+	if provider != `data_hash` {
+		panic(px.Error(UnsupportedDataProvider, issue.H{`name`: name, `path`: path, `provider`: provider, `function`: function}))
+	}
+	if _, ok := loadFunction(c, function); !ok {
+		panic(px.Error(FunctionNotFound, issue.H{`name`: name, `path`: path, `provider`: provider, `function`: function}))
+	}
+	return function
+}
+
+// locations returns the existing data files of the given hierarchy levels in order of precedence. Variables in
+// the paths are interpolated using the given invocation.
+func locations(ic *invocation, hierarchy []*level) []*location {
+	result := make([]*location, 0, len(hierarchy))
+	for _, l := range hierarchy {
+		for _, path := range l.paths(ic) {
+			if fi, err := os.Stat(path); err == nil && !fi.IsDir() {
+				result = append(result, &location{module: l.module, backend: l.backend, path: path, options: l.options})
+			}
+		}
+	}
+	return result
+}
+
+// paths returns the absolute paths of the data files of the level. Paths produced by globs are
+// only returned if they exist.
+func (l *level) paths(ic *invocation) []string {
+	switch l.location {
+	case `path`, `paths`:
+		paths := make([]string, len(l.patterns))
+		for i, p := range l.patterns {
+			paths[i] = l.resolve(ic.interpolatePath(p, nil))
+		}
+		return paths
+	case `glob`, `globs`:
+		paths := make([]string, 0)
+		for _, p := range l.patterns {
+			matches, _ := filepath.Glob(l.resolve(ic.interpolatePath(p, nil)))
+			sort.Strings(matches)
+			paths = append(paths, matches...)
+		}
+		return paths
+	case `mapped_paths`:
+		return l.mappedPaths(ic)
+	}
+	return nil
+}
+
+// mappedPaths returns one path for each element of the variable named by the first element of the
+// mapped_paths. The element is assigned to the key named by the second element when the template given by
+// the third element is interpolated.
+func (l *level) mappedPaths(ic *invocation) []string {
+	key := l.patterns[1]
+	template := l.patterns[2]
+	paths := make([]string, 0)
+	add := func(v px.Value) {
+		paths = append(paths, l.resolve(ic.interpolatePath(template, func(name string) (px.Value, bool) {
+			if name == key {
+				return v, true
+			}
+			return nil, false
+		})))
+	}
+	switch v := ic.variable(l.patterns[0]).(type) {
+	case *types.Array:
+		v.Each(add)
+	case *types.Hash:
+		v.EachKey(add)
+	case px.StringValue:
+		add(v)
+	}
+	return paths
+}
+
+func (l *level) resolve(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(l.datadir, path)
+}
@@ -0,0 +1,236 @@
+// Package hiera implements Hiera 5 data binding. Data is looked up using the hierarchies declared in the
+// global hiera.yaml, the hiera.yaml of the environment, and the hiera.yaml of the module that a key
+// belongs to, in that order.
+package hiera
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/pcore"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+)
+
+// ConfigSetting is the name of the setting that holds the path to the global hiera.yaml
+const ConfigSetting = `hiera_config`
+
+func init() {
+	pcore.DefineSetting(ConfigSetting, types.DefaultStringType(), nil)
+}
+
+// invocation holds the state of a lookup, including nested lookups performed by interpolation
+type invocation struct {
+	c     px.Context
+	names []string
+}
+
+// Lookup looks up the value for the given key. The key may contain dots to dig into the found value. The
+// given merge is the name of a merge strategy or a hash with a 'strategy' key and strategy options. When
+// merge is nil or undef, the merge given by the lookup_options for the key is used, or the first found
+// strategy when no such option exists.
+//
+// The found value is returned together with true, or nil and false when no value was found.
+func Lookup(c px.Context, key string, merge px.Value) (px.Value, bool) {
+	return (&invocation{c: c}).lookup(key, merge)
+}
+
+// LookupParameters performs automatic parameter lookup for the class, plan, or other named entity with the
+// given parameters. A value is looked up using the key <name>::<parameter name> for each parameter that has
+// no value in the given arguments. The returned map contains the given arguments and all found values.
+func LookupParameters(c px.Context, name string, parameters []px.Parameter, args px.OrderedMap) px.OrderedMap {
+	name = strings.ToLower(strings.TrimPrefix(name, `::`))
+	found := make([]*types.HashEntry, 0)
+	for _, p := range parameters {
+		if args.IncludesKey2(p.Name()) {
+			continue
+		}
+		if v, ok := Lookup(c, name+`::`+p.Name(), nil); ok {
+			found = append(found, types.WrapHashEntry2(p.Name(), v))
+		}
+	}
+	if len(found) == 0 {
+		return args
+	}
+	return args.Merge(types.WrapHash(found))
+}
+
+func (ic *invocation) lookup(key string, merge px.Value) (px.Value, bool) {
+	for _, name := range ic.names {
+		if name == key {
+			panic(px.Error(EndlessRecursion, issue.H{`name_stack`: strings.Join(append(ic.names, key), `, `)}))
+		}
+	}
+	ic.names = append(ic.names, key)
+	defer func() { ic.names = ic.names[:len(ic.names)-1] }()
+
+	segments := splitKey(key)
+	root := segments[0]
+	layers := ic.layers(root)
+	var options px.OrderedMap
+	if root != `lookup_options` {
+		options = ic.lookupOptions(layers, root)
+	}
+	if (merge == nil || merge == px.Undef) && options != nil {
+		merge = options.Get5(`merge`, px.Undef)
+	}
+
+	value, ok := ic.lookupIn(layers, root, segments[1:], newMergeStrategy(merge))
+	if ok && len(segments) == 1 && options != nil {
+		if ct, ok := options.Get4(`convert_to`); ok {
+			value = convert(ic.c, ct, value)
+		}
+	}
+	return value, ok
+}
+
+// lookupIn looks up the given key in the hierarchies of the given configs and digs into each found value
+// using the given segments. The values are combined using the given merge strategy. The default hierarchies
+// are consulted only when no value is found in the hierarchies.
+func (ic *invocation) lookupIn(layers []*config, key string, segments []string, ms *mergeStrategy) (px.Value, bool) {
+	values := make([]px.Value, 0)
+	collect := func(hierarchy []*level) bool {
+		for _, l := range locations(ic, hierarchy) {
+			v, ok := l.data(ic).Get4(key)
+			if !ok {
+				continue
+			}
+			if v, ok = dig(ic.interpolate(v), segments); ok {
+				values = append(values, v)
+				if ms.name == `first` {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	for _, cfg := range layers {
+		if collect(cfg.hierarchy) {
+			break
+		}
+	}
+	if len(values) == 0 {
+		for _, cfg := range layers {
+			if collect(cfg.defaultHierarchy) {
+				break
+			}
+		}
+	}
+	if len(values) == 0 {
+		return nil, false
+	}
+	return ms.merge(values), true
+}
+
+// lookupOptions returns the lookup options for the given key. The options are found in the deep merge of
+// all lookup_options hashes, either under the key itself or under a key that starts with '^' and is a
+// regular expression that matches the key. The regular expression uses the syntax selected by the
+// ruby_regexp setting.
+func (ic *invocation) lookupOptions(layers []*config, key string) px.OrderedMap {
+	v, ok := ic.lookupIn(layers, `lookup_options`, nil, &mergeStrategy{name: `deep`})
+	if !ok {
+		return nil
+	}
+	all, ok := v.(*types.Hash)
+	if !ok {
+		return nil
+	}
+	if options, ok := all.Get4(key); ok {
+		options, _ := options.(px.OrderedMap)
+		return options
+	}
+	var options px.OrderedMap
+	all.Find(func(e px.Value) bool {
+		entry := e.(px.MapEntry)
+		pattern := entry.Key().String()
+		if !strings.HasPrefix(pattern, `^`) {
+			return false
+		}
+		if rx, err := pdsl.CompileRegexp(pattern); err == nil && rx.MatchString(key) {
+			options, _ = entry.Value().(px.OrderedMap)
+			return true
+		}
+		return false
+	})
+	return options
+}
+
+// layers returns the configs that apply to the given key. They are the global config, the config of the
+// environment, and, when the key is in a module namespace, the config of that module.
+func (ic *invocation) layers(key string) []*config {
+	layers := make([]*config, 0, 3)
+	add := func(cfg *config) {
+		if cfg != nil {
+			layers = append(layers, cfg)
+		}
+	}
+	if path := setting(ConfigSetting); path != `` {
+		add(ic.config(path, ``, nil))
+	}
+	if dir := environmentDir(ic.c); dir != `` {
+		add(ic.config(filepath.Join(dir, ConfigFileName), ``, defaultEnvironmentConfig))
+	}
+	if i := strings.Index(key, `::`); i > 0 {
+		module := key[:i]
+		if dir := pdsl.ModuleDir(ic.c, module); dir != `` {
+			add(ic.config(filepath.Join(dir, ConfigFileName), module, nil))
+		}
+	}
+	return layers
+}
+
+// config returns the config read from the given path. The module is the name of the module that the config
+// belongs to or an empty string. The given default, when not nil, is used when no such file exists.
+func (ic *invocation) config(path, module string, dflt px.OrderedMap) *config {
+	if _, err := os.Stat(path); err != nil && dflt != nil {
+		// The default config is cached for as long as the directory is unchanged
+		return cached(`default:`+path, filepath.Dir(path), func() interface{} {
+			return parseConfig(ic.c, path, module, dflt)
+		}).(*config)
+	}
+	return cached(`config:`+path, path, func() interface{} {
+		return readConfig(ic.c, path, module)
+	}).(*config)
+}
+
+// environmentDir returns the directory of the current environment. It is the directory held by the context
+// variable pdsl.EnvironmentDirKey, or when no such variable exists, the directory given by the environmentpath
+// and environment settings. An empty string is returned if neither is set.
+func environmentDir(c px.Context) string {
+	if dir, ok := c.Get(pdsl.EnvironmentDirKey); ok {
+		return dir.(string)
+	}
+	path := setting(`environmentpath`)
+	if path == `` {
+		return ``
+	}
+	return filepath.Join(path, setting(`environment`))
+}
+
+func setting(name string) string {
+	v := pcore.Get(name, nil)
+	if v == px.Undef {
+		return ``
+	}
+	return v.String()
+}
+
+// convert converts the given value using the convert_to lookup option, which is either a type or an array
+// with a type followed by additional arguments to the type's constructor
+func convert(c px.Context, convertTo px.Value, value px.Value) px.Value {
+	args := []px.Value{nil, value}
+	if a, ok := convertTo.(*types.Array); ok && a.Len() > 0 {
+		convertTo = a.At(0)
+		args = a.Slice(1, a.Len()).AppendTo(args)
+	}
+	t, ok := convertTo.(px.Type)
+	if !ok {
+		t = c.ParseTypeValue(convertTo)
+	}
+	args[0] = t
+	return px.Call(c, `new`, args, nil)
+}
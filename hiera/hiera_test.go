@@ -0,0 +1,247 @@
+package hiera_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lyraproj/pcore/pcore"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/puppet-evaluator/hiera"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+	"github.com/lyraproj/puppet-evaluator/puppet"
+)
+
+// writeFiles creates a temporary directory that contains the given files and returns its path. The keys
+// are slash separated paths relative to the directory.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir(``, `hiera`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err = ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// layers are a global, an environment, and a module layer. The environment has a hierarchy with a level
+// that is interpolated using the certname of the node.
+var layers = map[string]string{
+	`global/hiera.yaml`: `
+version: 5
+defaults:
+  datadir: gdata
+hierarchy:
+  - name: Global
+    path: global.yaml
+`,
+	`global/gdata/global.yaml`: `
+a: global
+arr: [g1]
+h: {g: 1, shared: global}
+deep: {x: {g: 1}, list: [g]}
+rx::list: [g]
+rb::ff: [g]
+lookup_options:
+  arr:
+    merge: unique
+`,
+	`env/hiera.yaml`: `
+version: 5
+hierarchy:
+  - name: Nodes
+    path: "nodes/%{trusted.certname}.yaml"
+  - name: Common
+    path: common.yaml
+`,
+	`env/data/nodes/web01.example.com.yaml`: `
+b: node
+arr: [n1, c1]
+h: {n: 1, shared: node}
+deep: {x: {n: 1}, list: [n]}
+`,
+	`env/data/common.yaml`: `
+a: environment
+b: common
+arr: [c1, c2]
+h: {c: 1, shared: common}
+deep: {x: {c: 1}, list: [c]}
+rx::list: [c]
+rb::ff: [c]
+mod::y: environment
+mod::list: [e]
+conv: '42'
+interpolated: "%{lookup('b')} and %{trusted.hostname}"
+lookup_options:
+  '^rx::':
+    merge: unique
+  '^rb::\h+$':
+    merge: unique
+  conv:
+    convert_to: Integer
+`,
+	`env/modules/mod/hiera.yaml`: `
+version: 5
+hierarchy:
+  - name: Common
+    path: common.yaml
+`,
+	`env/modules/mod/data/common.yaml`: `
+mod::x: module
+mod::y: module
+mod::list: [m]
+mod::param: from hiera
+lookup_options:
+  mod::list:
+    merge: unique
+`,
+	`env/modules/mod/manifests/init.pp`: `class mod(String $param = 'default', String $other = 'default') {}`,
+}
+
+// withLayers runs the given function in the environment of the layers
+func withLayers(t *testing.T, f func(c pdsl.EvaluationContext)) {
+	t.Helper()
+	dir := writeFiles(t, layers)
+	defer os.RemoveAll(dir)
+	pcore.Set(hiera.ConfigSetting, types.WrapString(filepath.Join(dir, `global`, `hiera.yaml`)))
+	defer pcore.Set(hiera.ConfigSetting, types.WrapString(``))
+	puppet.DoWithEnvironment(filepath.Join(dir, `env`), func(c pdsl.EvaluationContext) {
+		c.SetFacts(types.WrapStringToInterfaceMap(c, map[string]interface{}{`clientcert`: `web01.example.com`}), nil, false)
+		f(c)
+	})
+}
+
+// expectLookups asserts that each key, optionally followed by a space and a merge strategy, is found with the
+// expected value. A nil value asserts that the key is not found.
+func expectLookups(t *testing.T, c px.Context, expected map[string]interface{}) {
+	t.Helper()
+	for key, e := range expected {
+		var merge px.Value
+		for i := range key {
+			if key[i] == ' ' {
+				key, merge = key[:i], types.WrapString(key[i+1:])
+				break
+			}
+		}
+		v, ok := hiera.Lookup(c, key, merge)
+		if e == nil {
+			if ok {
+				t.Errorf(`%s: expected no value, got %s`, key, v)
+			}
+			continue
+		}
+		ev := px.Wrap(c, e)
+		if !ok || !ev.Equals(v, nil) {
+			t.Errorf(`%s %v: expected %s, got %v`, key, merge, ev, v)
+		}
+	}
+}
+
+func TestLayers(t *testing.T) {
+	withLayers(t, func(c pdsl.EvaluationContext) {
+		expectLookups(t, c, map[string]interface{}{
+			// The global layer has precedence over the environment layer, which has precedence over the
+			// module layer
+			`a`:      `global`,
+			`b`:      `node`,
+			`mod::x`: `module`,
+			`mod::y`: `environment`,
+
+			// Data of a module is only consulted for keys in the module's namespace
+			`mod::param`: `from hiera`,
+			`param`:      nil,
+			`missing`:    nil,
+
+			// Dotted keys dig into the found value
+			`h.shared`:     `global`,
+			`deep.x.n`:     1,
+			`h.missing`:    nil,
+			`interpolated`: `node and web01`,
+		})
+	})
+}
+
+func TestMerges(t *testing.T) {
+	withLayers(t, func(c pdsl.EvaluationContext) {
+		expectLookups(t, c, map[string]interface{}{
+			`arr first`:  []string{`g1`},
+			`arr unique`: []string{`g1`, `n1`, `c1`, `c2`},
+			`h first`:    map[string]interface{}{`g`: 1, `shared`: `global`},
+			`h hash`:     map[string]interface{}{`g`: 1, `n`: 1, `c`: 1, `shared`: `global`},
+			`deep hash`:  map[string]interface{}{`x`: map[string]interface{}{`g`: 1}, `list`: []string{`g`}},
+			`deep deep`: map[string]interface{}{
+				`x`:    map[string]interface{}{`g`: 1, `n`: 1, `c`: 1},
+				`list`: []string{`g`, `n`, `c`}},
+		})
+		v, _ := hiera.Lookup(c, `h`, types.WrapStringToInterfaceMap(c, map[string]interface{}{`strategy`: `deep`}))
+		if s := v.(px.OrderedMap).Get5(`shared`, px.Undef).String(); s != `global` {
+			t.Errorf(`expected the value with the highest precedence to win, got %s`, s)
+		}
+	})
+}
+
+func TestLookupOptions(t *testing.T) {
+	withLayers(t, func(c pdsl.EvaluationContext) {
+		expectLookups(t, c, map[string]interface{}{
+			// Options declared for the key in the global layer
+			`arr`: []string{`g1`, `n1`, `c1`, `c2`},
+
+			// Options declared using a regular expression
+			`rx::list`: []string{`g`, `c`},
+
+			// Options declared in the module layer
+			`mod::list`: []string{`e`, `m`},
+
+			// An explicit merge overrides the options
+			`rx::list first`: []string{`g`},
+
+			`conv`: 42,
+		})
+
+		// \h is not valid in the syntax of the Go regexp package
+		expectLookups(t, c, map[string]interface{}{`rb::ff`: []string{`g`}})
+	})
+}
+
+func TestLookupOptionsRubyRegexp(t *testing.T) {
+	pcore.Set(pdsl.RubyRegexpSetting, types.WrapBoolean(true))
+	defer pcore.Set(pdsl.RubyRegexpSetting, types.WrapBoolean(false))
+	withLayers(t, func(c pdsl.EvaluationContext) {
+		expectLookups(t, c, map[string]interface{}{
+			`rb::ff`:   []string{`g`, `c`},
+			`rx::list`: []string{`g`, `c`},
+		})
+	})
+}
+
+func TestLookupParameters(t *testing.T) {
+	withLayers(t, func(c pdsl.EvaluationContext) {
+		params := []px.Parameter{
+			px.NewParameter(`param`, types.DefaultStringType(), nil, false),
+			px.NewParameter(`other`, types.DefaultStringType(), nil, false),
+			px.NewParameter(`x`, types.DefaultStringType(), nil, false),
+		}
+		given := types.WrapStringToInterfaceMap(c, map[string]interface{}{`x`: `given`})
+		found := hiera.LookupParameters(c, `::Mod`, params, given)
+		expected := types.WrapStringToInterfaceMap(c, map[string]interface{}{`x`: `given`, `param`: `from hiera`})
+		if !expected.Equals(found, nil) {
+			t.Errorf(`expected %s, got %s`, expected, found)
+		}
+
+		// Automatic parameter lookup when a class is included
+		expr := c.ParseAndValidate(`test.pp`, `include mod [$mod::param, $mod::other]`, false)
+		if v := pdsl.TopEvaluate(c, expr); v.String() != `['from hiera', 'default']` {
+			t.Errorf(`expected ['from hiera', 'default'], got %s`, v)
+		}
+	})
+}
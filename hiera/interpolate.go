@@ -0,0 +1,172 @@
+package hiera
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+var interpolationPattern = regexp.MustCompile(`%\{[^}]*\}`)
+
+var methodPattern = regexp.MustCompile(`\A(\w+)\(\s*(?:'([^']*)'|"([^"]*)")\s*\)\z`)
+
+// interpolate returns the given value with all interpolation expressions in strings replaced. Arrays and
+// hashes are interpolated recursively.
+func (ic *invocation) interpolate(value px.Value) px.Value {
+	switch v := value.(type) {
+	case px.StringValue:
+		return ic.interpolateString(v.String(), true, nil)
+	case *types.Array:
+		return v.Map(ic.interpolate)
+	case *types.Hash:
+		entries := make([]*types.HashEntry, 0, v.Len())
+		v.EachPair(func(k, e px.Value) {
+			entries = append(entries, types.WrapHashEntry(ic.interpolate(k), ic.interpolate(e)))
+		})
+		return types.WrapHash(entries)
+	}
+	return value
+}
+
+// interpolatePath interpolates the variables in the given path of a hierarchy level. The given function,
+// when not nil, is consulted before variables are looked up in the scope.
+func (ic *invocation) interpolatePath(path string, vars func(name string) (px.Value, bool)) string {
+	return ic.interpolateString(path, false, vars).String()
+}
+
+// interpolateString replaces all interpolation expressions in the given string. An expression is either a
+// variable name, optionally followed by dot separated keys, or a call to one of the methods alias, hiera,
+// literal, lookup, and scope. A string that consists of one alias call is replaced by the looked up value.
+func (ic *invocation) interpolateString(s string, allowMethods bool, vars func(name string) (px.Value, bool)) px.Value {
+	if !strings.Contains(s, `%{`) {
+		return types.WrapString(s)
+	}
+	var alias px.Value
+	result := interpolationPattern.ReplaceAllStringFunc(s, func(match string) string {
+		expr := strings.TrimSpace(match[2 : len(match)-1])
+		m := methodPattern.FindStringSubmatch(expr)
+		if m == nil {
+			return stringify(ic.scopeVariable(expr, vars))
+		}
+		if !allowMethods {
+			panic(px.Error(InterpolationMethodSyntaxNotAllowed, issue.NoArgs))
+		}
+		arg := m[2] + m[3]
+		switch m[1] {
+		case `alias`:
+			if match != s {
+				panic(px.Error(InterpolationAliasNotEntireString, issue.NoArgs))
+			}
+			alias, _ = ic.lookup(arg, nil)
+			return ``
+		case `hiera`, `lookup`:
+			v, _ := ic.lookup(arg, nil)
+			return stringify(v)
+		case `literal`:
+			return arg
+		case `scope`:
+			return stringify(ic.scopeVariable(arg, vars))
+		default:
+			panic(px.Error(UnknownInterpolationMethod, issue.H{`name`: m[1]}))
+		}
+	})
+	if alias != nil {
+		return alias
+	}
+	return types.WrapString(result)
+}
+
+// scopeVariable returns the value of the given variable. The name may be followed by dot separated keys
+// that are used to dig into the value. The given function, when not nil, is consulted before the scope.
+func (ic *invocation) scopeVariable(expr string, vars func(name string) (px.Value, bool)) px.Value {
+	segments := splitKey(expr)
+	if vars != nil {
+		if v, ok := vars(segments[0]); ok {
+			if v, ok = dig(v, segments[1:]); ok {
+				return v
+			}
+			return px.Undef
+		}
+	}
+	if v, ok := dig(ic.variable(segments[0]), segments[1:]); ok {
+		return v
+	}
+	return px.Undef
+}
+
+// variable returns the value of the given variable in the current scope or undef if no such variable exists
+func (ic *invocation) variable(name string) px.Value {
+	scope := ic.c.Scope()
+	if v, ok := scope.Get(types.WrapString(name)); ok {
+		return v
+	}
+	if !strings.HasPrefix(name, `::`) {
+		// Qualified variables are found in the global scope
+		if v, ok := scope.Get(types.WrapString(`::` + name)); ok {
+			return v
+		}
+	}
+	return px.Undef
+}
+
+// splitKey splits the given key into dot separated segments. A segment can be quoted using single or double
+// quotes in order to contain dots.
+func splitKey(key string) []string {
+	if !strings.ContainsAny(key, `.'"`) {
+		return []string{key}
+	}
+	segments := make([]string, 0)
+	b := strings.Builder{}
+	var quote rune
+	for _, r := range key {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			b.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '.':
+			segments = append(segments, b.String())
+			b.Reset()
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return append(segments, b.String())
+}
+
+// dig returns the value found by using the given segments as successive keys into the given value. An
+// Array is indexed using segments that are integers.
+func dig(value px.Value, segments []string) (px.Value, bool) {
+	for _, segment := range segments {
+		switch v := value.(type) {
+		case *types.Hash:
+			var ok bool
+			if value, ok = v.Get4(segment); !ok {
+				return nil, false
+			}
+		case *types.Array:
+			i, err := strconv.Atoi(segment)
+			if err != nil || i < 0 || i >= v.Len() {
+				return nil, false
+			}
+			value = v.At(i)
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// stringify returns the string that is used when the given value is interpolated
+func stringify(value px.Value) string {
+	if value == nil || value == px.Undef {
+		return ``
+	}
+	return value.String()
+}
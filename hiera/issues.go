@@ -0,0 +1,56 @@
+package hiera
+
+import "github.com/lyraproj/issue/issue"
+
+const (
+	DataFileMalformed                   = `HIERA_DATA_FILE_MALFORMED`
+	DataFileNotFound                    = `HIERA_DATA_FILE_NOT_FOUND`
+	EndlessRecursion                    = `HIERA_ENDLESS_RECURSION`
+	FunctionNotFound                    = `HIERA_FUNCTION_NOT_FOUND`
+	IllegalMergeValue                   = `HIERA_ILLEGAL_MERGE_VALUE`
+	InterpolationAliasNotEntireString   = `HIERA_INTERPOLATION_ALIAS_NOT_ENTIRE_STRING`
+	InterpolationMethodSyntaxNotAllowed = `HIERA_INTERPOLATION_METHOD_SYNTAX_NOT_ALLOWED`
+	MultipleDataProviders               = `HIERA_MULTIPLE_DATA_PROVIDERS`
+	MultipleLocations                   = `HIERA_MULTIPLE_LOCATIONS`
+	NameNotFound                        = `HIERA_NAME_NOT_FOUND`
+	NotHash                             = `HIERA_NOT_HASH`
+	UnknownInterpolationMethod          = `HIERA_UNKNOWN_INTERPOLATION_METHOD`
+	UnknownMergeStrategy                = `HIERA_UNKNOWN_MERGE_STRATEGY`
+	UnsupportedDataProvider             = `HIERA_UNSUPPORTED_DATA_PROVIDER`
+)
+
+func init() {
+	issue.Hard(DataFileMalformed, `Unable to parse the %{format} data in '%{path}': %{detail}`)
+
+	issue.Hard(DataFileNotFound, `Unable to find the data file '%{path}'`)
+
+	issue.Hard(EndlessRecursion, `Recursive lookup detected in [%{name_stack}]`)
+
+	issue.Hard(FunctionNotFound,
+		`The hierarchy level '%{name}' in %{path} uses the %{provider} function '%{function}' which cannot be found`)
+
+	issue.Hard2(IllegalMergeValue, `The %{strategy} merge strategy cannot merge %{value}`,
+		issue.HF{`value`: issue.AnOrA})
+
+	issue.Hard(InterpolationAliasNotEntireString,
+		`'alias' interpolation is only permitted if the expression is equal to the entire string`)
+
+	issue.Hard(InterpolationMethodSyntaxNotAllowed, `Interpolation using method syntax is not allowed in this context`)
+
+	issue.Hard(MultipleDataProviders,
+		`The hierarchy level '%{name}' in %{path} can only use one of the data providers data_hash, lookup_key, or data_dig`)
+
+	issue.Hard(MultipleLocations,
+		`The hierarchy level '%{name}' in %{path} can only use one of path, paths, glob, globs, and mapped_paths`)
+
+	issue.Hard(NameNotFound, `lookup() did not find a value for the name '%{name}'`)
+
+	issue.Hard(NotHash, `The data in '%{path}' must be a Hash`)
+
+	issue.Hard(UnknownInterpolationMethod, `Unknown interpolation method '%{name}'`)
+
+	issue.Hard(UnknownMergeStrategy, `Unknown merge strategy '%{name}'`)
+
+	issue.Hard(UnsupportedDataProvider,
+		`The hierarchy level '%{name}' in %{path} uses the %{provider} data provider '%{function}' which is not supported`)
+}
@@ -0,0 +1,157 @@
+package hiera
+
+import (
+	"io"
+	"sync"
+
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+// LookupContextType is the type of the context that is passed to data_hash functions. It gives the
+// function access to interpolation and to a cache that is shared by all calls to the same function.
+var LookupContextType px.ObjectType
+
+func init() {
+	LookupContextType = px.NewObjectType(`LookupContext`, `{
+	attributes => {
+		environment_name => { type => String, kind => derived },
+		module_name => { type => Optional[String], kind => derived }
+	},
+	functions => {
+		not_found => Callable[[0, 0], Undef],
+		interpolate => Callable[[Any], Any],
+		cache => Callable[[Any, Any], Any],
+		cache_all => Callable[[Hash], Undef],
+		cache_has_key => Callable[[Any], Boolean],
+		cached_value => Callable[[Any], Any],
+		cached_entries => Callable[[0, 0], Hash]
+	}
+}`)
+}
+
+// notFound is raised by the not_found function of the lookup context
+type notFound struct{}
+
+type functionCache struct {
+	sync.Mutex
+	entries map[string]*types.HashEntry
+	keys    []string
+}
+
+// functionCaches contains one cache per data_hash function name
+var functionCaches = struct {
+	sync.Mutex
+	caches map[string]*functionCache
+}{caches: make(map[string]*functionCache)}
+
+type lookupContext struct {
+	ic     *invocation
+	module string
+	cache  *functionCache
+}
+
+func newLookupContext(ic *invocation, module, function string) *lookupContext {
+	functionCaches.Lock()
+	cache, ok := functionCaches.caches[function]
+	if !ok {
+		cache = &functionCache{entries: make(map[string]*types.HashEntry)}
+		functionCaches.caches[function] = cache
+	}
+	functionCaches.Unlock()
+	return &lookupContext{ic: ic, module: module, cache: cache}
+}
+
+func (lc *lookupContext) Call(c px.Context, method px.ObjFunc, args []px.Value, block px.Lambda) (px.Value, bool) {
+	switch method.Name() {
+	case `not_found`:
+		panic(notFound{})
+	case `interpolate`:
+		return lc.ic.interpolate(args[0]), true
+	case `cache`:
+		lc.cache.put(args[0], args[1])
+		return args[1], true
+	case `cache_all`:
+		args[0].(px.OrderedMap).EachPair(lc.cache.put)
+		return px.Undef, true
+	case `cache_has_key`:
+		_, ok := lc.cache.get(args[0])
+		return types.WrapBoolean(ok), true
+	case `cached_value`:
+		if v, ok := lc.cache.get(args[0]); ok {
+			return v, true
+		}
+		return px.Undef, true
+	case `cached_entries`:
+		return lc.cache.all(), true
+	}
+	return nil, false
+}
+
+func (lc *lookupContext) Get(key string) (px.Value, bool) {
+	switch key {
+	case `environment_name`:
+		return types.WrapString(setting(`environment`)), true
+	case `module_name`:
+		if lc.module == `` {
+			return px.Undef, true
+		}
+		return types.WrapString(lc.module), true
+	}
+	return nil, false
+}
+
+func (lc *lookupContext) InitHash() px.OrderedMap {
+	return LookupContextType.InstanceHash(lc)
+}
+
+func (lc *lookupContext) Equals(o interface{}, g px.Guard) bool {
+	return lc == o
+}
+
+func (lc *lookupContext) PType() px.Type {
+	return LookupContextType
+}
+
+func (lc *lookupContext) String() string {
+	return px.ToString(lc)
+}
+
+func (lc *lookupContext) ToString(b io.Writer, s px.FormatContext, g px.RDetect) {
+	types.ObjectToString(lc, s, b, g)
+}
+
+func (fc *functionCache) put(key, value px.Value) {
+	k := cacheKey(key)
+	fc.Lock()
+	if _, ok := fc.entries[k]; !ok {
+		fc.keys = append(fc.keys, k)
+	}
+	fc.entries[k] = types.WrapHashEntry(key, value)
+	fc.Unlock()
+}
+
+func (fc *functionCache) get(key px.Value) (px.Value, bool) {
+	fc.Lock()
+	defer fc.Unlock()
+	if e, ok := fc.entries[cacheKey(key)]; ok {
+		return e.Value(), true
+	}
+	return nil, false
+}
+
+func (fc *functionCache) all() px.OrderedMap {
+	fc.Lock()
+	defer fc.Unlock()
+	entries := make([]*types.HashEntry, len(fc.keys))
+	for i, k := range fc.keys {
+		entries[i] = fc.entries[k]
+	}
+	return types.WrapHash(entries)
+}
+
+// cacheKey returns a string that is unique for the given key. It includes the name of the type so that,
+// e.g., 1 and '1' are different keys.
+func cacheKey(key px.Value) string {
+	return key.PType().Name() + `:` + key.String()
+}
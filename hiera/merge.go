@@ -0,0 +1,182 @@
+package hiera
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+// mergeStrategy determines how the values found for a key in different locations are combined
+type mergeStrategy struct {
+	name             string
+	knockoutPrefix   string
+	sortMergedArrays bool
+	mergeHashArrays  bool
+}
+
+var firstFound = &mergeStrategy{name: `first`}
+
+// newMergeStrategy creates a merge strategy from the given merge value, which is either the name of the
+// strategy or a hash with the key 'strategy' and the options of the strategy. The first found strategy is
+// returned when the value is nil or undef.
+func newMergeStrategy(merge px.Value) *mergeStrategy {
+	switch merge := merge.(type) {
+	case nil:
+		return firstFound
+	case px.StringValue:
+		return newMergeStrategy(types.WrapHash([]*types.HashEntry{types.WrapHashEntry2(`strategy`, merge)}))
+	case px.OrderedMap:
+		name := merge.Get5(`strategy`, px.Undef).String()
+		switch name {
+		case `first`, `unique`, `hash`, `deep`:
+			return &mergeStrategy{
+				name:             name,
+				knockoutPrefix:   merge.Get5(`knockout_prefix`, px.EmptyString).String(),
+				sortMergedArrays: px.IsTruthy(merge.Get5(`sort_merged_arrays`, px.Undef)),
+				mergeHashArrays:  px.IsTruthy(merge.Get5(`merge_hash_arrays`, px.Undef)),
+			}
+		}
+		panic(px.Error(UnknownMergeStrategy, issue.H{`name`: name}))
+	}
+	if merge == px.Undef {
+		return firstFound
+	}
+	panic(px.Error(UnknownMergeStrategy, issue.H{`name`: merge.String()}))
+}
+
+// merge combines the given values, which must be in order of precedence
+func (ms *mergeStrategy) merge(values []px.Value) px.Value {
+	switch ms.name {
+	case `unique`:
+		elements := make([]px.Value, 0)
+		for _, v := range values {
+			switch v := v.(type) {
+			case *types.Array:
+				elements = v.Flatten().AppendTo(elements)
+			case *types.Hash:
+				panic(px.Error(IllegalMergeValue, issue.H{`strategy`: ms.name, `value`: v.PType()}))
+			default:
+				elements = append(elements, v)
+			}
+		}
+		return ms.sorted(types.WrapValues(elements).Unique())
+	case `hash`:
+		result := ms.hash(values[0])
+		for _, v := range values[1:] {
+			result = ms.hash(v).Merge(result)
+		}
+		return result
+	case `deep`:
+		result := values[0]
+		for _, v := range values[1:] {
+			result = ms.deepMerge(result, v)
+		}
+		return ms.knockout(result)
+	default:
+		return values[0]
+	}
+}
+
+// hash asserts that the given value is a hash
+func (ms *mergeStrategy) hash(value px.Value) px.OrderedMap {
+	if hash, ok := value.(*types.Hash); ok {
+		return hash
+	}
+	panic(px.Error(IllegalMergeValue, issue.H{`strategy`: ms.name, `value`: value.PType()}))
+}
+
+// deepMerge recursively merges the two values, where a has higher precedence than b. Hashes are merged
+// key by key, and arrays are combined into an array of unique elements.
+func (ms *mergeStrategy) deepMerge(a, b px.Value) px.Value {
+	switch av := a.(type) {
+	case *types.Hash:
+		bv, ok := b.(*types.Hash)
+		if !ok {
+			return a
+		}
+		entries := make([]*types.HashEntry, 0, av.Len()+bv.Len())
+		bv.EachPair(func(k, v px.Value) {
+			if ov, ok := av.Get(k); ok {
+				v = ms.deepMerge(ov, v)
+			}
+			entries = append(entries, types.WrapHashEntry(k, v))
+		})
+		av.EachPair(func(k, v px.Value) {
+			if !bv.IncludesKey(k) {
+				entries = append(entries, types.WrapHashEntry(k, v))
+			}
+		})
+		return types.WrapHash(entries)
+	case *types.Array:
+		bv, ok := b.(*types.Array)
+		if !ok {
+			return a
+		}
+		if ms.mergeHashArrays && allHashes(av) && allHashes(bv) {
+			elements := make([]px.Value, 0, av.Len())
+			av.EachWithIndex(func(e px.Value, i int) {
+				if i < bv.Len() {
+					e = ms.deepMerge(e, bv.At(i))
+				}
+				elements = append(elements, e)
+			})
+			if bv.Len() > av.Len() {
+				elements = bv.Slice(av.Len(), bv.Len()).AppendTo(elements)
+			}
+			return types.WrapValues(elements)
+		}
+		elements := av.AppendTo(make([]px.Value, 0, av.Len()+bv.Len()))
+		if ms.knockoutPrefix != `` {
+			bv = bv.Reject(func(e px.Value) bool {
+				s, ok := e.(px.StringValue)
+				return ok && av.Any(func(ae px.Value) bool { return ae.String() == ms.knockoutPrefix+s.String() })
+			}).(*types.Array)
+		}
+		return ms.sorted(types.WrapValues(bv.AppendTo(elements)).Unique())
+	}
+	return a
+}
+
+// knockout removes all array elements and hash entries that have been knocked out using the knockout prefix
+func (ms *mergeStrategy) knockout(value px.Value) px.Value {
+	if ms.knockoutPrefix == `` {
+		return value
+	}
+	isKnockout := func(v px.Value) bool {
+		s, ok := v.(px.StringValue)
+		return ok && strings.HasPrefix(s.String(), ms.knockoutPrefix)
+	}
+	switch v := value.(type) {
+	case *types.Hash:
+		entries := make([]*types.HashEntry, 0, v.Len())
+		v.EachPair(func(k, e px.Value) {
+			if s, ok := e.(px.StringValue); !ok || s.String() != ms.knockoutPrefix {
+				entries = append(entries, types.WrapHashEntry(k, ms.knockout(e)))
+			}
+		})
+		return types.WrapHash(entries)
+	case *types.Array:
+		return v.Reject(isKnockout).Map(ms.knockout)
+	}
+	return value
+}
+
+// sorted returns the given array sorted if the strategy has the sort_merged_arrays option set
+func (ms *mergeStrategy) sorted(a px.List) px.List {
+	if !ms.sortMergedArrays {
+		return a
+	}
+	elements := a.AppendTo(make([]px.Value, 0, a.Len()))
+	sort.SliceStable(elements, func(i, j int) bool { return elements[i].String() < elements[j].String() })
+	return types.WrapValues(elements)
+}
+
+func allHashes(a *types.Array) bool {
+	return a.All(func(e px.Value) bool {
+		_, ok := e.(*types.Hash)
+		return ok
+	})
+}
@@ -0,0 +1,33 @@
+package hocon
+
+import "github.com/lyraproj/issue/issue"
+
+const (
+	IllegalConcatenation     = `HOCON_ILLEGAL_CONCATENATION`
+	IllegalEscape            = `HOCON_ILLEGAL_ESCAPE`
+	SubstitutionCycle        = `HOCON_SUBSTITUTION_CYCLE`
+	UnexpectedCharacter      = `HOCON_UNEXPECTED_CHARACTER`
+	UnexpectedEnd            = `HOCON_UNEXPECTED_END`
+	UnresolvedSubstitution   = `HOCON_UNRESOLVED_SUBSTITUTION`
+	UnterminatedString       = `HOCON_UNTERMINATED_STRING`
+	UnterminatedSubstitution = `HOCON_UNTERMINATED_SUBSTITUTION`
+)
+
+func init() {
+	issue.Hard2(IllegalConcatenation, `Unable to concatenate %{left} with %{right}`,
+		issue.HF{`left`: issue.AnOrA, `right`: issue.AnOrA})
+
+	issue.Hard(IllegalEscape, `Illegal escape sequence '\%{char}' in string`)
+
+	issue.Hard(SubstitutionCycle, `Substitution ${%{path}} is part of a cycle`)
+
+	issue.Hard(UnexpectedCharacter, `Unexpected character '%{char}', expected %{expected}`)
+
+	issue.Hard(UnexpectedEnd, `Unexpected end of input, expected %{expected}`)
+
+	issue.Hard(UnresolvedSubstitution, `Unable to resolve substitution ${%{path}}`)
+
+	issue.Hard(UnterminatedString, `Unterminated quoted string`)
+
+	issue.Hard(UnterminatedSubstitution, `Unterminated substitution`)
+}
@@ -0,0 +1,569 @@
+// Package hocon implements a parser for the Human-Optimized Config Object Notation (HOCON). The parser
+// supports comments, omitted root braces, path expression keys, object merging, value concatenation,
+// substitutions with environment variable fallback, self-referential fields, the += operator, and includes
+// of other files.
+package hocon
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+const eof = -1
+
+// forbidden are the characters that cannot be part of an unquoted string
+const forbidden = "$\"{}[]:=,+#`^?!@*&\\"
+
+type (
+	// node is an unresolved value. It is either a px.Value, an *object, an *array, an unquoted string,
+	// whitespace, a *substitution, or a concatenation.
+	node interface{}
+
+	object struct {
+		keys   []string
+		fields map[string]node
+	}
+
+	array struct {
+		elements []node
+	}
+
+	unquoted string
+
+	whitespace string
+
+	substitution struct {
+		path     []string
+		optional bool
+		envOnly  bool
+		line     int
+	}
+
+	concatenation struct {
+		parts []node
+		line  int
+	}
+
+	parser struct {
+		file string
+		src  []rune
+		pos  int
+		line int
+	}
+)
+
+// Parse parses the given HOCON content and returns the resulting hash. The given file is used when
+// reporting errors and when resolving relative includes.
+func Parse(file string, content []byte) px.OrderedMap {
+	p := &parser{file: file, src: []rune(string(content)), line: 1}
+	root := p.parseRoot([]string{})
+	return (&resolver{file: file, root: root}).resolve(root).(px.OrderedMap)
+}
+
+func (p *parser) parseRoot(prefix []string) *object {
+	p.skip(true)
+	var root *object
+	if p.peek() == '{' {
+		p.next()
+		root = p.parseObject(prefix, '}')
+	} else {
+		root = p.parseObject(prefix, eof)
+	}
+	p.skip(true)
+	if r := p.peek(); r != eof {
+		panic(p.unexpected(r, `end of input`))
+	}
+	return root
+}
+
+// parseObject parses the fields of an object up to and including the given end character. The given
+// prefix is the path of the object, or nil if the object isn't addressable by a path.
+func (p *parser) parseObject(prefix []string, end rune) *object {
+	obj := newObject()
+	for {
+		p.skip(true)
+		r := p.peek()
+		if r == end {
+			p.next()
+			return obj
+		}
+		if r == eof {
+			panic(p.error(UnexpectedEnd, issue.H{`expected`: `'}'`}))
+		}
+		p.parseField(obj, prefix)
+		p.separator(end)
+	}
+}
+
+func (p *parser) parseArray() *array {
+	arr := &array{elements: make([]node, 0)}
+	for {
+		p.skip(true)
+		r := p.peek()
+		if r == ']' {
+			p.next()
+			return arr
+		}
+		if r == eof {
+			panic(p.error(UnexpectedEnd, issue.H{`expected`: `']'`}))
+		}
+		arr.elements = append(arr.elements, p.parseValue(nil))
+		p.separator(']')
+	}
+}
+
+// separator consumes the comma or newline that separates fields and array elements
+func (p *parser) separator(end rune) {
+	p.skip(false)
+	switch r := p.peek(); {
+	case r == ',':
+		p.next()
+	case r == '\n' || r == end:
+	case r == eof:
+		panic(p.error(UnexpectedEnd, issue.H{`expected`: `'` + string(end) + `'`}))
+	default:
+		panic(p.unexpected(r, `',' or newline`))
+	}
+}
+
+func (p *parser) parseField(obj *object, prefix []string) {
+	if p.atInclude() {
+		p.parseInclude(obj, prefix)
+		return
+	}
+	path := p.parseKey()
+	p.skip(false)
+	appendTo := false
+	switch r := p.peek(); {
+	case r == ':' || r == '=':
+		p.next()
+	case r == '+' && p.peekAt(1) == '=':
+		p.pos += 2
+		appendTo = true
+	case r == '{':
+	case r == eof:
+		panic(p.error(UnexpectedEnd, issue.H{`expected`: `':', '=', or '{'`}))
+	default:
+		panic(p.unexpected(r, `':', '=', or '{'`))
+	}
+	p.skip(true)
+
+	var full []string
+	if prefix != nil {
+		full = append(append(make([]string, 0, len(prefix)+len(path)), prefix...), path...)
+	}
+	line := p.line
+	value := p.parseValue(full)
+	if appendTo {
+		self := full
+		if self == nil {
+			self = path
+		}
+		value = concatenation{
+			parts: []node{&substitution{path: self, optional: true, line: line}, &array{elements: []node{value}}},
+			line:  line}
+	}
+	obj.set(path, value, full)
+}
+
+// parseKey parses a path expression. Segments are separated by dots and are either quoted or unquoted.
+func (p *parser) parseKey() []string {
+	path := make([]string, 0, 1)
+	b := strings.Builder{}
+	empty := true
+	for {
+		r := p.peek()
+		switch {
+		case r == '"':
+			b.WriteString(p.parseQuoted())
+			empty = false
+			continue
+		case r == '.':
+			if empty {
+				panic(p.unexpected(r, `a key`))
+			}
+			path = append(path, b.String())
+			b.Reset()
+			empty = true
+			p.next()
+			continue
+		case p.isUnquoted(r):
+			b.WriteRune(p.next())
+			empty = false
+			continue
+		}
+		if empty {
+			if r == eof {
+				panic(p.error(UnexpectedEnd, issue.H{`expected`: `a key`}))
+			}
+			panic(p.unexpected(r, `a key`))
+		}
+		return append(path, b.String())
+	}
+}
+
+// parseValue parses a value, which may be a concatenation of several values on the same line. The given
+// path is the path of the field that the value is assigned to, or nil if the value isn't addressable.
+func (p *parser) parseValue(path []string) node {
+	line := p.line
+	parts := make([]node, 0, 1)
+	for done := false; !done; {
+		r := p.peek()
+		switch {
+		case r == '{':
+			p.next()
+			parts = append(parts, p.parseObject(path, '}'))
+		case r == '[':
+			p.next()
+			parts = append(parts, p.parseArray())
+		case r == '"':
+			parts = append(parts, types.WrapString(p.parseQuoted()))
+		case r == '$' && p.peekAt(1) == '{':
+			parts = append(parts, p.parseSubstitution())
+		case p.isUnquoted(r) || r == '.':
+			start := p.pos
+			for r = p.peek(); p.isUnquoted(r) || r == '.'; r = p.peek() {
+				p.next()
+			}
+			parts = append(parts, unquoted(p.src[start:p.pos]))
+		case isWhitespace(r):
+			start := p.pos
+			for isWhitespace(p.peek()) {
+				p.next()
+			}
+			parts = append(parts, whitespace(p.src[start:p.pos]))
+		default:
+			done = true
+		}
+	}
+	for len(parts) > 0 {
+		if _, ok := parts[len(parts)-1].(whitespace); !ok {
+			break
+		}
+		parts = parts[:len(parts)-1]
+	}
+	switch len(parts) {
+	case 0:
+		if r := p.peek(); r != eof {
+			panic(p.unexpected(r, `a value`))
+		}
+		panic(p.error(UnexpectedEnd, issue.H{`expected`: `a value`}))
+	case 1:
+		return parts[0]
+	default:
+		return concatenation{parts: parts, line: line}
+	}
+}
+
+func (p *parser) parseSubstitution() node {
+	line := p.line
+	p.pos += 2
+	optional := false
+	if p.peek() == '?' {
+		p.next()
+		optional = true
+	}
+	p.skip(false)
+	path := p.parseKey()
+	p.skip(false)
+	if p.peek() != '}' {
+		panic(p.error(UnterminatedSubstitution, issue.NoArgs))
+	}
+	p.next()
+	return &substitution{path: path, optional: optional, line: line}
+}
+
+// parseQuoted parses a double quoted or a triple quoted string and returns its content
+func (p *parser) parseQuoted() string {
+	if p.peekAt(1) == '"' && p.peekAt(2) == '"' {
+		return p.parseTripleQuoted()
+	}
+	p.next()
+	b := strings.Builder{}
+	for {
+		r := p.next()
+		switch r {
+		case '"':
+			return b.String()
+		case eof, '\n':
+			panic(p.error(UnterminatedString, issue.NoArgs))
+		case '\\':
+			b.WriteRune(p.parseEscape())
+		default:
+			b.WriteRune(r)
+		}
+	}
+}
+
+func (p *parser) parseEscape() rune {
+	r := p.next()
+	switch r {
+	case '"', '\\', '/':
+		return r
+	case 'b':
+		return '\b'
+	case 'f':
+		return '\f'
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 't':
+		return '\t'
+	case 'u':
+		if p.pos+4 <= len(p.src) {
+			if n, err := strconv.ParseUint(string(p.src[p.pos:p.pos+4]), 16, 16); err == nil {
+				p.pos += 4
+				return rune(n)
+			}
+		}
+	case eof:
+		panic(p.error(UnterminatedString, issue.NoArgs))
+	}
+	panic(p.error(IllegalEscape, issue.H{`char`: string(r)}))
+}
+
+// parseTripleQuoted parses a string that starts with three double quotes. The content is verbatim and ends
+// at the last quote of the first sequence of at least three double quotes.
+func (p *parser) parseTripleQuoted() string {
+	p.pos += 3
+	start := p.pos
+	for {
+		r := p.next()
+		if r == eof {
+			panic(p.error(UnterminatedString, issue.NoArgs))
+		}
+		if r == '"' && p.peek() == '"' && p.peekAt(1) == '"' {
+			p.pos += 2
+			for p.peek() == '"' {
+				p.next()
+			}
+			return string(p.src[start : p.pos-3])
+		}
+	}
+}
+
+func (p *parser) atInclude() bool {
+	const keyword = `include`
+	end := p.pos + len(keyword)
+	if end >= len(p.src) || string(p.src[p.pos:end]) != keyword || !isWhitespace(p.src[end]) {
+		return false
+	}
+	for end < len(p.src) && isWhitespace(p.src[end]) {
+		end++
+	}
+	rest := string(p.src[end:])
+	return strings.HasPrefix(rest, `"`) || strings.HasPrefix(rest, `file(`) || strings.HasPrefix(rest, `required(`)
+}
+
+// parseInclude parses an include statement and merges the object of the included file into the given object.
+// A relative file name is relative to the directory of the including file. An include that cannot be found
+// is silently ignored unless it is declared using required().
+func (p *parser) parseInclude(obj *object, prefix []string) {
+	p.pos += len(`include`)
+	p.skip(false)
+	required := p.consume(`required(`)
+	fileFunc := p.consume(`file(`)
+	if p.peek() != '"' {
+		panic(p.unexpected(p.peek(), `a quoted file name`))
+	}
+	name := p.parseQuoted()
+	for _, enclosed := range []bool{fileFunc, required} {
+		if enclosed {
+			p.skip(false)
+			if r := p.next(); r != ')' {
+				panic(p.unexpected(r, `')'`))
+			}
+		}
+	}
+
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(filepath.Dir(p.file), name)
+	}
+	content, err := ioutil.ReadFile(name)
+	if err != nil {
+		if required {
+			panic(px.Error(px.FileNotFound, issue.H{`path`: name}))
+		}
+		return
+	}
+	ip := &parser{file: name, src: []rune(string(content)), line: 1}
+	included := ip.parseRoot(prefix)
+	for _, key := range included.keys {
+		var full []string
+		if prefix != nil {
+			full = append(append(make([]string, 0, len(prefix)+1), prefix...), key)
+		}
+		obj.set([]string{key}, included.fields[key], full)
+	}
+}
+
+func (p *parser) consume(s string) bool {
+	end := p.pos + len(s)
+	if end <= len(p.src) && string(p.src[p.pos:end]) == s {
+		p.pos = end
+		p.skip(false)
+		return true
+	}
+	return false
+}
+
+// skip skips whitespace and comments. Newlines are skipped too if the given flag is true.
+func (p *parser) skip(newlines bool) {
+	for {
+		r := p.peek()
+		switch {
+		case r == '\n':
+			if !newlines {
+				return
+			}
+			p.next()
+		case r == '#' || r == '/' && p.peekAt(1) == '/':
+			for r = p.peek(); r != '\n' && r != eof; r = p.peek() {
+				p.next()
+			}
+		case isWhitespace(r):
+			p.next()
+		default:
+			return
+		}
+	}
+}
+
+func (p *parser) peek() rune {
+	return p.peekAt(0)
+}
+
+func (p *parser) peekAt(n int) rune {
+	if p.pos+n < len(p.src) {
+		return p.src[p.pos+n]
+	}
+	return eof
+}
+
+func (p *parser) next() rune {
+	r := p.peek()
+	if r != eof {
+		p.pos++
+		if r == '\n' {
+			p.line++
+		}
+	}
+	return r
+}
+
+// isUnquoted returns true if the given character can be part of an unquoted string or key segment
+func (p *parser) isUnquoted(r rune) bool {
+	return !(r == eof || r == '.' || r == '\n' || isWhitespace(r) || strings.ContainsRune(forbidden, r) ||
+		r == '/' && p.peekAt(1) == '/')
+}
+
+func (p *parser) error(code issue.Code, args issue.H) issue.Reported {
+	return px.Error2(issue.NewLocation(p.file, p.line, 0), code, args)
+}
+
+func (p *parser) unexpected(r rune, expected string) issue.Reported {
+	return p.error(UnexpectedCharacter, issue.H{`char`: string(r), `expected`: expected})
+}
+
+func isWhitespace(r rune) bool {
+	return r != '\n' && (r == '\uFEFF' || unicode.IsSpace(r))
+}
+
+func newObject() *object {
+	return &object{keys: make([]string, 0), fields: make(map[string]node)}
+}
+
+// set assigns the given value to the given path. An object value is merged with an existing object
+// value. Substitutions in the value that refer to the field itself are replaced with the previous value
+// of the field. The given full path is nil when the object isn't addressable.
+func (o *object) set(path []string, value node, full []string) {
+	key := path[0]
+	prev, exists := o.fields[key]
+	if len(path) > 1 {
+		child, ok := prev.(*object)
+		if !ok {
+			child = newObject()
+			o.put(key, child)
+		}
+		child.set(path[1:], value, full)
+		return
+	}
+	if full != nil {
+		value = replaceSelf(value, full, prev, exists)
+	}
+	if po, ok := prev.(*object); ok {
+		if vo, ok := value.(*object); ok {
+			po.merge(vo)
+			return
+		}
+	}
+	o.put(key, value)
+}
+
+// merge merges the fields of the given object into this object
+func (o *object) merge(other *object) {
+	for _, key := range other.keys {
+		value := other.fields[key]
+		if po, ok := o.fields[key].(*object); ok {
+			if vo, ok := value.(*object); ok {
+				po.merge(vo)
+				continue
+			}
+		}
+		o.put(key, value)
+	}
+}
+
+func (o *object) put(key string, value node) {
+	if _, ok := o.fields[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.fields[key] = value
+}
+
+// replaceSelf replaces substitutions of the given path with the given previous value. When there is no
+// previous value, the substitutions are restricted to environment variables.
+func replaceSelf(n node, path []string, prev node, exists bool) node {
+	switch n := n.(type) {
+	case *substitution:
+		if equalPaths(n.path, path) {
+			if exists {
+				return prev
+			}
+			return &substitution{path: n.path, optional: n.optional, envOnly: true, line: n.line}
+		}
+	case concatenation:
+		parts := make([]node, len(n.parts))
+		for i, part := range n.parts {
+			parts[i] = replaceSelf(part, path, prev, exists)
+		}
+		return concatenation{parts: parts, line: n.line}
+	case *array:
+		elements := make([]node, len(n.elements))
+		for i, e := range n.elements {
+			elements[i] = replaceSelf(e, path, prev, exists)
+		}
+		return &array{elements: elements}
+	}
+	return n
+}
+
+func equalPaths(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
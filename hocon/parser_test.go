@@ -0,0 +1,168 @@
+package hocon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+)
+
+func expectParsed(t *testing.T, tests map[string]string) {
+	t.Helper()
+	for source, expected := range tests {
+		if actual := Parse(`test.conf`, []byte(source)); actual.String() != expected {
+			t.Errorf("%s:\nexpected %s\ngot %s", source, expected, actual)
+		}
+	}
+}
+
+func TestPathKeys(t *testing.T) {
+	expectParsed(t, map[string]string{
+		`a.b.c = 1`:               `{'a' => {'b' => {'c' => 1}}}`,
+		`"a.b" = 1`:               `{'a.b' => 1}`,
+		`a."b.c".d = 1`:           `{'a' => {'b.c' => {'d' => 1}}}`,
+		"a.b = 1\na.c = 2":        `{'a' => {'b' => 1, 'c' => 2}}`,
+		`{ a: 1, b: "x" }`:        `{'a' => 1, 'b' => 'x'}`,
+		"a = 1 // comment\n# b=2": `{'a' => 1}`,
+		`a { b { c = true } }`:    `{'a' => {'b' => {'c' => true}}}`,
+	})
+}
+
+func TestObjectMerge(t *testing.T) {
+	expectParsed(t, map[string]string{
+		"a { x = 1 }\na { y = 2 }":        `{'a' => {'x' => 1, 'y' => 2}}`,
+		"a { x { p = 1 } }\na.x.q = 2":    `{'a' => {'x' => {'p' => 1, 'q' => 2}}}`,
+		"a { x = 1 }\na = 3":              `{'a' => 3}`,
+		"a = 3\na { x = 1 }":              `{'a' => {'x' => 1}}`,
+		"a = 1\na = 2":                    `{'a' => 2}`,
+		"a { x = 1, y = 2 }\na { x = 3 }": `{'a' => {'x' => 3, 'y' => 2}}`,
+	})
+}
+
+func TestAppend(t *testing.T) {
+	expectParsed(t, map[string]string{
+		"a = [1]\na += 2":         `{'a' => [1, 2]}`,
+		"a = [1]\na += [2]":       `{'a' => [1, [2]]}`,
+		`a += 1`:                  `{'a' => [1]}`,
+		"x { a = [1] }\nx.a += 2": `{'x' => {'a' => [1, 2]}}`,
+	})
+}
+
+func TestConcatenation(t *testing.T) {
+	expectParsed(t, map[string]string{
+		`a = foo bar  baz`:            `{'a' => 'foo bar  baz'}`,
+		`a = "x" y`:                   `{'a' => 'x y'}`,
+		"b = 1\na = ${b} px":          `{'b' => 1, 'a' => '1 px'}`,
+		"b = 1\na = ${b}${b}":         `{'b' => 1, 'a' => '11'}`,
+		`a = [1] [2]`:                 `{'a' => [1, 2]}`,
+		`a = { x = 1 } { y = 2 }`:     `{'a' => {'x' => 1, 'y' => 2}}`,
+		"b = [1]\na = ${b} [2]":       `{'b' => [1], 'a' => [1, 2]}`,
+		`a = """multi "line" text"""`: `{'a' => 'multi "line" text'}`,
+		`a = 10 seconds`:              `{'a' => '10 seconds'}`,
+	})
+}
+
+func TestSubstitution(t *testing.T) {
+	if err := os.Setenv(`HOCON_TEST_VAR`, `from env`); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Unsetenv(`HOCON_TEST_VAR`)
+	expectParsed(t, map[string]string{
+		"a { b = 1 }\nc = ${a.b}":                   `{'a' => {'b' => 1}, 'c' => 1}`,
+		"c = ${a.b}\na { b = 1 }":                   `{'c' => 1, 'a' => {'b' => 1}}`,
+		"a = ${?missing}":                           `{}`,
+		"a = [${?missing}, 2]":                      `{'a' => [2]}`,
+		`a = ${HOCON_TEST_VAR}`:                     `{'a' => 'from env'}`,
+		`a = ${?HOCON_TEST_VAR}`:                    `{'a' => 'from env'}`,
+		"HOCON_TEST_VAR = x\na = ${HOCON_TEST_VAR}": `{'HOCON_TEST_VAR' => 'x', 'a' => 'x'}`,
+		"a = ${b}\nb = ${c}\nc = 3":                 `{'a' => 3, 'b' => 3, 'c' => 3}`,
+	})
+}
+
+func TestSelfReference(t *testing.T) {
+	expectParsed(t, map[string]string{
+		"path = a\npath = ${path}\":b\"":    `{'path' => 'a:b'}`,
+		"a = { x = 1 }\na = ${a} { y = 2 }": `{'a' => {'x' => 1, 'y' => 2}}`,
+		"a = [1]\na = ${a} [2]":             `{'a' => [1, 2]}`,
+		"a = ${?a} [1]":                     `{'a' => [1]}`,
+	})
+}
+
+func TestIncludes(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `hocon`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, content := range map[string]string{
+		`inc.conf`:        "a = 1\nb { x = 1 }",
+		`sub/nested.conf`: `include "../inc.conf"`,
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err = os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+			err = ioutil.WriteFile(path, []byte(content), 0644)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	main := filepath.Join(dir, `main.conf`)
+	for source, expected := range map[string]string{
+		"include \"inc.conf\"\nc = ${a}":                   `{'a' => 1, 'b' => {'x' => 1}, 'c' => 1}`,
+		"b { y = 2 }\ninclude \"inc.conf\"":                `{'b' => {'y' => 2, 'x' => 1}, 'a' => 1}`,
+		"include \"inc.conf\"\na = 2":                      `{'a' => 2, 'b' => {'x' => 1}}`,
+		`x { include file("inc.conf") }`:                   `{'x' => {'a' => 1, 'b' => {'x' => 1}}}`,
+		`include required("sub/nested.conf")`:              `{'a' => 1, 'b' => {'x' => 1}}`,
+		"include \"missing.conf\"\na = 1":                  `{'a' => 1}`,
+		`include "` + filepath.Join(dir, `inc.conf`) + `"`: `{'a' => 1, 'b' => {'x' => 1}}`,
+	} {
+		if actual := Parse(main, []byte(source)); actual.String() != expected {
+			t.Errorf("%s:\nexpected %s\ngot %s", source, expected, actual)
+		}
+	}
+	expectError(t, `include required("missing.conf")`, px.FileNotFound, 0)
+}
+
+// expectError asserts that parsing the given source fails with the given issue on the given line. A line
+// of 0 is not checked.
+func expectError(t *testing.T, source string, code issue.Code, line int) {
+	t.Helper()
+	defer func() {
+		r := recover()
+		ri, ok := r.(issue.Reported)
+		if !ok || ri.Code() != code {
+			t.Errorf(`%s: expected %s, got %v`, source, code, r)
+			return
+		}
+		if line > 0 && ri.Location().Line() != line {
+			t.Errorf(`%s: expected %s on line %d, got line %d`, source, code, line, ri.Location().Line())
+		}
+	}()
+	Parse(`test.conf`, []byte(source))
+}
+
+func TestErrors(t *testing.T) {
+	for _, tt := range []struct {
+		source string
+		code   issue.Code
+		line   int
+	}{
+		{"a = 1\nb = \"abc", UnterminatedString, 2},
+		{"a = 1\nb = \"\"\"abc", UnterminatedString, 2},
+		{"a = {\nb = 1\n", UnexpectedEnd, 3},
+		{"a = [1,\n2", UnexpectedEnd, 2},
+		{"a = 1\n\nb = 2 }", UnexpectedCharacter, 3},
+		{"a = 1\nb : : 2", UnexpectedCharacter, 2},
+		{"a = 1\nb = \"\\q\"", IllegalEscape, 2},
+		{"a = 1\nb = ${c", UnterminatedSubstitution, 2},
+		{"a = 1\n\nb = ${c}", UnresolvedSubstitution, 3},
+		{"a = ${b}\nb = ${a}", SubstitutionCycle, 1},
+		{"a = 1\nb = [1] { x = 1 }", IllegalConcatenation, 2},
+		{"x = [1]\nb = ${x} foo", IllegalConcatenation, 2},
+	} {
+		expectError(t, tt.source, tt.code, tt.line)
+	}
+}
@@ -0,0 +1,229 @@
+package hocon
+
+import (
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+var integerPattern = regexp.MustCompile(`\A-?\d+\z`)
+
+var floatPattern = regexp.MustCompile(`\A-?\d+(?:\.\d+)?(?:[eE]-?\d+)?\z`)
+
+// resolver turns the nodes produced by the parser into values. A resolved value is nil when it is
+// an optional substitution that cannot be resolved.
+type resolver struct {
+	file      string
+	root      *object
+	resolving []string
+}
+
+func (r *resolver) resolve(n node) px.Value {
+	switch n := n.(type) {
+	case *object:
+		entries := make([]*types.HashEntry, 0, len(n.keys))
+		for _, key := range n.keys {
+			if v := r.resolve(n.fields[key]); v != nil {
+				entries = append(entries, types.WrapHashEntry2(key, v))
+			}
+		}
+		return types.WrapHash(entries)
+	case *array:
+		elements := make([]px.Value, 0, len(n.elements))
+		for _, e := range n.elements {
+			if v := r.resolve(e); v != nil {
+				elements = append(elements, v)
+			}
+		}
+		return types.WrapValues(elements)
+	case unquoted:
+		return typed(string(n))
+	case whitespace:
+		return types.WrapString(string(n))
+	case *substitution:
+		return r.substitute(n)
+	case concatenation:
+		return r.concatenate(n)
+	default:
+		return n.(px.Value)
+	}
+}
+
+// typed returns the boolean, null, or number denoted by the given unquoted string, or the string itself
+func typed(s string) px.Value {
+	switch s {
+	case `true`:
+		return types.BooleanTrue
+	case `false`:
+		return types.BooleanFalse
+	case `null`:
+		return px.Undef
+	}
+	if integerPattern.MatchString(s) {
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return types.WrapInteger(i)
+		}
+	}
+	if floatPattern.MatchString(s) {
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return types.WrapFloat(f)
+		}
+	}
+	return types.WrapString(s)
+}
+
+// substitute resolves the value of the given substitution. Environment variables are consulted when the
+// path cannot be found.
+func (r *resolver) substitute(s *substitution) px.Value {
+	key := strings.Join(s.path, `.`)
+	if !s.envOnly {
+		for _, k := range r.resolving {
+			if k == key {
+				panic(r.error(s, SubstitutionCycle, issue.H{`path`: key}))
+			}
+		}
+		r.resolving = append(r.resolving, key)
+		v := r.lookup(s.path)
+		r.resolving = r.resolving[:len(r.resolving)-1]
+		if v != nil {
+			return v
+		}
+	}
+	if env, ok := os.LookupEnv(key); ok {
+		return types.WrapString(env)
+	}
+	if s.optional {
+		return nil
+	}
+	panic(r.error(s, UnresolvedSubstitution, issue.H{`path`: key}))
+}
+
+// lookup returns the resolved value at the given path from the root, or nil if no such value exists
+func (r *resolver) lookup(path []string) px.Value {
+	var n node = r.root
+	for i, segment := range path {
+		o, ok := n.(*object)
+		if !ok {
+			v := r.resolve(n)
+			for _, segment = range path[i:] {
+				h, ok := v.(px.OrderedMap)
+				if !ok {
+					return nil
+				}
+				if v, ok = h.Get4(segment); !ok {
+					return nil
+				}
+			}
+			return v
+		}
+		if n, ok = o.fields[segment]; !ok {
+			return nil
+		}
+	}
+	return r.resolve(n)
+}
+
+// concatenate resolves a value concatenation. Strings and other simple values are concatenated into a
+// string, arrays into an array, and objects are merged. Whitespace is ignored between arrays and objects.
+func (r *resolver) concatenate(c concatenation) px.Value {
+	var result px.Value
+	resultIsSpace := false
+	for _, part := range c.parts {
+		var v px.Value
+		isSpace := false
+		switch part := part.(type) {
+		case unquoted:
+			v = types.WrapString(string(part))
+		case whitespace:
+			v = types.WrapString(string(part))
+			isSpace = true
+		default:
+			if v = r.resolve(part); v == nil {
+				continue
+			}
+		}
+		if result == nil {
+			result, resultIsSpace = v, isSpace
+			continue
+		}
+
+		switch rv := result.(type) {
+		case *types.Array:
+			if a, ok := v.(*types.Array); ok {
+				result = types.WrapValues(a.AppendTo(rv.AppendTo(make([]px.Value, 0, rv.Len()+a.Len()))))
+				continue
+			}
+		case *types.Hash:
+			if h, ok := v.(*types.Hash); ok {
+				result = mergeHashes(rv, h)
+				continue
+			}
+		default:
+			switch v.(type) {
+			case *types.Array, *types.Hash:
+				if resultIsSpace {
+					result, resultIsSpace = v, false
+					continue
+				}
+			default:
+				result, resultIsSpace = types.WrapString(stringify(result)+stringify(v)), resultIsSpace && isSpace
+				continue
+			}
+		}
+		if isSpace {
+			continue
+		}
+		panic(r.error(c, IllegalConcatenation, issue.H{`left`: result.PType(), `right`: v.PType()}))
+	}
+	return result
+}
+
+// mergeHashes merges b into a. Hashes found under the same key in both are merged recursively.
+func mergeHashes(a, b *types.Hash) *types.Hash {
+	entries := make([]*types.HashEntry, 0, a.Len()+b.Len())
+	a.EachPair(func(k, v px.Value) {
+		if bv, ok := b.Get(k); ok {
+			ah, aok := v.(*types.Hash)
+			bh, bok := bv.(*types.Hash)
+			if aok && bok {
+				v = mergeHashes(ah, bh)
+			} else {
+				v = bv
+			}
+		}
+		entries = append(entries, types.WrapHashEntry(k, v))
+	})
+	b.EachPair(func(k, v px.Value) {
+		if !a.IncludesKey(k) {
+			entries = append(entries, types.WrapHashEntry(k, v))
+		}
+	})
+	return types.WrapHash(entries)
+}
+
+func stringify(v px.Value) string {
+	if v == px.Undef {
+		return `null`
+	}
+	return v.String()
+}
+
+// error creates an error located at the line of the given substitution or concatenation
+func (r *resolver) error(n node, code issue.Code, args issue.H) issue.Reported {
+	return px.Error2(issue.NewLocation(r.file, lineOf(n), 0), code, args)
+}
+
+func lineOf(n node) int {
+	switch n := n.(type) {
+	case *substitution:
+		return n.line
+	case concatenation:
+		return n.line
+	}
+	return 0
+}
@@ -0,0 +1,123 @@
+package pdsl
+
+import (
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+)
+
+type (
+	// A Reference identifies a Resource by its type name and its title.
+	Reference interface {
+		// Type returns the capitalized name of the resource type, e.g. "File"
+		Type() string
+
+		// Title returns the title of the resource
+		Title() string
+	}
+
+	// A Resource is an entry in a Catalog. It is uniquely identified by its type name and
+	// its title.
+	Resource interface {
+		issue.Located
+		Reference
+
+		// Attributes returns the attributes of the resource in the order they were declared
+		Attributes() px.OrderedMap
+
+		// Exported returns true if the resource was declared as an exported resource
+		Exported() bool
+
+		// Tags returns the lower case tags of the resource
+		Tags() []string
+
+		// Virtual returns true if the resource was declared as a virtual or exported resource and
+		// has not yet been realized
+		Virtual() bool
+	}
+
+	// An Edge is a dependency between two resources in a Catalog. The source of the edge must
+	// be applied before its target.
+	Edge interface {
+		issue.Located
+
+		// Source returns the reference to the resource that must be applied first
+		Source() Reference
+
+		// Target returns the reference to the resource that depends on the source
+		Target() Reference
+
+		// Subscribe returns true if the target should be notified when the source changes
+		Subscribe() bool
+	}
+
+	// A Catalog is the container for all resources that are declared during an evaluation.
+	// A Catalog is shared between forked contexts and is safe for concurrent use.
+	Catalog interface {
+		// AddEdge adds the given edge to the catalog. It will panic with an issue.Reported if
+		// the edge introduces a dependency cycle.
+		AddEdge(edge Edge)
+
+		// Alias makes the given resource available under an alternative title. It returns true if the
+		// resource was found. It will panic with an issue.Reported if another resource of the same type
+		// already uses the alias as its title or alias.
+		Alias(resource Reference, alias string) bool
+
+		// AddResource adds the given resource to the catalog. It will panic with an issue.Reported
+		// if a resource with the same type name and title has already been added.
+		AddResource(resource Resource)
+
+		// Contain records that the given container contains the given resource. The first recorded container
+		// of a resource is retained when the resource is contained more than once.
+		Contain(container, resource Reference)
+
+		// Container returns the container of the given resource together with a boolean indicating
+		// if the resource has a container or not
+		Container(resource Reference) (container Reference, found bool)
+
+		// Edges returns all edges of the catalog in the order they were added
+		Edges() []Edge
+
+		// Resource returns the resource with the given type name and title or alias together with a
+		// boolean indicating if the resource was found or not
+		Resource(typeName, title string) (resource Resource, found bool)
+
+		// Realize ensures that the given virtual resource becomes a regular resource. It returns true
+		// if the resource was found and was virtual.
+		Realize(resource Reference) bool
+
+		// Resources returns all resources of the catalog, including virtual resources, in the order they
+		// were added
+		Resources() []Resource
+
+		// SetAttributes replaces the attributes of the given resource. It returns true if the resource
+		// was found.
+		SetAttributes(resource Reference, attributes px.OrderedMap) bool
+
+		// Tag adds the given tags to the given resource. Tags are converted to lower case and tags that
+		// the resource already has are ignored. It returns true if the resource was found.
+		Tag(resource Reference, tags ...string) bool
+	}
+
+	// A Collector realizes and overrides resources that match a query. A Collector is applied to all
+	// resources that are present in the catalog when it is created and to all resources that are added
+	// after that.
+	Collector interface {
+		// Collect realizes and overrides the given resource if it matches the query of the collector. It
+		// returns true if the resource matched.
+		Collect(c EvaluationContext, resource Resource) bool
+	}
+
+	// A ResourceStore stores the resources that are exported by one node so that they can be collected
+	// when the catalogs of other nodes are evaluated. An implementation must be safe for concurrent use.
+	ResourceStore interface {
+		// Export replaces all resources previously exported by the given node with the given resources
+		Export(certname string, resources []Resource)
+
+		// Exported returns all resources of the given type that have been exported by nodes other than
+		// the given node
+		Exported(typeName, certname string) []Resource
+	}
+)
+
+// ResourceStoreKey is the context variable that holds the ResourceStore used for exported resources
+const ResourceStoreKey = `puppet.resourceStore`
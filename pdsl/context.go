@@ -1,31 +1,84 @@
 package pdsl
 
 import (
+	"bytes"
+
 	"github.com/lyraproj/pcore/px"
 	"github.com/lyraproj/puppet-parser/parser"
 )
 
 const PuppetContextKey = `puppet.context`
 
+// CertnameKey is the context variable that holds the certname of the node that is being evaluated
+const CertnameKey = `puppet.certname`
+
 type EvaluationContext interface {
 	px.Context
 
+	// AddCollector adds a collector that will be applied to all resources that are subsequently
+	// added to the catalog
+	AddCollector(collector Collector)
+
 	AddDefinitions(expression parser.Expression)
 
+	// Catalog returns the catalog that receives the resources declared by the evaluation
+	Catalog() Catalog
+
+	// ClassScope returns the scope that was used when the class with the given lower case name was
+	// evaluated, or false when no such class has been evaluated
+	ClassScope(name string) (Scope, bool)
+
+	// Collectors returns all collectors that have been added to the receiver
+	Collectors() []Collector
+
+	// Container returns a reference to the class or defined type instance that contains the resources
+	// that are declared by the evaluation, or nil when no such container exists
+	Container() Reference
+
 	// DoStatic ensures that the receiver is in static mode during the evaluation of the given doer
 	DoStatic(doer px.Doer)
 
+	// DoWithContainer assigns the given container to the receiver and calls the doer. The original
+	// container is restored before this call returns.
+	DoWithContainer(container Reference, doer px.Doer)
+
+	// DoWithRenderBuffer assigns the given buffer to the receiver and calls the doer. Text that is rendered
+	// by EPP expressions during the call is written to the buffer. The original buffer is restored before this
+	// call returns.
+	DoWithRenderBuffer(buffer *bytes.Buffer, doer px.Doer)
+
 	// DoWithScope assigns the given scope to the receiver and calls the doer. The original scope is
 	// restored before this call returns.
 	DoWithScope(scope Scope, doer px.Doer)
 
+	// EvaluateSiteManifest evaluates the given site manifest for the node with the given certname and returns
+	// the resulting catalog. The node definition that matches the certname is evaluated after the rest of the
+	// manifest. An exact match of the name takes precedence over a regular expression match, which in turn
+	// takes precedence over the default node definition.
+	EvaluateSiteManifest(certname string, manifest parser.Expression) Catalog
+
 	// EvaluatorConstructor returns the evaluator constructor
 	GetEvaluator() Evaluator
 
+	// LoadFacts reads facts from the file at the given path and assigns them using SetFacts. The file must
+	// contain a hash in the JSON or YAML format produced by Facter. The file is read as YAML when its
+	// extension is .yaml or .yml. The output of the 'puppet facts' command, where the facts are in the
+	// 'values' entry of the hash, is also accepted.
+	LoadFacts(path string, trusted px.OrderedMap, legacy bool)
+
 	// ParseAndValidate parses and evaluates the given content. It will panic with
 	// an issue.Reported unless the parsing and evaluation was successful.
 	ParseAndValidate(filename, content string, singleExpression bool) parser.Expression
 
+	// ParseAndValidateEpp parses and validates the given EPP template. The result is a LambdaExpression
+	// that has the parameters of the template and an EppExpression as its body. It will panic with an
+	// issue.Reported unless the parsing and validation was successful.
+	ParseAndValidateEpp(filename, content string) parser.Expression
+
+	// RenderBuffer returns the buffer that receives text rendered by EPP expressions, or nil when no
+	// template is being evaluated
+	RenderBuffer() *bytes.Buffer
+
 	// ResolveDefinitions resolves all definitions of a parser.Program
 	ResolveDefinitions() []interface{}
 
@@ -34,6 +87,27 @@ type EvaluationContext interface {
 	// is evaluates to a Type
 	ResolveType(expr parser.Expression) px.Type
 
+	// ResourceDefaults returns the default attributes for resources of the given type, or an empty map
+	// when no such defaults exist
+	ResourceDefaults(typeName string) px.OrderedMap
+
+	// SetClassScope records the scope that is used when evaluating the class with the given lower case name
+	SetClassScope(name string, scope Scope)
+
+	// SetFacts assigns the given facts to the global variable $facts and the given trusted information to
+	// the global variable $trusted. When trusted is nil, the information that Puppet provides for a node
+	// that is evaluated locally is used. Its certname is the certname of this context or, if the context
+	// has none, the clientcert or fqdn fact. When legacy is true, each fact is also assigned to a global
+	// variable with the same name. A Go map can be converted to facts using types.WrapStringToInterfaceMap.
+	//
+	// The $facts and $trusted variables cannot be assigned by Puppet code.
+	SetFacts(facts px.OrderedMap, trusted px.OrderedMap, legacy bool)
+
+	// SetResourceDefaults merges the given attributes into the default attributes for resources of
+	// the given type. The defaults are dynamically scoped, i.e. they are in effect until the current
+	// scope is left, and apply to resources that are declared after this call.
+	SetResourceDefaults(typeName string, defaults px.OrderedMap)
+
 	// Static returns true during evaluation of type expressions. It is used to prevent
 	// dynamic expressions within such expressions
 	Static() bool
@@ -0,0 +1,37 @@
+package pdsl
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lyraproj/pcore/px"
+)
+
+// ModuleFile returns the path that the given name denotes. An absolute name is returned unchanged. Any other
+// name is on the form <module name>/<file> and denotes a file in the given subdirectory of the module. An
+// empty string is returned when the name has no module part or when no module with that name can be found.
+func ModuleFile(c px.Context, subDir, name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	if i := strings.IndexByte(name, '/'); i > 0 {
+		if dir := ModuleDir(c, name[:i]); dir != `` {
+			return filepath.Join(dir, subDir, name[i+1:])
+		}
+	}
+	return ``
+}
+
+// FindFile returns the path of the first of the given names that denotes an existing file, or an empty string
+// if no such file exists. A name that isn't absolute denotes a file in the files directory of a module.
+func FindFile(c px.Context, names ...string) string {
+	for _, name := range names {
+		if path := ModuleFile(c, `files`, name); path != `` {
+			if _, err := os.Stat(path); err == nil {
+				return path
+			}
+		}
+	}
+	return ``
+}
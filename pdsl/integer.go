@@ -0,0 +1,21 @@
+package pdsl
+
+import (
+	"github.com/lyraproj/pcore/pcore"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+// BigIntegerSetting is the name of the boolean setting that, when true, makes integer arithmetic that
+// overflows a 64-bit Integer produce an arbitrary-precision integer instead of an IntegerOverflow error.
+const BigIntegerSetting = `big_integers`
+
+func init() {
+	pcore.DefineSetting(BigIntegerSetting, types.DefaultBooleanType(), types.WrapBoolean(false))
+}
+
+// BigIntegers returns the value of the big_integers setting
+func BigIntegers() bool {
+	b, ok := pcore.Get(BigIntegerSetting, nil).(px.Boolean)
+	return ok && b.Bool()
+}
@@ -3,6 +3,23 @@ package pdsl
 import "github.com/lyraproj/issue/issue"
 
 const (
+	AttributeAlreadySet         = `EVAL_ATTRIBUTE_ALREADY_SET`
+	AttributesNotHash           = `EVAL_ATTRIBUTES_NOT_HASH`
+	BadEnvironmentConf          = `EVAL_BAD_ENVIRONMENT_CONF`
+	BadFactsFile                = `EVAL_BAD_FACTS_FILE`
+	BadModuleMetadata           = `EVAL_BAD_MODULE_METADATA`
+	CatalogBadJson              = `EVAL_CATALOG_BAD_JSON`
+	CatalogNestedSensitive      = `EVAL_CATALOG_NESTED_SENSITIVE`
+	ConfigVersionFailed         = `EVAL_CONFIG_VERSION_FAILED`
+	CyclicValue                 = `EVAL_CYCLIC_VALUE`
+	DeferredResolutionFailed    = `EVAL_DEFERRED_RESOLUTION_FAILED`
+	DependencyCycle             = `EVAL_DEPENDENCY_CYCLE`
+	DivisionByZero              = `EVAL_DIVISION_BY_ZERO`
+	DuplicateAlias              = `EVAL_DUPLICATE_ALIAS`
+	DuplicateAttribute          = `EVAL_DUPLICATE_ATTRIBUTE`
+	DuplicateNode               = `EVAL_DUPLICATE_NODE`
+	DuplicateResource           = `EVAL_DUPLICATE_RESOURCE`
+	EppRenderOutsideTemplate    = `EVAL_EPP_RENDER_OUTSIDE_TEMPLATE`
 	IllegalArgument             = `EVAL_ILLEGAL_ARGUMENT`
 	IllegalArgumentCount        = `EVAL_ILLEGAL_ARGUMENT_COUNT`
 	IllegalArgumentType         = `EVAL_ILLEGAL_ARGUMENT_TYPE`
@@ -13,24 +30,87 @@ const (
 	IllegalMultiAssignmentSize  = `EVAL_ILLEGAL_MULTI_ASSIGNMENT_SIZE`
 	IllegalWhenStaticExpression = `EVAL_ILLEGAL_WHEN_STATIC_EXPRESSION`
 	IllegalReassignment         = `EVAL_ILLEGAL_REASSIGNMENT`
+	IllegalReservedAssignment   = `EVAL_ILLEGAL_RESERVED_ASSIGNMENT`
+	IllegalRelationshipOperand  = `EVAL_ILLEGAL_RELATIONSHIP_OPERAND`
+	IllegalResourceReference    = `EVAL_ILLEGAL_RESOURCE_REFERENCE`
+	IllegalResourceType         = `EVAL_ILLEGAL_RESOURCE_TYPE`
+	IllegalStage                = `EVAL_ILLEGAL_STAGE`
+	IllegalTitleType            = `EVAL_ILLEGAL_TITLE_TYPE`
+	IntegerOverflow             = `EVAL_INTEGER_OVERFLOW`
+	InvalidScanfFormat          = `EVAL_INVALID_SCANF_FORMAT`
+	MissingModuleDependency     = `EVAL_MISSING_MODULE_DEPENDENCY`
 	MissingMultiAssignmentKey   = `EVAL_MISSING_MULTI_ASSIGNMENT_KEY`
+	MissingParameter            = `EVAL_MISSING_PARAMETER`
 	MissingRegexpInType         = `EVAL_MISSING_REGEXP_IN_TYPE`
+	ModuleNameClash             = `EVAL_MODULE_NAME_CLASH`
+	ModulePathConflict          = `EVAL_MODULE_PATH_CONFLICT`
+	ModuloByZero                = `EVAL_MODULO_BY_ZERO`
 	NotCollectionAt             = `EVAL_NOT_COLLECTION_AT`
+	NotIterable                 = `EVAL_NOT_ITERABLE`
+	NotIterableRange            = `EVAL_NOT_ITERABLE_RANGE`
 	NotOnlyDefinition           = `EVAL_NOT_ONLY_DEFINITION`
 	NotNumeric                  = `EVAL_NOT_NUMERIC`
 	OperatorNotApplicable       = `EVAL_OPERATOR_NOT_APPLICABLE`
 	OperatorNotApplicableWhen   = `EVAL_OPERATOR_NOT_APPLICABLE_WHEN`
+	ParameterTypeMismatch       = `EVAL_PARAMETER_TYPE_MISMATCH`
+	ReservedParameter           = `EVAL_RESERVED_PARAMETER`
+	RestrictedFunction          = `EVAL_RESTRICTED_FUNCTION`
 	TaskBadJson                 = `EVAL_TASK_BAD_JSON`
 	TaskInitializerNotFound     = `EVAL_TASK_INITIALIZER_NOT_FOUND`
 	TaskNoExecutableFound       = `EVAL_TASK_NO_EXECUTABLE_FOUND`
 	TaskNotJsonObject           = `EVAL_TASK_NOT_JSON_OBJECT`
 	TaskTooManyFiles            = `EVAL_TASK_TOO_MANY_FILES`
+	TimespanOverflow            = `EVAL_TIMESPAN_OVERFLOW`
 	UnhandledExpression         = `EVAL_UNHANDLED_EXPRESSION`
+	UnknownClass                = `EVAL_UNKNOWN_CLASS`
+	UnknownEnvironment          = `EVAL_UNKNOWN_ENVIRONMENT`
+	UnknownFile                 = `EVAL_UNKNOWN_FILE`
+	UnknownNode                 = `EVAL_UNKNOWN_NODE`
+	UnknownParameter            = `EVAL_UNKNOWN_PARAMETER`
 	UnknownPlan                 = `EVAL_UNKNOWN_PLAN`
+	UnknownResource             = `EVAL_UNKNOWN_RESOURCE`
 	UnknownTask                 = `EVAL_UNKNOWN_TASK`
+	UnknownTemplate             = `EVAL_UNKNOWN_TEMPLATE`
+	UnresolvableDeferred        = `EVAL_UNRESOLVABLE_DEFERRED`
 )
 
 func init() {
+	issue.Hard(AttributeAlreadySet, `The attribute '%{attribute}' is already set on %{resource}; cannot redefine`)
+
+	issue.Hard2(AttributesNotHash, `The value of the '* =>' operator must be a Hash, got %{actual}`,
+		issue.HF{`actual`: issue.AnOrA})
+
+	issue.Hard(BadEnvironmentConf, `Unable to read environment configuration from '%{path}': %{detail}`)
+
+	issue.Hard(BadFactsFile, `Unable to read facts from '%{path}': %{detail}`)
+
+	issue.Hard(BadModuleMetadata, `Unable to read the metadata of module '%{module}' from '%{path}': %{detail}`)
+
+	issue.Hard(CatalogBadJson, `Unable to parse catalog from '%{path}': %{detail}`)
+
+	issue.Hard(CatalogNestedSensitive,
+		`%{resource}: parameter '%{name}' contains a nested Sensitive value. Only the value of a parameter can be Sensitive`)
+
+	issue.Hard(ConfigVersionFailed, `Unable to get the config_version of environment '%{environment}' using '%{command}': %{detail}`)
+
+	issue.Hard(CyclicValue, `The value at path %{path} contains itself`)
+
+	issue.Hard(DeferredResolutionFailed, `Unable to resolve the Deferred value at path %{path}: %{detail}`)
+
+	issue.Hard(DependencyCycle, `Found 1 dependency cycle: (%{cycle})`)
+
+	issue.Hard(DivisionByZero, `Division by 0`)
+
+	issue.Hard(DuplicateAlias, `Cannot alias %{resource} to '%{alias}'; %{type}[%{alias}] is already declared at %{file}:%{line}`)
+
+	issue.Hard(DuplicateAttribute, `The attribute '%{attribute}' has already been set in this resource body`)
+
+	issue.Hard(DuplicateNode, `Node '%{name}' is matched by more than one node definition. The other definition is at %{file}:%{line}`)
+
+	issue.Hard(DuplicateResource, `Duplicate declaration: %{type}[%{title}] is already declared at %{file}:%{line}; cannot redeclare`)
+
+	issue.Hard(EppRenderOutsideTemplate, `Text can only be rendered during the evaluation of an EPP template`)
+
 	issue.Hard2(IllegalArgument,
 		`Error when evaluating %{expression}, argument %{number}:  %{message}`, issue.HF{`expression`: issue.AnOrA})
 
@@ -57,12 +137,48 @@ func init() {
 
 	issue.Hard(IllegalReassignment, `Cannot reassign variable '$%{var}'`)
 
+	issue.Hard(IllegalReservedAssignment, `Attempt to assign to a reserved variable name: '$%{var}'`)
+
+	issue.Hard2(IllegalRelationshipOperand,
+		`Illegal relationship operand, can not form a relationship with %{actual}. A Catalog type is required`,
+		issue.HF{`actual`: issue.AnOrA})
+
+	issue.Hard2(IllegalResourceReference,
+		`Illegal resource reference. Expected a resource reference or an Array of resource references, got %{actual}`,
+		issue.HF{`actual`: issue.AnOrA})
+
+	issue.Hard2(IllegalResourceType, `Illegal resource type name. Expected a String or a Type, got %{actual}`,
+		issue.HF{`actual`: issue.AnOrA})
+
+	issue.Hard(IllegalStage, `Only classes can set 'stage'; normal resources like %{resource} cannot change run stage`)
+
+	issue.Hard2(IllegalTitleType, `Illegal title type at index %{index}. Expected String, got %{actual}`,
+		issue.HF{`actual`: issue.AnOrA})
+
+	issue.Hard(IntegerOverflow, `Integer overflow: %{value} is outside the range of a 64-bit Integer`)
+
+	issue.Hard(InvalidScanfFormat, `Invalid scanf format '%{format}': %{detail}`)
+
+	issue.Hard(MissingModuleDependency, `Module '%{module}' depends on module '%{dependency}' which is not found on the modulepath`)
+
 	issue.Hard(MissingMultiAssignmentKey, `No value for required key '%{name}' in assignment to variables from hash`)
 
+	issue.Hard(MissingParameter, `%{resource}: expects a value for parameter '%{name}'`)
+
 	issue.Hard(MissingRegexpInType, `Given Regexp Type has no regular expression`)
 
+	issue.Hard(ModuleNameClash, `Module '%{name}' in '%{path}' is ignored since a module with the same name is found in '%{first}'`)
+
+	issue.Hard(ModulePathConflict, `The settings 'modulepath' and 'module_path' cannot both be set. Use 'modulepath' to load modules that contain Puppet manifests`)
+
+	issue.Hard(ModuloByZero, `Modulo by 0`)
+
 	issue.Hard(NotCollectionAt, `The given data does not contain a Collection at %{walked_path}, got '%{klass}'`)
 
+	issue.Hard2(NotIterable, `Expected an Iterable or an Iterator, got %{actual}`, issue.HF{`actual`: issue.AnOrA})
+
+	issue.Hard(NotIterableRange, `The range of %{type} cannot be iterated since it is unbounded`)
+
 	issue.Hard(NotNumeric, `The value '%{value}' cannot be converted to Numeric`)
 
 	issue.Hard(NotOnlyDefinition, `The code loaded from %{source} must contain only the %{type} '%{name}`)
@@ -74,6 +190,13 @@ func init() {
 		`Operator '%{operator}' is not applicable to %{left} when right side is %{right}`,
 		issue.HF{`left`: issue.AnOrA, `right`: issue.AnOrA})
 
+	issue.Hard2(ParameterTypeMismatch, `%{resource}: parameter '%{name}' expects %{expected} value, got %{actual}`,
+		issue.HF{`expected`: issue.AnOrA})
+
+	issue.Hard(ReservedParameter, `The reserved variable name '$%{name}' cannot be used as a parameter name`)
+
+	issue.Hard(RestrictedFunction, `The function '%{name}' is not among the functions that a Deferred value can call`)
+
 	issue.Hard(TaskBadJson, `Unable to parse task metadata from '%{path}': %{detail}`)
 
 	issue.Hard(TaskInitializerNotFound, `Unable to load the initializer for the Task data`)
@@ -84,9 +207,27 @@ func init() {
 
 	issue.Hard(TaskTooManyFiles, `Only one file can exists besides the .json file for task %{name} in directory %{directory}`)
 
+	issue.Hard(TimespanOverflow, `The result of the '%{operator}' operation is outside the range of a Timespan`)
+
 	issue.Hard(UnhandledExpression, `Evaluator cannot handle an expression of type %<expression>T`)
 
+	issue.Hard(UnknownClass, `Could not find class '%{name}'`)
+
+	issue.Hard(UnknownEnvironment, `No environment directory found at '%{path}': %{detail}`)
+
+	issue.Hard(UnknownFile, `Could not find any files from %{names}`)
+
+	issue.Hard(UnknownNode, `Could not find a node definition that matches '%{name}' and no default node definition exists`)
+
+	issue.Hard(UnknownParameter, `%{resource}: has no parameter named '%{name}'`)
+
 	issue.Hard(UnknownPlan, `Unknown plan: '%{name}'`)
 
+	issue.Hard(UnknownResource, `Could not find resource '%{resource}' for overriding`)
+
 	issue.Hard(UnknownTask, `Task not found: '%{name}'`)
+
+	issue.Hard(UnknownTemplate, `Could not find template '%{name}'`)
+
+	issue.Hard(UnresolvableDeferred, `A %{type} cannot be resolved using a restricted set of functions`)
 }
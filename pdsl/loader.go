@@ -0,0 +1,76 @@
+package pdsl
+
+import (
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/pcore"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+// NsClass denotes a Puppet class
+const NsClass = px.Namespace(`class`)
+
+// NsDefinedType denotes a resource type that is declared in the Puppet language using the define keyword
+const NsDefinedType = px.Namespace(`defined_type`)
+
+// PuppetManifestPath denotes the manifests directory of an environment or a module. The directory contains
+// classes and defined types.
+const PuppetManifestPath = px.PathType(`puppetManifest`)
+
+// ModulePathSetting is the name of the setting that holds the modulepath, i.e. a list of directories that
+// contain modules, separated by the OS specific path list separator.
+//
+// The module_path setting of pcore is not used because it is a single directory whose modules are loaded by
+// the shared environment loader of pcore. That loader only knows about functions, types, plans and tasks, and
+// since it is the parent of the loaders of an evaluation, its flat view of the modules would take precedence
+// over the private loaders that give each module access to its own and its dependencies' definitions only.
+// The two settings are therefore mutually exclusive.
+const ModulePathSetting = `modulepath`
+
+// pcoreModulePathSetting is the name of the setting that pcore uses for its single modules directory
+const pcoreModulePathSetting = `module_path`
+
+func init() {
+	pcore.DefineSetting(ModulePathSetting, types.DefaultStringType(), nil)
+}
+
+// ModulePath returns the value of the modulepath setting and true, or an empty string and false when the
+// setting is not set. It panics with an issue.Reported if the module_path setting of pcore is also set.
+func ModulePath() (string, bool) {
+	mp, ok := pcore.Get(ModulePathSetting, nil).(px.StringValue)
+	if !ok {
+		return ``, false
+	}
+	if _, ok := pcore.Get(pcoreModulePathSetting, nil).(px.StringValue); ok {
+		panic(px.Error(ModulePathConflict, issue.NoArgs))
+	}
+	return mp.String(), true
+}
+
+// EnvironmentDirKey is the context variable that holds the directory of the environment that is being
+// evaluated, if any
+const EnvironmentDirKey = `puppet.environment_dir`
+
+// ModuleDir returns the directory of the module with the given name, or an empty string if the loader of the
+// given context cannot find such a module.
+func ModuleDir(c px.Context, name string) string {
+	l := c.Loader()
+	for l != nil {
+		switch ml := l.(type) {
+		case px.DependencyLoader:
+			if ml := ml.LoaderFor(name); ml != nil {
+				return ml.Path()
+			}
+		case px.ModuleLoader:
+			if ml.ModuleName() == name {
+				return ml.Path()
+			}
+		}
+		pl, ok := l.(px.ParentedLoader)
+		if !ok {
+			break
+		}
+		l = pl.Parent()
+	}
+	return ``
+}
@@ -0,0 +1,168 @@
+package pdsl
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/pcore"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/puppet-evaluator/rubyregexp"
+)
+
+// RubyRegexpSetting is the name of the boolean setting that, when true, makes the match operators, the
+// case and selector expressions, and the match and split functions use regular expressions with Ruby
+// syntax and semantics instead of those of the Go regexp package.
+const RubyRegexpSetting = `ruby_regexp`
+
+func init() {
+	pcore.DefineSetting(RubyRegexpSetting, types.DefaultBooleanType(), types.WrapBoolean(false))
+}
+
+// Regexp is a compiled regular expression. It is implemented by both *regexp.Regexp and *rubyregexp.Regexp
+type Regexp interface {
+	String() string
+	MatchString(s string) bool
+	FindStringSubmatch(s string) []string
+	FindStringSubmatchIndex(s string) []int
+	FindAllStringSubmatchIndex(s string, n int) [][]int
+	NumSubexp() int
+	SubexpNames() []string
+}
+
+// RubyRegexps returns the value of the ruby_regexp setting
+func RubyRegexps() bool {
+	b, ok := pcore.Get(RubyRegexpSetting, nil).(px.Boolean)
+	return ok && b.Bool()
+}
+
+// CompileRegexp compiles the given pattern using the syntax selected by the ruby_regexp setting
+func CompileRegexp(pattern string) (Regexp, error) {
+	if RubyRegexps() {
+		return rubyregexp.Compile(pattern)
+	}
+	return regexp.Compile(pattern)
+}
+
+// CompileRegexpOptions is like CompileRegexp but also applies the given options. The options are the
+// letters of the Ruby regexp options i (ignore case), m (dot matches newline), and x (extended syntax).
+func CompileRegexpOptions(pattern, options string) (Regexp, error) {
+	if options == `` {
+		return CompileRegexp(pattern)
+	}
+	if RubyRegexps() {
+		return rubyregexp.Compile(`(?` + options + `)` + pattern)
+	}
+	flags := ``
+	if strings.ContainsRune(options, 'i') {
+		flags += `i`
+	}
+	if strings.ContainsRune(options, 'm') {
+		flags += `s`
+	}
+	if strings.ContainsRune(options, 'x') {
+		pattern = stripExtended(pattern)
+	}
+	if flags != `` {
+		pattern = `(?` + flags + `)` + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+// stripExtended removes the whitespace and comments that the extended syntax allows outside of
+// character classes, since the Go regexp package doesn't support that syntax
+func stripExtended(pattern string) string {
+	b := strings.Builder{}
+	inClass := false
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			if pattern[i] == ' ' && !inClass {
+				b.WriteByte(' ')
+			} else {
+				b.WriteByte(c)
+				b.WriteByte(pattern[i])
+			}
+			continue
+		case inClass:
+			inClass = c != ']'
+		case c == '[':
+			// A ']' that is first in the class is a literal
+			inClass = true
+			b.WriteByte(c)
+			if i+1 < len(pattern) && pattern[i+1] == '^' {
+				i++
+				b.WriteByte('^')
+			}
+			if i+1 < len(pattern) && pattern[i+1] == ']' {
+				i++
+				b.WriteByte(']')
+			}
+			continue
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v':
+			continue
+		case c == '#':
+			for i < len(pattern) && pattern[i] != '\n' {
+				i++
+			}
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// WrapRegexp creates a Regexp value from the given pattern using the syntax selected by the ruby_regexp
+// setting. The value of a Ruby expression holds the Go regexp returned by its Value method. Code that
+// matches using the value must use RegexpOf to get the Ruby expression.
+func WrapRegexp(pattern string) *types.Regexp {
+	if RubyRegexps() {
+		rx, err := rubyregexp.Compile(pattern)
+		if err != nil {
+			panic(px.Error(px.InvalidRegexp, issue.H{`pattern`: pattern, `detail`: err.Error()}))
+		}
+		return types.WrapRegexp2(rx.Value())
+	}
+	return types.WrapRegexp(pattern)
+}
+
+// RegexpOf returns the compiled regular expression that should be used when matching with the given
+// Regexp value. The value's own Go regexp is used when the ruby_regexp setting is false or when the
+// value was not created from a valid Ruby expression.
+func RegexpOf(rx *types.Regexp) Regexp {
+	if RubyRegexps() {
+		if rrx, err := rubyregexp.Compile(rx.PatternString()); err == nil {
+			return rrx
+		}
+	}
+	return rx.Regexp()
+}
+
+// MatchPattern returns true if the given string matches one of the regular expressions of the given Pattern
+// type, or if the type has no regular expressions. The expressions are matched as RegexpOf selects.
+func MatchPattern(t *types.PatternType, s string) bool {
+	patterns := t.Patterns()
+	if patterns.Len() == 0 {
+		return true
+	}
+	return patterns.Any(func(p px.Value) bool {
+		return RegexpOf(types.WrapRegexp2(p.(*types.RegexpType).Regexp())).MatchString(s)
+	})
+}
+
+// Split splits the given string around the matches of the given regular expression. A Ruby expression
+// splits the way Ruby's String#split does.
+func Split(s px.StringValue, rx Regexp) px.List {
+	if rrx, ok := rx.(*rubyregexp.Regexp); ok {
+		parts := rrx.Split(s.String(), 0)
+		result := make([]px.Value, len(parts))
+		for i, p := range parts {
+			result[i] = types.WrapString(p)
+		}
+		return types.WrapValues(result)
+	}
+	return s.Split(rx.(*regexp.Regexp))
+}
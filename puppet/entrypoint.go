@@ -10,8 +10,32 @@ import (
 	_ "github.com/lyraproj/puppet-evaluator/functions"
 )
 
+// Do calls the given function with an evaluation context. When the modulepath setting is set, the loader
+// of the context loads from the modules found on that path. The modulepath setting cannot be combined with
+// the module_path setting of pcore.
 func Do(f func(ctx pdsl.EvaluationContext)) {
 	pcore.Do(func(c px.Context) {
-		f(evaluator.WithParent(c, evaluator.NewEvaluator))
+		ec := evaluator.WithParent(c, evaluator.NewEvaluator)
+		if mp, ok := pdsl.ModulePath(); ok {
+			ec.DoWithLoader(evaluator.NewModulePathLoader(ec, ec.Loader(), mp), func() { f(ec) })
+			return
+		}
+		f(ec)
+	})
+}
+
+// DoWithEnvironment calls the given function with an evaluation context that is wired to the environment in
+// the given directory. The loader of the context loads from the environment directory and from the modules
+// on the modulepath of the environment. The *evaluator.Environment is available in the context variable
+// evaluator.EnvironmentKey and its directory, which is where the hiera.yaml of the environment is found, in
+// the context variable pdsl.EnvironmentDirKey. No settings are changed so environments that are evaluated
+// one after another, or concurrently, don't affect each other.
+func DoWithEnvironment(path string, f func(ctx pdsl.EvaluationContext)) {
+	env := evaluator.LoadEnvironment(path)
+	pcore.Do(func(c px.Context) {
+		ec := evaluator.WithParent(c, evaluator.NewEvaluator)
+		ec.Set(evaluator.EnvironmentKey, env)
+		ec.Set(pdsl.EnvironmentDirKey, env.Path)
+		ec.DoWithLoader(env.NewLoader(ec, ec.Loader()), func() { f(ec) })
 	})
 }
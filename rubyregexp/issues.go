@@ -0,0 +1,11 @@
+package rubyregexp
+
+import "github.com/lyraproj/issue/issue"
+
+const (
+	MatchLimitExceeded = `RUBYREGEXP_MATCH_LIMIT_EXCEEDED`
+)
+
+func init() {
+	issue.Hard(MatchLimitExceeded, `Matching /%{pattern}/ was abandoned after %{steps} steps. The expression backtracks too much on the given input`)
+}
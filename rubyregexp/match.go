@@ -0,0 +1,439 @@
+package rubyregexp
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+)
+
+// maxSteps is the number of instructions that the backtracking matcher may execute in one search before
+// it gives up with a MatchLimitExceeded error. Since every entry on the backtrack stack is pushed by an
+// instruction, the limit also bounds the memory used by a search.
+const maxSteps = 5000000
+
+type opcode uint8
+
+const (
+	// opRune matches the rune r, ignoring case when flag is set
+	opRune opcode = iota
+
+	// opClass matches a rune of the class
+	opClass
+
+	// opAny matches any rune. A newline is only matched when flag is set.
+	opAny
+
+	// opAssert matches the empty string at a position where the assertion holds
+	opAssert
+
+	// opSplit continues at out and, on failure, at alt
+	opSplit
+
+	// opJmp continues at out
+	opJmp
+
+	// opMark stores the current position in register arg
+	opMark
+
+	// opCapture sets the positions of group arg to the position in register arg2 and the current position
+	opCapture
+
+	// opRepeatInit sets the iteration counter in register arg to zero
+	opRepeatInit
+
+	// opRepeat decides whether the repetition with the counter in register arg iterates once more, in
+	// which case the body starts at the next instruction, or continues at alt
+	opRepeat
+
+	// opRepeatEnd ends an iteration of the repetition that starts at out
+	opRepeatEnd
+
+	// opAtomic matches the body that starts at the next instruction without backtracking into it and
+	// then continues at alt
+	opAtomic
+
+	// opLook matches the empty string when the body that starts at the next instruction matches ahead of,
+	// or, when flag is set, behind the current position. The result is negated when negate is set.
+	opLook
+
+	// opBackref matches the text of group arg, ignoring case when flag is set
+	opBackref
+
+	// opSucceed ends a successful match of the whole expression or of the body of an atomic group or a
+	// look-around
+	opSucceed
+)
+
+type inst struct {
+	op     opcode
+	out    int
+	alt    int
+	arg    int
+	arg2   int
+	min    int
+	max    int
+	flag   bool
+	negate bool
+	r      rune
+	class  *charClass
+	assert assertion
+}
+
+// program is the expression compiled to instructions for the backtracking matcher
+type program struct {
+	insts []inst
+
+	// nregs is the number of registers. The first registers hold the positions of the capture groups.
+	nregs int
+}
+
+func compile(root node, ncap int) *program {
+	c := &program{nregs: 2 * ncap}
+	c.compile(root)
+	c.emit(inst{op: opSucceed})
+	return c
+}
+
+func (c *program) emit(in inst) int {
+	c.insts = append(c.insts, in)
+	return len(c.insts) - 1
+}
+
+func (c *program) register() int {
+	c.nregs++
+	return c.nregs - 1
+}
+
+func (c *program) compile(n node) {
+	switch n := n.(type) {
+	case *literal:
+		c.emit(inst{op: opRune, r: n.r, flag: n.fold})
+	case *charClass:
+		c.emit(inst{op: opClass, class: n})
+	case *anyChar:
+		c.emit(inst{op: opAny, flag: n.multiline})
+	case assertion:
+		c.emit(inst{op: opAssert, assert: n})
+	case concat:
+		for _, e := range n {
+			c.compile(e)
+		}
+	case alternation:
+		if len(n) == 0 {
+			return
+		}
+		var jumps []int
+		for _, e := range n[:len(n)-1] {
+			split := c.emit(inst{op: opSplit})
+			c.insts[split].out = split + 1
+			c.compile(e)
+			jumps = append(jumps, c.emit(inst{op: opJmp}))
+			c.insts[split].alt = len(c.insts)
+		}
+		c.compile(n[len(n)-1])
+		for _, j := range jumps {
+			c.insts[j].out = len(c.insts)
+		}
+	case *group:
+		if n.index == 0 {
+			c.compile(n.body)
+			return
+		}
+		// The positions of the group are set when its body has matched so that a back reference to the
+		// group within the body refers to the previous match of the group
+		start := c.register()
+		c.emit(inst{op: opMark, arg: start})
+		c.compile(n.body)
+		c.emit(inst{op: opCapture, arg: n.index, arg2: start})
+	case *repeat:
+		counter := c.register()
+		start := c.register()
+		c.emit(inst{op: opRepeatInit, arg: counter})
+		loop := c.emit(inst{op: opRepeat, arg: counter, min: n.min, max: n.max, flag: n.lazy})
+		c.emit(inst{op: opMark, arg: start})
+		c.compile(n.body)
+		c.emit(inst{op: opRepeatEnd, out: loop, arg: counter, arg2: start, min: n.min})
+		c.insts[loop].alt = len(c.insts)
+	case *atomic:
+		at := c.emit(inst{op: opAtomic})
+		c.compile(n.body)
+		c.emit(inst{op: opSucceed})
+		c.insts[at].alt = len(c.insts)
+	case *look:
+		at := c.emit(inst{op: opLook, flag: n.behind, negate: n.negate})
+		c.compile(n.body)
+		c.emit(inst{op: opSucceed})
+		c.insts[at].alt = len(c.insts)
+	case *backref:
+		c.emit(inst{op: opBackref, arg: n.index, flag: n.fold})
+	}
+}
+
+// frame is an entry on the backtrack stack. It either resumes the match at pc and pos, or, when pc is
+// negative, restores the value pos of register reg.
+type frame struct {
+	pc  int32
+	reg int32
+	pos int
+}
+
+// machine is a backtracking matcher that executes a compiled expression. It is used for expressions that
+// cannot be translated to the Go regexp syntax. Alternatives that remain to be tried are kept on an
+// explicit stack so that the depth of the Go stack doesn't depend on the input.
+type machine struct {
+	re    *Regexp
+	input string
+	start int
+	regs  []int
+	stack []frame
+	steps int
+}
+
+// exec returns the capture positions of the leftmost match that starts at or after the given position,
+// or nil when there is no match
+func exec(re *Regexp, input string, from int) []int {
+	m := &machine{re: re, input: input, start: from, regs: make([]int, re.prog.nregs)}
+	ncap := 2 * len(re.names)
+	for pos := from; pos <= len(input); {
+		for i := range m.regs {
+			m.regs[i] = -1
+		}
+		m.stack = m.stack[:0]
+		if end, ok := m.run(0, pos, -1); ok {
+			m.regs[0], m.regs[1] = pos, end
+			return m.regs[:ncap:ncap]
+		}
+		if pos == len(input) {
+			break
+		}
+		_, size := utf8.DecodeRuneInString(input[pos:])
+		pos += size
+	}
+	return nil
+}
+
+// run executes the instructions from pc until an opSucceed is reached at the target position, or at
+// any position when the target is negative. It returns the position where the match ended and true, or
+// false when all alternatives have failed. The registers are restored when the match fails.
+func (m *machine) run(pc, pos, target int) (int, bool) {
+	insts := m.re.prog.insts
+	base := len(m.stack)
+	for {
+		if m.steps++; m.steps > maxSteps {
+			panic(px.Error(MatchLimitExceeded, issue.H{`pattern`: m.re.expr, `steps`: maxSteps}))
+		}
+		in := &insts[pc]
+		ok := true
+		switch in.op {
+		case opRune:
+			r, size := utf8.DecodeRuneInString(m.input[pos:])
+			if ok = size > 0 && (r == in.r || in.flag && equalFold(r, in.r)); ok {
+				pos += size
+				pc++
+			}
+		case opClass:
+			r, size := utf8.DecodeRuneInString(m.input[pos:])
+			if ok = size > 0 && in.class.matches(r); ok {
+				pos += size
+				pc++
+			}
+		case opAny:
+			r, size := utf8.DecodeRuneInString(m.input[pos:])
+			if ok = size > 0 && (in.flag || r != '\n'); ok {
+				pos += size
+				pc++
+			}
+		case opAssert:
+			if ok = m.assert(in.assert, pos); ok {
+				pc++
+			}
+		case opSplit:
+			m.push(in.alt, pos)
+			pc = in.out
+		case opJmp:
+			pc = in.out
+		case opMark:
+			m.set(in.arg, pos)
+			pc++
+		case opCapture:
+			m.set(2*in.arg, m.regs[in.arg2])
+			m.set(2*in.arg+1, pos)
+			pc++
+		case opRepeatInit:
+			m.set(in.arg, 0)
+			pc++
+		case opRepeat:
+			count := m.regs[in.arg]
+			switch {
+			case count < in.min:
+				pc++
+			case in.max >= 0 && count >= in.max:
+				pc = in.alt
+			case in.flag:
+				m.push(pc+1, pos)
+				pc = in.alt
+			default:
+				m.push(in.alt, pos)
+				pc++
+			}
+		case opRepeatEnd:
+			// An iteration beyond the minimum must consume input, or the repetition would never end
+			count := m.regs[in.arg]
+			if ok = pos != m.regs[in.arg2] || count < in.min; ok {
+				m.set(in.arg, count+1)
+				pc = in.out
+			}
+		case opAtomic:
+			var end int
+			if end, ok = m.sub(pc+1, pos, -1, false); ok {
+				pos = end
+				pc = in.alt
+			}
+		case opLook:
+			found := false
+			if in.flag {
+				for start := pos; start >= 0 && !found; start-- {
+					if start < len(m.input) && !utf8.RuneStart(m.input[start]) {
+						continue
+					}
+					_, found = m.sub(pc+1, start, pos, in.negate)
+				}
+			} else {
+				_, found = m.sub(pc+1, pos, -1, in.negate)
+			}
+			if ok = found != in.negate; ok {
+				pc = in.alt
+			}
+		case opBackref:
+			var end int
+			if end, ok = m.backref(in, pos); ok {
+				pos = end
+				pc++
+			}
+		case opSucceed:
+			if target < 0 || pos == target {
+				return pos, true
+			}
+			ok = false
+		}
+		if ok {
+			continue
+		}
+		if pc, pos, ok = m.backtrack(base); !ok {
+			return -1, false
+		}
+	}
+}
+
+// sub matches the body of an atomic group or a look-around. The alternatives of the body are discarded
+// when it matches, but the registers that it changed are restored when the match backtracks past it. The
+// registers are always restored when discard is true.
+func (m *machine) sub(pc, pos, target int, discard bool) (int, bool) {
+	saved := make([]int, len(m.regs))
+	copy(saved, m.regs)
+	base := len(m.stack)
+	end, ok := m.run(pc, pos, target)
+	if !ok {
+		return end, false
+	}
+	m.stack = m.stack[:base]
+	if discard {
+		copy(m.regs, saved)
+	} else {
+		for i, v := range saved {
+			if m.regs[i] != v {
+				m.stack = append(m.stack, frame{pc: -1, reg: int32(i), pos: v})
+			}
+		}
+	}
+	return end, true
+}
+
+// backtrack pops the stack down to the given base until it finds an alternative to resume. It returns
+// false when no alternative remains.
+func (m *machine) backtrack(base int) (int, int, bool) {
+	for len(m.stack) > base {
+		f := m.stack[len(m.stack)-1]
+		m.stack = m.stack[:len(m.stack)-1]
+		if f.pc >= 0 {
+			return int(f.pc), f.pos, true
+		}
+		m.regs[f.reg] = f.pos
+	}
+	return 0, 0, false
+}
+
+func (m *machine) push(pc, pos int) {
+	m.stack = append(m.stack, frame{pc: int32(pc), pos: pos})
+}
+
+// set assigns a register and pushes a frame that restores its current value on backtracking
+func (m *machine) set(reg, v int) {
+	m.stack = append(m.stack, frame{pc: -1, reg: int32(reg), pos: m.regs[reg]})
+	m.regs[reg] = v
+}
+
+func (m *machine) backref(in *inst, pos int) (int, bool) {
+	start, end := m.regs[2*in.arg], m.regs[2*in.arg+1]
+	if start < 0 {
+		// A reference to a group that did not participate in the match fails
+		return pos, false
+	}
+	for _, r := range m.input[start:end] {
+		ir, size := utf8.DecodeRuneInString(m.input[pos:])
+		if size == 0 || !(ir == r || in.flag && equalFold(ir, r)) {
+			return pos, false
+		}
+		pos += size
+	}
+	return pos, true
+}
+
+func (m *machine) assert(a assertion, pos int) bool {
+	switch a {
+	case beginLine:
+		return pos == 0 || m.input[pos-1] == '\n'
+	case endLine:
+		return pos == len(m.input) || m.input[pos] == '\n'
+	case beginText:
+		return pos == 0
+	case endText:
+		return pos == len(m.input)
+	case endTextOptionalNewline:
+		return pos == len(m.input) || pos == len(m.input)-1 && m.input[pos] == '\n'
+	case wordBoundary:
+		return m.isWordAt(pos-1) != m.isWordAt(pos)
+	case nonWordBoundary:
+		return m.isWordAt(pos-1) == m.isWordAt(pos)
+	default:
+		return pos == m.start
+	}
+}
+
+func (m *machine) isWordAt(pos int) bool {
+	return pos >= 0 && pos < len(m.input) && wordSet.contains(rune(m.input[pos]))
+}
+
+func (c *charClass) matches(r rune) bool {
+	found := c.set.contains(r)
+	if !found && c.fold {
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			if c.set.contains(f) {
+				found = true
+				break
+			}
+		}
+	}
+	return found != c.negate
+}
+
+func equalFold(a, b rune) bool {
+	for f := unicode.SimpleFold(a); f != a; f = unicode.SimpleFold(f) {
+		if f == b {
+			return true
+		}
+	}
+	return false
+}
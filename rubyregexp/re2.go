@@ -0,0 +1,154 @@
+package rubyregexp
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// maxRE2Repeat is the largest repetition count accepted by the Go regexp package
+const maxRE2Repeat = 1000
+
+// translate returns the expression in Go regexp syntax that is equivalent to the given node, or false
+// if the node uses constructs that have no equivalent.
+func translate(n node) (string, bool) {
+	b := &strings.Builder{}
+	if !writeRE2(b, n) {
+		return ``, false
+	}
+	return b.String(), true
+}
+
+func writeRE2(b *strings.Builder, n node) bool {
+	switch n := n.(type) {
+	case *literal:
+		if n.fold {
+			b.WriteString(`(?i:`)
+		}
+		writeRE2Rune(b, n.r)
+		if n.fold {
+			b.WriteByte(')')
+		}
+	case *charClass:
+		writeRE2Class(b, n)
+	case *anyChar:
+		if n.multiline {
+			b.WriteString(`(?s:.)`)
+		} else {
+			b.WriteByte('.')
+		}
+	case assertion:
+		switch n {
+		case beginLine:
+			b.WriteString(`(?m:^)`)
+		case endLine:
+			b.WriteString(`(?m:$)`)
+		case beginText:
+			b.WriteString(`\A`)
+		case endText:
+			b.WriteString(`\z`)
+		case wordBoundary:
+			b.WriteString(`\b`)
+		case nonWordBoundary:
+			b.WriteString(`\B`)
+		default:
+			return false
+		}
+	case *group:
+		if n.name != `` {
+			b.WriteString(`(?P<`)
+			b.WriteString(n.name)
+			b.WriteByte('>')
+		} else {
+			b.WriteByte('(')
+		}
+		if !writeRE2(b, n.body) {
+			return false
+		}
+		b.WriteByte(')')
+	case concat:
+		for _, e := range n {
+			if !writeRE2(b, e) {
+				return false
+			}
+		}
+	case alternation:
+		b.WriteString(`(?:`)
+		for i, e := range n {
+			if i > 0 {
+				b.WriteByte('|')
+			}
+			if !writeRE2(b, e) {
+				return false
+			}
+		}
+		b.WriteByte(')')
+	case *repeat:
+		if n.min > maxRE2Repeat || n.max > maxRE2Repeat {
+			return false
+		}
+		b.WriteString(`(?:`)
+		if !writeRE2(b, n.body) {
+			return false
+		}
+		b.WriteByte(')')
+		switch {
+		case n.min == 0 && n.max < 0:
+			b.WriteByte('*')
+		case n.min == 1 && n.max < 0:
+			b.WriteByte('+')
+		case n.min == 0 && n.max == 1:
+			b.WriteByte('?')
+		case n.max < 0:
+			fmt.Fprintf(b, `{%d,}`, n.min)
+		case n.min == n.max:
+			fmt.Fprintf(b, `{%d}`, n.min)
+		default:
+			fmt.Fprintf(b, `{%d,%d}`, n.min, n.max)
+		}
+		if n.lazy {
+			b.WriteByte('?')
+		}
+	default:
+		// Atomic groups, look-around, and back references
+		return false
+	}
+	return true
+}
+
+func writeRE2Rune(b *strings.Builder, r rune) {
+	if r < ' ' || r == 0x7f || !unicode.IsPrint(r) {
+		fmt.Fprintf(b, `\x{%x}`, r)
+	} else {
+		b.WriteString(regexp.QuoteMeta(string(r)))
+	}
+}
+
+func writeRE2Class(b *strings.Builder, c *charClass) {
+	if len(c.set) == 0 {
+		if c.negate {
+			b.WriteString(`(?s:.)`)
+		} else {
+			b.WriteString(`[^\x00-\x{10ffff}]`)
+		}
+		return
+	}
+	if c.fold {
+		b.WriteString(`(?i:`)
+	}
+	b.WriteByte('[')
+	if c.negate {
+		b.WriteByte('^')
+	}
+	for i := 0; i < len(c.set); i += 2 {
+		fmt.Fprintf(b, `\x{%x}`, c.set[i])
+		if c.set[i+1] != c.set[i] {
+			fmt.Fprintf(b, `-\x{%x}`, c.set[i+1])
+		}
+	}
+	b.WriteByte(']')
+	if c.fold {
+		b.WriteByte(')')
+	}
+}
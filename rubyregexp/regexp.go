@@ -0,0 +1,249 @@
+// Package rubyregexp implements regular expressions with the syntax and semantics of Ruby (Onigmo).
+//
+// An expression is parsed using the Ruby syntax. If the expression only uses constructs that have an
+// equivalent in the Go regexp package, it is translated and matched by that package. Expressions that
+// use look-around, back references, atomic groups, possessive quantifiers, \Z, or \G are matched by a
+// backtracking matcher.
+package rubyregexp
+
+import (
+	"reflect"
+	"regexp"
+	"sync"
+	"unicode/utf8"
+	"unsafe"
+)
+
+// Regexp is a compiled regular expression. It is safe for concurrent use.
+type Regexp struct {
+	expr  string
+	names []string
+	root  node
+
+	// re2 is the translated expression, or nil when the expression must be matched by the backtracking
+	// matcher
+	re2 *regexp.Regexp
+
+	// prog is the program of the backtracking matcher, or nil when the expression has been translated
+	prog *program
+
+	// value represents the expression where a Go regexp is required
+	value *regexp.Regexp
+}
+
+// maxCached is the number of compiled expressions that the cache holds
+const maxCached = 512
+
+var cache = struct {
+	sync.RWMutex
+	entries map[string]*Regexp
+}{entries: make(map[string]*Regexp, maxCached)}
+
+// neverMatch is the Go regexp that represents expressions that have no translation
+var neverMatch = regexp.MustCompile(`[^\x00-\x{10ffff}]`)
+
+// Compile parses a Ruby regular expression. Compiled expressions are cached.
+func Compile(expr string) (*Regexp, error) {
+	cache.RLock()
+	re, ok := cache.entries[expr]
+	cache.RUnlock()
+	if ok {
+		return re, nil
+	}
+	root, names, err := parse(expr)
+	if err != nil {
+		return nil, err
+	}
+	re = &Regexp{expr: expr, names: names, root: root}
+	if s, ok := translate(root); ok {
+		// The translation may still be rejected, e.g. when a group name is used twice
+		re.re2, _ = regexp.Compile(s)
+	}
+	if re.re2 == nil {
+		re.prog = compile(root, len(names))
+		re.value = withSource(neverMatch, expr)
+	} else {
+		re.value = withSource(re.re2, expr)
+	}
+
+	cache.Lock()
+	if len(cache.entries) >= maxCached {
+		// Evict an arbitrary entry
+		for k := range cache.entries {
+			delete(cache.entries, k)
+			break
+		}
+	}
+	cache.entries[expr] = re
+	cache.Unlock()
+	return re, nil
+}
+
+// MustCompile is like Compile but panics if the expression cannot be parsed
+func MustCompile(expr string) *Regexp {
+	re, err := Compile(expr)
+	if err != nil {
+		panic(err)
+	}
+	return re
+}
+
+// String returns the source text of the expression
+func (re *Regexp) String() string {
+	return re.expr
+}
+
+// NumSubexp returns the number of capture groups
+func (re *Regexp) NumSubexp() int {
+	return len(re.names) - 1
+}
+
+// SubexpNames returns the names of the capture groups. The first name is the name of the whole match
+// and is always empty, as is the name of an unnamed group.
+func (re *Regexp) SubexpNames() []string {
+	return re.names
+}
+
+// MatchString returns true if the given string contains a match
+func (re *Regexp) MatchString(s string) bool {
+	return re.index(s) != nil
+}
+
+// FindStringSubmatchIndex returns the start and end positions of the leftmost match and of each of its
+// capture groups, or nil if there is no match. The positions of a group that didn't participate in the
+// match are -1.
+func (re *Regexp) FindStringSubmatchIndex(s string) []int {
+	return re.index(s)
+}
+
+// FindStringSubmatch returns the text of the leftmost match and of each of its capture groups, or nil if
+// there is no match. The text of a group that didn't participate in the match is empty.
+func (re *Regexp) FindStringSubmatch(s string) []string {
+	loc := re.index(s)
+	if loc == nil {
+		return nil
+	}
+	group := make([]string, len(loc)/2)
+	for i := range group {
+		if loc[2*i] >= 0 {
+			group[i] = s[loc[2*i]:loc[2*i+1]]
+		}
+	}
+	return group
+}
+
+// FindAllStringSubmatchIndex returns the positions of at most n successive non-overlapping matches, or
+// of all matches when n is negative. As with the Go regexp package, an empty match that abuts a
+// preceding match is ignored.
+func (re *Regexp) FindAllStringSubmatchIndex(s string, n int) [][]int {
+	if re.re2 != nil {
+		return re.re2.FindAllStringSubmatchIndex(s, n)
+	}
+	var result [][]int
+	for pos, prevEnd := 0, -1; (n < 0 || len(result) < n) && pos <= len(s); {
+		loc := exec(re, s, pos)
+		if loc == nil {
+			break
+		}
+		accept := true
+		if loc[1] == pos {
+			if loc[0] == prevEnd {
+				accept = false
+			}
+			if pos < len(s) {
+				_, size := utf8.DecodeRuneInString(s[pos:])
+				pos += size
+			} else {
+				pos++
+			}
+		} else {
+			pos = loc[1]
+		}
+		prevEnd = loc[1]
+		if accept {
+			result = append(result, loc)
+		}
+	}
+	return result
+}
+
+// Split splits the given string around the matches of the expression the way Ruby's String#split does.
+// The text of the capture groups of each match is included in the result. A limit of zero means no limit
+// and removal of trailing empty strings. A positive limit is the maximum number of strings returned, and
+// a negative limit means no limit and no removal.
+func (re *Regexp) Split(s string, limit int) []string {
+	var result []string
+	n := -1
+	if limit > 0 {
+		if limit == 1 {
+			if s == `` {
+				return []string{}
+			}
+			return []string{s}
+		}
+		n = limit - 1
+	}
+	beg := 0
+	for _, loc := range re.FindAllStringSubmatchIndex(s, n) {
+		if loc[1] == 0 {
+			// An empty match at the start does not produce an empty leading string
+			continue
+		}
+		result = append(result, s[beg:loc[0]])
+		for i := 2; i < len(loc); i += 2 {
+			if loc[i] >= 0 {
+				result = append(result, s[loc[i]:loc[i+1]])
+			}
+		}
+		beg = loc[1]
+	}
+	if beg < len(s) || limit != 0 {
+		result = append(result, s[beg:])
+	}
+	if limit == 0 {
+		for len(result) > 0 && result[len(result)-1] == `` {
+			result = result[:len(result)-1]
+		}
+	}
+	if result == nil {
+		result = []string{}
+	}
+	return result
+}
+
+// Go returns the translation of this expression to Go syntax, or nil when the expression uses syntax that
+// has no Go equivalent, such as look-behind, back references, or atomic groups
+func (re *Regexp) Go() *regexp.Regexp {
+	return re.re2
+}
+
+// Value returns a Go regexp that represents this expression where a *regexp.Regexp is required, such as in
+// the Regexp values of pcore. The source of the Go regexp is the source of this expression, so String
+// returns the Ruby source and Compile of that source returns this expression. The Go regexp matches as the
+// translation of the expression, or never when the expression has no translation.
+func (re *Regexp) Value() *regexp.Regexp {
+	return re.value
+}
+
+// withSource returns a copy of the given Go regexp with the given source. The regexp package has no API to
+// set the source, so the unexported field that holds it is assigned directly. The given regexp is returned
+// unchanged if the field cannot be found.
+func withSource(rx *regexp.Regexp, source string) *regexp.Regexp {
+	if rx.String() == source {
+		return rx
+	}
+	c := *rx
+	f := reflect.ValueOf(&c).Elem().FieldByName(`expr`)
+	if f.Kind() != reflect.String {
+		return rx
+	}
+	*(*string)(unsafe.Pointer(f.UnsafeAddr())) = source
+	return &c
+}
+
+func (re *Regexp) index(s string) []int {
+	if re.re2 != nil {
+		return re.re2.FindStringSubmatchIndex(s)
+	}
+	return exec(re, s, 0)
+}
@@ -0,0 +1,184 @@
+package rubyregexp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lyraproj/issue/issue"
+)
+
+type matchTest struct {
+	pattern string
+	input   string
+
+	// expected is the text of the match and of its groups, or nil when there is no match
+	expected []string
+
+	// translated is true when the expression is expected to be matched by the Go regexp package
+	translated bool
+}
+
+func TestMatch(t *testing.T) {
+	for _, tt := range []matchTest{
+		// Look-behind
+		{`(?<=x)a`, `ba xa`, []string{`a`}, false},
+		{`(?<=x)a`, `ba`, nil, false},
+		{`(?<!x)a`, `xa ba`, []string{`a`}, false},
+		{`(?<=ab|c)d`, `abd`, []string{`d`}, false},
+		{`(?<=é)t`, `été`, []string{`t`}, false},
+
+		// Look-ahead
+		{`\w+(?=!)`, `hi there!`, []string{`there`}, false},
+		{`\d(?!\d)`, `123`, []string{`3`}, false},
+		{`(?=(a+))a*b\1`, `baaabac`, []string{`aba`, `a`}, false},
+
+		// Back references
+		{`(\w)\1`, `abccd`, []string{`cc`, `c`}, false},
+		{`(?<q>['"]).*?\k<q>`, `say "it's" now`, []string{`"it's"`, `"`}, false},
+		{`(?i)(a)\1`, `aA`, []string{`aA`, `a`}, false},
+		{`(a)|\1b`, `b`, nil, false},
+		{`(a\1?)+`, `aaa`, []string{`aaa`, `aa`}, false},
+
+		// Hex digits
+		{`\h+`, `xyz 0fA9g`, []string{`0fA9`}, true},
+		{`\H+`, `0fxyz`, []string{`xyz`}, true},
+		{`[\h-]+`, `zz12-ab`, []string{`12-ab`}, true},
+
+		// Possessive quantifiers and atomic groups
+		{`a++a`, `aaaa`, nil, false},
+		{`a*+b`, `aaab`, []string{`aaab`}, false},
+		{`"[^"]*+"`, `"abc"`, []string{`"abc"`}, false},
+		{`(?>a|ab)c`, `abc`, nil, false},
+		{`(?>ab|a)c`, `abc`, []string{`abc`}, false},
+
+		// Options
+		{`(?i)hello`, `HeLLo`, []string{`HeLLo`}, true},
+		{`a(?i)b|c`, `aB`, []string{`aB`}, true},
+		{`a(?i:b)c`, `aBc`, []string{`aBc`}, true},
+		{`a(?i:b)c`, `aBC`, nil, true},
+		{`(?m)a.b`, "a\nb", []string{"a\nb"}, true},
+		{`a.b`, "a\nb", nil, true},
+		{`^b`, "a\nb", []string{`b`}, true},
+		{`(?x) a b # comment`, `ab`, []string{`ab`}, true},
+
+		// Anchors
+		{`a\Z`, "a\n", []string{`a`}, false},
+		{`a\z`, "a\n", nil, true},
+		{`\Aa`, `ba`, nil, true},
+
+		// Plain groups don't capture when there are named groups
+		{`(?<a>x)(y)`, `xy`, []string{`xy`, `x`}, true},
+
+		// Repetitions
+		{`(a|b)*?c`, `abac`, []string{`abac`, `a`}, true},
+		{`a{2,3}`, `aaaa`, []string{`aaa`}, true},
+		{`a{,2}b`, `aaab`, []string{`aab`}, true},
+		{`(a*)*b`, `aab`, []string{`aab`, `aa`}, true},
+		{`(?=a)(a*)*b`, `aab`, []string{`aab`, `aa`}, false},
+		{`(?=x)(x{1001})`, strings.Repeat(`x`, 1001), []string{strings.Repeat(`x`, 1001), strings.Repeat(`x`, 1001)}, false},
+		{`x{1001}`, strings.Repeat(`x`, 1002), []string{strings.Repeat(`x`, 1001)}, false},
+	} {
+		re, err := Compile(tt.pattern)
+		if err != nil {
+			t.Errorf(`%s: %s`, tt.pattern, err)
+			continue
+		}
+		if (re.Go() != nil) != tt.translated {
+			t.Errorf(`%s: expected translated to be %t`, tt.pattern, tt.translated)
+		}
+		if actual := re.FindStringSubmatch(tt.input); !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf(`%s on %q: expected %q, got %q`, tt.pattern, tt.input, tt.expected, actual)
+		}
+	}
+}
+
+func TestTranslation(t *testing.T) {
+	for pattern, expected := range map[string]string{
+		`a+b`:      `(?:a)+b`,
+		`\h`:       `[\x{30}-\x{39}\x{41}-\x{46}\x{61}-\x{66}]`,
+		`(?<n>a)$`: `(?P<n>a)(?m:$)`,
+		`(?i)a.`:   `(?i:a).`,
+		`\Aa\z`:    `\Aa\z`,
+	} {
+		if actual := MustCompile(pattern).Go().String(); actual != expected {
+			t.Errorf(`%s: expected translation %s, got %s`, pattern, expected, actual)
+		}
+	}
+}
+
+func TestFindAll(t *testing.T) {
+	for _, re := range []*Regexp{MustCompile(`(?<=,)\w*`), MustCompile(`(?<=,)(?:\w*)`)} {
+		var found []string
+		for _, loc := range re.FindAllStringSubmatchIndex(`a,b,,cd`, -1) {
+			found = append(found, `a,b,,cd`[loc[0]:loc[1]])
+		}
+		if !reflect.DeepEqual(found, []string{`b`, ``, `cd`}) {
+			t.Errorf(`%s: expected [b  cd], got %q`, re, found)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	for _, tt := range []struct {
+		pattern  string
+		input    string
+		limit    int
+		expected []string
+	}{
+		{`,`, `a,b,,c,,`, 0, []string{`a`, `b`, ``, `c`}},
+		{`,`, `a,b,,c,,`, -1, []string{`a`, `b`, ``, `c`, ``, ``}},
+		{`,`, `a,b,c`, 2, []string{`a`, `b,c`}},
+		{`(?<=a)`, `aab`, 0, []string{`a`, `a`, `b`}},
+		{`(-)`, `a-b`, 0, []string{`a`, `-`, `b`}},
+		{``, `abc`, 0, []string{`a`, `b`, `c`}},
+		{`,`, ``, 0, []string{}},
+	} {
+		if actual := MustCompile(tt.pattern).Split(tt.input, tt.limit); !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf(`split(%q, /%s/, %d): expected %q, got %q`, tt.input, tt.pattern, tt.limit, tt.expected, actual)
+		}
+	}
+}
+
+func TestSyntaxErrors(t *testing.T) {
+	for _, pattern := range []string{`(`, `a)`, `*a`, `[a`, `\k<x>`, `\1`, `(?z)`} {
+		if _, err := Compile(pattern); err == nil {
+			t.Errorf(`%s: expected a syntax error`, pattern)
+		}
+	}
+}
+
+// expectLimit asserts that matching the given expression on the given input is abandoned with a
+// MatchLimitExceeded error within a reasonable time
+func expectLimit(t *testing.T, pattern, input string) {
+	t.Helper()
+	re := MustCompile(pattern)
+	started := time.Now()
+	defer func() {
+		r := recover()
+		if ri, ok := r.(issue.Reported); !ok || ri.Code() != MatchLimitExceeded {
+			t.Fatalf(`%s: expected %s, got %v`, pattern, MatchLimitExceeded, r)
+		}
+		if d := time.Since(started); d > 10*time.Second {
+			t.Errorf(`%s: giving up took %s`, pattern, d)
+		}
+	}()
+	re.MatchString(input)
+}
+
+func TestPathologicalInput(t *testing.T) {
+	// Would exhaust the Go stack if the matcher recursed once per character
+	expectLimit(t, `(?<=x)|(0)*(?=c)`, strings.Repeat(`0`, 3000000))
+
+	// Backtracks exponentially
+	expectLimit(t, `^(a+)+(?=c)`, strings.Repeat(`a`, 26))
+}
+
+func TestLongInput(t *testing.T) {
+	// A long match that does not backtrack is within the limit
+	input := strings.Repeat(`0`, 100000) + `c`
+	if loc := MustCompile(`(0)*(?=c)`).FindStringSubmatchIndex(input); !reflect.DeepEqual(loc, []int{0, 100000, 99999, 100000}) {
+		t.Errorf(`expected [0 100000 99999 100000], got %v`, loc)
+	}
+}
@@ -0,0 +1,191 @@
+package rubyregexp
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// runeSet is a set of characters represented as pairs of inclusive lo-hi ranges. A normalized set is
+// sorted and has no overlapping or adjacent ranges.
+type runeSet []rune
+
+func (s runeSet) normalize() runeSet {
+	n := len(s) / 2
+	if n < 2 {
+		return s
+	}
+	pairs := make([][2]rune, n)
+	for i := range pairs {
+		pairs[i] = [2]rune{s[2*i], s[2*i+1]}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i][0] < pairs[j][0] })
+	result := make(runeSet, 0, len(s))
+	for _, p := range pairs {
+		last := len(result) - 1
+		if last > 0 && p[0] <= result[last]+1 {
+			if p[1] > result[last] {
+				result[last] = p[1]
+			}
+			continue
+		}
+		result = append(result, p[0], p[1])
+	}
+	return result
+}
+
+// negate returns the complement of a normalized set
+func (s runeSet) negate() runeSet {
+	result := make(runeSet, 0, len(s)+2)
+	next := rune(0)
+	for i := 0; i < len(s); i += 2 {
+		if s[i] > next {
+			result = append(result, next, s[i]-1)
+		}
+		next = s[i+1] + 1
+	}
+	if next <= unicode.MaxRune {
+		result = append(result, next, unicode.MaxRune)
+	}
+	return result
+}
+
+// intersect returns the intersection of two normalized sets
+func (s runeSet) intersect(o runeSet) runeSet {
+	var result runeSet
+	for i, j := 0, 0; i < len(s) && j < len(o); {
+		lo, hi := s[i], s[i+1]
+		if o[j] > lo {
+			lo = o[j]
+		}
+		if o[j+1] < hi {
+			hi = o[j+1]
+		}
+		if lo <= hi {
+			result = append(result, lo, hi)
+		}
+		if s[i+1] < o[j+1] {
+			i += 2
+		} else {
+			j += 2
+		}
+	}
+	return result
+}
+
+// contains returns true if the normalized set contains the given character
+func (s runeSet) contains(r rune) bool {
+	n := len(s) / 2
+	i := sort.Search(n, func(i int) bool { return s[2*i+1] >= r })
+	return i < n && s[2*i] <= r
+}
+
+func tableSet(tables ...*unicode.RangeTable) runeSet {
+	var s runeSet
+	for _, t := range tables {
+		for _, r := range t.R16 {
+			s = appendStrided(s, rune(r.Lo), rune(r.Hi), rune(r.Stride))
+		}
+		for _, r := range t.R32 {
+			s = appendStrided(s, rune(r.Lo), rune(r.Hi), rune(r.Stride))
+		}
+	}
+	return s.normalize()
+}
+
+func appendStrided(s runeSet, lo, hi, stride rune) runeSet {
+	if stride == 1 {
+		return append(s, lo, hi)
+	}
+	for r := lo; r <= hi; r += stride {
+		s = append(s, r, r)
+	}
+	return s
+}
+
+var (
+	digitSet  = runeSet{'0', '9'}
+	wordSet   = runeSet{'0', '9', 'A', 'Z', '_', '_', 'a', 'z'}
+	spaceSet  = runeSet{'\t', '\r', ' ', ' '}
+	hexSet    = runeSet{'0', '9', 'A', 'F', 'a', 'f'}
+	asciiPunc = runeSet{'!', '/', ':', '@', '[', '`', '{', '~'}
+)
+
+// escapeClass returns the set denoted by one of the escapes \d, \w, \s, \h and whether or not the
+// set is negated. As in Ruby, these escapes only match ASCII characters.
+func escapeClass(r rune) (runeSet, bool) {
+	negate := unicode.IsUpper(r)
+	switch unicode.ToLower(r) {
+	case 'd':
+		return digitSet, negate
+	case 'w':
+		return wordSet, negate
+	case 's':
+		return spaceSet, negate
+	default:
+		return hexSet, negate
+	}
+}
+
+// posixClasses are the sets of the POSIX brackets. Unlike the backslash escapes, they match Unicode
+// characters.
+var posixClasses = map[string]func() runeSet{
+	`alnum`: func() runeSet { return tableSet(unicode.L, unicode.M, unicode.Nd) },
+	`alpha`: func() runeSet { return tableSet(unicode.L, unicode.M) },
+	`ascii`: func() runeSet { return runeSet{0, 0x7f} },
+	`blank`: func() runeSet { return append(tableSet(unicode.Zs), '\t', '\t').normalize() },
+	`cntrl`: func() runeSet { return tableSet(unicode.Cc) },
+	`digit`: func() runeSet { return tableSet(unicode.Nd) },
+	`graph`: func() runeSet {
+		return append(tableSet(unicode.White_Space, unicode.Cc, unicode.Cs), 0xd800, 0xdfff).normalize().negate()
+	},
+	`lower`: func() runeSet { return tableSet(unicode.Ll) },
+	`print`: func() runeSet {
+		return append(tableSet(unicode.Cc, unicode.Zl, unicode.Zp), 0xd800, 0xdfff).normalize().negate()
+	},
+	`punct`:  func() runeSet { return append(tableSet(unicode.P), asciiPunc...).normalize() },
+	`space`:  func() runeSet { return tableSet(unicode.White_Space) },
+	`upper`:  func() runeSet { return tableSet(unicode.Lu) },
+	`word`:   func() runeSet { return tableSet(unicode.L, unicode.M, unicode.Nd, unicode.Pc) },
+	`xdigit`: func() runeSet { return hexSet },
+}
+
+var properties struct {
+	sync.Once
+	tables map[string]*unicode.RangeTable
+}
+
+// propertySet returns the set of the character property with the given name. The name is matched
+// case insensitively and without regard to spaces, hyphens, and underscores.
+func propertySet(name string) (runeSet, bool) {
+	key := propertyKey(name)
+	if key == `any` {
+		return runeSet{0, unicode.MaxRune}, true
+	}
+	if pc, ok := posixClasses[key]; ok {
+		return pc(), true
+	}
+	properties.Do(func() {
+		properties.tables = make(map[string]*unicode.RangeTable)
+		for _, m := range []map[string]*unicode.RangeTable{unicode.Properties, unicode.Scripts, unicode.Categories} {
+			for n, t := range m {
+				properties.tables[propertyKey(n)] = t
+			}
+		}
+	})
+	if t, ok := properties.tables[key]; ok {
+		return tableSet(t), true
+	}
+	return nil, false
+}
+
+func propertyKey(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '_':
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, name)
+}
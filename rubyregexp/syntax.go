@@ -0,0 +1,787 @@
+package rubyregexp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type (
+	node interface{}
+
+	// literal matches a single character
+	literal struct {
+		r    rune
+		fold bool
+	}
+
+	// charClass matches one character that is in (or, when negated, not in) a set
+	charClass struct {
+		set    runeSet
+		negate bool
+		fold   bool
+	}
+
+	// anyChar matches any character. A newline is only matched in multiline mode.
+	anyChar struct {
+		multiline bool
+	}
+
+	assertion int
+
+	// group is a group that captures when index is greater than zero
+	group struct {
+		index int
+		name  string
+		body  node
+	}
+
+	concat []node
+
+	alternation []node
+
+	repeat struct {
+		body node
+		min  int
+		max  int
+		lazy bool
+	}
+
+	// atomic is a group that, once matched, is never backtracked into
+	atomic struct {
+		body node
+	}
+
+	look struct {
+		body   node
+		behind bool
+		negate bool
+	}
+
+	backref struct {
+		index int
+		name  string
+		fold  bool
+	}
+)
+
+const (
+	beginLine assertion = iota
+	endLine
+	beginText
+	endText
+	endTextOptionalNewline
+	wordBoundary
+	nonWordBoundary
+	searchStart
+)
+
+type flags struct {
+	fold      bool
+	multiline bool
+	extended  bool
+}
+
+type parser struct {
+	src   []rune
+	pos   int
+	flags flags
+
+	// when set, plain groups do not capture. This is the case when the expression contains named groups.
+	noPlainCapture bool
+
+	names    []string
+	backrefs []*backref
+	named    bool
+}
+
+// parse parses the given expression using the Ruby syntax. It returns the root node and the names of
+// the capture groups, the first being the name of the whole match.
+func parse(expr string) (node, []string, error) {
+	root, names, named, err := parse2(expr, false)
+	if err == nil && named && len(names) > 1 {
+		// Ruby does not capture plain groups in an expression that contains named groups
+		root, names, _, err = parse2(expr, true)
+	}
+	return root, names, err
+}
+
+func parse2(expr string, noPlainCapture bool) (root node, names []string, named bool, err error) {
+	p := &parser{src: []rune(expr), noPlainCapture: noPlainCapture, names: []string{``}}
+	defer func() {
+		if r := recover(); r != nil {
+			se, ok := r.(*syntaxError)
+			if !ok {
+				panic(r)
+			}
+			err = se
+		}
+	}()
+	root = p.parseAlternation()
+	if p.pos < len(p.src) {
+		// Only an unmatched right parenthesis ends the alternation prematurely
+		p.fail(`unmatched close parenthesis`)
+	}
+	for _, br := range p.backrefs {
+		p.resolve(br)
+	}
+	return root, p.names, p.named, nil
+}
+
+type syntaxError struct {
+	msg  string
+	expr string
+}
+
+func (e *syntaxError) Error() string {
+	return fmt.Sprintf(`%s: /%s/`, e.msg, e.expr)
+}
+
+func (p *parser) fail(format string, args ...interface{}) {
+	panic(&syntaxError{msg: fmt.Sprintf(format, args...), expr: string(p.src)})
+}
+
+func (p *parser) peek() rune {
+	if p.pos < len(p.src) {
+		return p.src[p.pos]
+	}
+	return -1
+}
+
+func (p *parser) next() rune {
+	if p.pos < len(p.src) {
+		r := p.src[p.pos]
+		p.pos++
+		return r
+	}
+	p.fail(`premature end of regular expression`)
+	return -1
+}
+
+func (p *parser) lookingAt(s string) bool {
+	return strings.HasPrefix(string(p.src[p.pos:]), s)
+}
+
+func (p *parser) parseAlternation() node {
+	var alts alternation
+	for {
+		alts = append(alts, p.parseConcat())
+		if p.peek() != '|' {
+			break
+		}
+		p.pos++
+	}
+	if len(alts) == 1 {
+		return alts[0]
+	}
+	return alts
+}
+
+func (p *parser) parseConcat() node {
+	var items concat
+	for {
+		p.skipExtended()
+		r := p.peek()
+		if r < 0 || r == '|' || r == ')' {
+			break
+		}
+		if r == '(' && p.isOptionSetting() {
+			// Options set in the middle of a group apply to the rest of the group, including subsequent
+			// alternatives
+			saved := p.flags
+			p.parseOptions()
+			p.next()
+			items = append(items, p.parseAlternation())
+			p.flags = saved
+			break
+		}
+		atom := p.parseAtom()
+		if atom == nil {
+			continue
+		}
+		items = append(items, p.parseQuantifiers(atom))
+	}
+	switch len(items) {
+	case 0:
+		return concat(nil)
+	case 1:
+		return items[0]
+	default:
+		return items
+	}
+}
+
+// skipExtended skips whitespace and comments when in extended mode
+func (p *parser) skipExtended() {
+	if !p.flags.extended {
+		return
+	}
+	for p.pos < len(p.src) {
+		r := p.src[p.pos]
+		if r == '#' {
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		} else if !unicode.IsSpace(r) {
+			return
+		}
+		p.pos++
+	}
+}
+
+func (p *parser) parseQuantifiers(atom node) node {
+	for {
+		p.skipExtended()
+		min, max := 0, -1
+		start := p.pos
+		switch p.peek() {
+		case '*':
+			p.pos++
+		case '+':
+			p.pos++
+			min = 1
+		case '?':
+			p.pos++
+			max = 1
+		case '{':
+			var ok bool
+			if min, max, ok = p.parseInterval(); !ok {
+				return atom
+			}
+		default:
+			return atom
+		}
+		if _, isAssertion := atom.(assertion); isAssertion {
+			p.fail(`target of repeat operator is invalid`)
+		}
+		rp := &repeat{body: atom, min: min, max: max}
+		atom = rp
+		switch p.peek() {
+		case '?':
+			p.pos++
+			rp.lazy = true
+		case '+':
+			// Only the simple quantifiers have a possessive form. An interval followed by a plus is a
+			// nested repetition
+			if p.src[start] != '{' {
+				p.pos++
+				atom = &atomic{rp}
+			}
+		}
+	}
+}
+
+// parseInterval parses {n}, {n,}, {,m}, or {n,m}. A brace that doesn't start a valid interval is a literal.
+func (p *parser) parseInterval() (int, int, bool) {
+	start := p.pos
+	p.pos++
+	min, hasMin := p.parseDecimal()
+	max := min
+	if p.peek() == ',' {
+		p.pos++
+		var hasMax bool
+		if max, hasMax = p.parseDecimal(); !hasMax {
+			max = -1
+		}
+		if !(hasMin || hasMax) {
+			p.pos = start
+			return 0, 0, false
+		}
+	} else if !hasMin {
+		p.pos = start
+		return 0, 0, false
+	}
+	if p.peek() != '}' {
+		p.pos = start
+		return 0, 0, false
+	}
+	p.pos++
+	if max >= 0 && max < min {
+		p.fail(`upper bound must be greater than lower bound`)
+	}
+	return min, max, true
+}
+
+func (p *parser) parseDecimal() (int, bool) {
+	start := p.pos
+	for p.pos < len(p.src) && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+		p.pos++
+	}
+	if start == p.pos {
+		return 0, false
+	}
+	n, err := strconv.Atoi(string(p.src[start:p.pos]))
+	if err != nil || n > 100000 {
+		p.fail(`too big number for repeat range`)
+	}
+	return n, true
+}
+
+// parseAtom returns the next atom, or nil when a construct that doesn't produce a node, such as a
+// comment, was consumed
+func (p *parser) parseAtom() node {
+	r := p.next()
+	switch r {
+	case '(':
+		return p.parseGroup()
+	case '[':
+		return p.classNode(p.parseClass())
+	case '.':
+		return &anyChar{multiline: p.flags.multiline}
+	case '^':
+		return beginLine
+	case '$':
+		return endLine
+	case '\\':
+		return p.parseEscape()
+	case '*', '+', '?':
+		p.fail(`target of repeat operator is not specified`)
+	case '{':
+		p.pos--
+		if _, _, ok := p.parseInterval(); ok {
+			p.fail(`target of repeat operator is not specified`)
+		}
+		p.pos++
+	}
+	return &literal{r: r, fold: p.flags.fold}
+}
+
+func (p *parser) classNode(set runeSet, negate bool) node {
+	return &charClass{set: set, negate: negate, fold: p.flags.fold}
+}
+
+// isOptionSetting returns true if the parser is positioned at an option setting such as (?i) or (?m-x)
+func (p *parser) isOptionSetting() bool {
+	i := p.pos + 1
+	if i >= len(p.src) || p.src[i] != '?' {
+		return false
+	}
+	for i++; i < len(p.src); i++ {
+		switch p.src[i] {
+		case 'i', 'm', 'x', '-':
+		case ')':
+			return i > p.pos+2
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// parseOptions parses options on the form imx-imx, starting at the '(?', and leaves the parser at the
+// terminating ':' or ')'
+func (p *parser) parseOptions() {
+	p.pos += 2
+	on := true
+	for {
+		switch p.peek() {
+		case 'i':
+			p.flags.fold = on
+		case 'm':
+			p.flags.multiline = on
+		case 'x':
+			p.flags.extended = on
+		case '-':
+			if !on {
+				p.fail(`undefined group option`)
+			}
+			on = false
+		case ':', ')':
+			return
+		default:
+			p.fail(`undefined group option`)
+		}
+		p.pos++
+	}
+}
+
+func (p *parser) parseGroup() node {
+	saved := p.flags
+	defer func() { p.flags = saved }()
+
+	var n node
+	if p.peek() != '?' {
+		n = p.captureGroup(``)
+	} else {
+		p.pos++
+		switch r := p.next(); r {
+		case ':':
+			n = p.parseAlternation()
+		case '=', '!':
+			n = &look{body: p.parseAlternation(), negate: r == '!'}
+		case '>':
+			n = &atomic{p.parseAlternation()}
+		case '#':
+			for p.next() != ')' {
+			}
+			return nil
+		case '\'':
+			n = p.captureGroup(p.parseGroupName('\''))
+		case '<':
+			switch p.peek() {
+			case '=', '!':
+				negate := p.next() == '!'
+				n = &look{body: p.parseAlternation(), behind: true, negate: negate}
+			default:
+				n = p.captureGroup(p.parseGroupName('>'))
+			}
+		case '~':
+			p.fail(`absence operator is not supported`)
+		case '(':
+			p.fail(`conditional expressions are not supported`)
+		default:
+			p.pos -= 3
+			p.parseOptions()
+			if p.next() != ':' {
+				p.fail(`invalid group option`)
+			}
+			n = p.parseAlternation()
+		}
+	}
+	if p.peek() != ')' {
+		p.fail(`end pattern with unmatched parenthesis`)
+	}
+	p.pos++
+	return n
+}
+
+func (p *parser) captureGroup(name string) node {
+	if name == `` && p.noPlainCapture {
+		return p.parseAlternation()
+	}
+	if name != `` {
+		p.named = true
+	}
+	g := &group{index: len(p.names), name: name}
+	p.names = append(p.names, name)
+	g.body = p.parseAlternation()
+	return g
+}
+
+func (p *parser) parseGroupName(end rune) string {
+	start := p.pos
+	for p.peek() != end {
+		r := p.next()
+		if !(r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) && p.pos > start+1) {
+			p.fail(`invalid group name <%s>`, string(p.src[start:p.pos]))
+		}
+	}
+	if start == p.pos {
+		p.fail(`group name is empty`)
+	}
+	name := string(p.src[start:p.pos])
+	p.pos++
+	return name
+}
+
+func (p *parser) parseEscape() node {
+	r := p.next()
+	switch r {
+	case 'A':
+		return beginText
+	case 'z':
+		return endText
+	case 'Z':
+		return endTextOptionalNewline
+	case 'b':
+		return wordBoundary
+	case 'B':
+		return nonWordBoundary
+	case 'G':
+		return searchStart
+	case 'd', 'D', 'w', 'W', 's', 'S', 'h', 'H':
+		set, negate := escapeClass(r)
+		return p.classNode(set, negate)
+	case 'p', 'P':
+		set, negate := p.parseProperty(r == 'P')
+		return p.classNode(set, negate)
+	case 'R':
+		// A linebreak. The CRLF sequence is matched atomically.
+		return &atomic{alternation{
+			concat{&literal{r: '\r'}, &literal{r: '\n'}},
+			&charClass{set: runeSet{'\n', '\r', 0x85, 0x85, 0x2028, 0x2029}},
+		}}
+	case 'k':
+		return p.parseNamedBackref()
+	case 'g':
+		p.fail(`subexpression calls are not supported`)
+	case 'K':
+		p.fail(`keep (\K) is not supported`)
+	case 'X':
+		p.fail(`extended grapheme clusters (\X) are not supported`)
+	case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		p.pos--
+		start := p.pos
+		n, _ := p.parseDecimal()
+		if n >= 10 && n >= len(p.names) && r <= '7' {
+			// Not a valid back reference. Ruby treats this as an octal escape
+			p.pos = start
+			return &literal{r: p.parseOctal(), fold: p.flags.fold}
+		}
+		br := &backref{index: n, fold: p.flags.fold}
+		p.backrefs = append(p.backrefs, br)
+		return br
+	}
+	p.pos--
+	return &literal{r: p.parseCharEscape(), fold: p.flags.fold}
+}
+
+func (p *parser) parseNamedBackref() node {
+	var end rune
+	switch p.next() {
+	case '<':
+		end = '>'
+	case '\'':
+		end = '\''
+	default:
+		p.fail(`invalid backref name`)
+	}
+	start := p.pos
+	for p.next() != end {
+	}
+	ref := string(p.src[start : p.pos-1])
+	br := &backref{fold: p.flags.fold}
+	if n, err := strconv.Atoi(ref); err == nil {
+		if n < 0 {
+			// Relative reference
+			n = len(p.names) + n
+			if n <= 0 {
+				p.fail(`invalid backref number/name`)
+			}
+		}
+		br.index = n
+	} else {
+		br.name = ref
+	}
+	p.backrefs = append(p.backrefs, br)
+	return br
+}
+
+// resolve validates the back reference and resolves a named reference to a group index
+func (p *parser) resolve(br *backref) {
+	if br.name != `` {
+		for i := len(p.names) - 1; i > 0; i-- {
+			if p.names[i] == br.name {
+				br.index = i
+				return
+			}
+		}
+		p.fail(`undefined name <%s> reference`, br.name)
+	}
+	if br.index >= len(p.names) {
+		p.fail(`invalid backref number/name`)
+	}
+}
+
+// parseCharEscape parses an escape sequence that denotes a single character. The parser is positioned
+// after the backslash.
+func (p *parser) parseCharEscape() rune {
+	r := p.next()
+	switch r {
+	case 't':
+		return '\t'
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 'f':
+		return '\f'
+	case 'v':
+		return '\v'
+	case 'a':
+		return '\a'
+	case 'e':
+		return 0x1b
+	case '0':
+		p.pos--
+		return p.parseOctal()
+	case 'x':
+		if p.peek() == '{' {
+			return p.parseBracedHex()
+		}
+		return p.parseHex(1, 2)
+	case 'u':
+		if p.peek() == '{' {
+			return p.parseBracedHex()
+		}
+		return p.parseHex(4, 4)
+	case 'c':
+		return p.next() & 0x1f
+	case 'C':
+		if p.next() != '-' {
+			p.fail(`invalid control-code syntax`)
+		}
+		return p.next() & 0x1f
+	}
+	if r < 0x80 && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+		p.fail(`invalid escape \%c`, r)
+	}
+	return r
+}
+
+func (p *parser) parseOctal() rune {
+	start := p.pos
+	for p.pos < len(p.src) && p.pos < start+3 && p.src[p.pos] >= '0' && p.src[p.pos] <= '7' {
+		p.pos++
+	}
+	n, _ := strconv.ParseInt(string(p.src[start:p.pos]), 8, 32)
+	return rune(n)
+}
+
+func (p *parser) parseHex(minDigits, maxDigits int) rune {
+	start := p.pos
+	for p.pos < len(p.src) && p.pos < start+maxDigits && isHexDigit(p.src[p.pos]) {
+		p.pos++
+	}
+	if p.pos-start < minDigits {
+		p.fail(`invalid code point value`)
+	}
+	n, _ := strconv.ParseInt(string(p.src[start:p.pos]), 16, 32)
+	return rune(n)
+}
+
+func (p *parser) parseBracedHex() rune {
+	p.pos++
+	r := p.parseHex(1, 8)
+	if p.next() != '}' || r > unicode.MaxRune {
+		p.fail(`invalid code point value`)
+	}
+	return r
+}
+
+func isHexDigit(r rune) bool {
+	return r >= '0' && r <= '9' || r >= 'a' && r <= 'f' || r >= 'A' && r <= 'F'
+}
+
+// parseProperty parses the name of a character property on the form {Name} or {^Name}
+func (p *parser) parseProperty(negate bool) (runeSet, bool) {
+	if p.next() != '{' {
+		p.fail(`invalid character property name`)
+	}
+	if p.peek() == '^' {
+		p.pos++
+		negate = !negate
+	}
+	start := p.pos
+	for p.next() != '}' {
+	}
+	name := string(p.src[start : p.pos-1])
+	set, ok := propertySet(name)
+	if !ok {
+		p.fail(`invalid character property name {%s}`, name)
+	}
+	return set, negate
+}
+
+// parseClass parses a bracketed character class. The parser is positioned after the left bracket.
+func (p *parser) parseClass() (runeSet, bool) {
+	negate := false
+	if p.peek() == '^' {
+		p.pos++
+		negate = true
+	}
+	return p.parseClassItems(true), negate
+}
+
+// parseClassItems parses the items of a character class up to and including the terminating right
+// bracket. A right bracket that is the first item is a literal.
+func (p *parser) parseClassItems(first bool) runeSet {
+	var set runeSet
+	for ; ; first = false {
+		r := p.next()
+		switch {
+		case r == ']' && !first:
+			return set.normalize()
+		case r == '[':
+			if p.peek() == ':' {
+				if s, ok := p.parsePosixClass(); ok {
+					set = append(set, s...)
+					continue
+				}
+			}
+			s, negate := p.parseClass()
+			if negate {
+				s = s.negate()
+			}
+			set = append(set, s...)
+		case r == '&' && p.peek() == '&':
+			// The rest of the class is intersected with what has been collected so far
+			p.pos++
+			return set.normalize().intersect(p.parseClassItems(false))
+		case r == '\\':
+			e := p.next()
+			switch e {
+			case 'd', 'D', 'w', 'W', 's', 'S', 'h', 'H':
+				s, negate := escapeClass(e)
+				if negate {
+					s = s.negate()
+				}
+				set = append(set, s...)
+			case 'p', 'P':
+				s, negate := p.parseProperty(e == 'P')
+				if negate {
+					s = s.negate()
+				}
+				set = append(set, s...)
+			case 'b':
+				set = p.appendRange(set, '\b')
+			default:
+				p.pos--
+				set = p.appendRange(set, p.parseCharEscape())
+			}
+		default:
+			set = p.appendRange(set, r)
+		}
+	}
+}
+
+// appendRange appends the given character, or the range that it starts, to the set
+func (p *parser) appendRange(set runeSet, lo rune) runeSet {
+	hi := lo
+	if p.peek() == '-' && p.pos+1 < len(p.src) && p.src[p.pos+1] != ']' {
+		p.pos++
+		hi = p.next()
+		switch hi {
+		case '\\':
+			hi = p.parseCharEscape()
+		case '[':
+			p.fail(`char-class value at end of range`)
+		}
+		if hi < lo {
+			p.fail(`empty range in char class`)
+		}
+	}
+	return append(set, lo, hi)
+}
+
+// parsePosixClass parses a POSIX bracket such as [:alpha:] or [:^digit:]. The parser is positioned at
+// the colon. The parser is left unchanged if no valid bracket is found.
+func (p *parser) parsePosixClass() (runeSet, bool) {
+	start := p.pos
+	p.pos++
+	negate := false
+	if p.peek() == '^' {
+		p.pos++
+		negate = true
+	}
+	nameStart := p.pos
+	for p.pos < len(p.src) && p.src[p.pos] >= 'a' && p.src[p.pos] <= 'z' {
+		p.pos++
+	}
+	name := string(p.src[nameStart:p.pos])
+	if !p.lookingAt(`:]`) {
+		p.pos = start
+		return nil, false
+	}
+	set, ok := posixClasses[name]
+	if !ok {
+		p.fail(`invalid POSIX bracket type [:%s:]`, name)
+	}
+	p.pos += 2
+	s := set()
+	if negate {
+		s = s.negate()
+	}
+	return s, true
+}